@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pavelmemory/jobtome/internal/auth"
+	shortenserv "github.com/pavelmemory/jobtome/internal/shorten"
+	apikeyrepo "github.com/pavelmemory/jobtome/internal/storage/apikey"
+)
+
+// apiKeyRecord is a JSON representation of the API key used for the output.
+type apiKeyRecord struct {
+	ID        int64        `json:"id"`
+	Name      string       `json:"name"`
+	Scopes    []auth.Scope `json:"scopes"`
+	Workspace string       `json:"workspace"`
+	CreatedAt time.Time    `json:"created_at"`
+	RevokedAt *time.Time   `json:"revoked_at,omitempty"`
+	// Key is a secret of the key, it is printed only once the key is issued.
+	Key string `json:"key,omitempty"`
+}
+
+func newAPIKeyRecord(key auth.Key) apiKeyRecord {
+	record := apiKeyRecord{ID: key.ID, Name: key.Name, Scopes: key.Scopes, Workspace: key.Workspace, CreatedAt: key.CreatedAt.UTC()}
+	if !key.RevokedAt.IsZero() {
+		revokedAt := key.RevokedAt.UTC()
+		record.RevokedAt = &revokedAt
+	}
+
+	return record
+}
+
+// apiKeyCommand manages API keys directly in the storage, it is the only way to issue the first admin key.
+func apiKeyCommand(ctx context.Context, env env, args []string) error {
+	name, args, err := subcommand("apikey", args, "issue", "list", "revoke")
+	if err != nil {
+		return err
+	}
+
+	flags := flag.NewFlagSet("apikey "+name, flag.ContinueOnError)
+	var (
+		keyName   = flags.String("name", "", "name of the key owner (issue only)")
+		scopes    = flags.String("scopes", "", fmt.Sprintf("comma separated scopes of the key %v (issue only)", auth.Scopes))
+		workspace = flags.String("workspace", shortenserv.DefaultWorkspace, "workspace the key manages shortens of (issue only)")
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := openStorage(env.settings, env.logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	service := auth.NewService(db, apikeyrepo.Repo{})
+	encoder := json.NewEncoder(os.Stdout)
+
+	switch name {
+	case "issue":
+		parsed, err := auth.ParseScopes(strings.FieldsFunc(*scopes, func(r rune) bool { return r == ',' }))
+		if err != nil {
+			return fmt.Errorf("flag -scopes: %w", err)
+		}
+
+		key, secret, err := service.Issue(ctx, *keyName, *workspace, parsed)
+		if err != nil {
+			env.logger.WithError(err).Error("issue api key")
+			return err
+		}
+
+		record := newAPIKeyRecord(key)
+		record.Key = secret
+		return encoder.Encode(record)
+	case "list":
+		keys, err := service.List(ctx, "")
+		if err != nil {
+			env.logger.WithError(err).Error("list api keys")
+			return err
+		}
+
+		for _, key := range keys {
+			if err := encoder.Encode(newAPIKeyRecord(key)); err != nil {
+				return err
+			}
+		}
+	default:
+		if flags.NArg() != 1 {
+			return errors.New("ID of the api key is required")
+		}
+
+		id, err := strconv.ParseInt(flags.Arg(0), 10, 64)
+		if err != nil {
+			return fmt.Errorf("ID of the api key: %w", err)
+		}
+
+		if err := service.Revoke(ctx, "", id); err != nil {
+			env.logger.WithError(err).WithInt64("id", id).Error("revoke api key")
+			return err
+		}
+	}
+
+	return nil
+}
@@ -2,22 +2,57 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap/zapcore"
 
 	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/auth"
 	"github.com/pavelmemory/jobtome/internal/config"
+	"github.com/pavelmemory/jobtome/internal/health"
 	"github.com/pavelmemory/jobtome/internal/logging"
+	"github.com/pavelmemory/jobtome/internal/policy"
+	"github.com/pavelmemory/jobtome/internal/ratelimit"
 	shortenserv "github.com/pavelmemory/jobtome/internal/shorten"
 	"github.com/pavelmemory/jobtome/internal/storage"
+	apikeyrepo "github.com/pavelmemory/jobtome/internal/storage/apikey"
+	clickrepo "github.com/pavelmemory/jobtome/internal/storage/click"
 	"github.com/pavelmemory/jobtome/internal/storage/migrations"
 	shortenrepo "github.com/pavelmemory/jobtome/internal/storage/shorten"
+	"github.com/pavelmemory/jobtome/internal/tracing"
 	"github.com/pavelmemory/jobtome/internal/webhttp"
 )
 
-func run([]string) error {
+// env holds dependencies shared by all commands.
+type env struct {
+	settings config.EnvSettings
+	logger   logging.Logger
+}
+
+// command executes a subcommand of the binary with the `args` following its name.
+type command func(ctx context.Context, env env, args []string) error
+
+var commands = map[string]command{
+	"serve":   serve,
+	"migrate": migrate,
+	"shorten": shortenCommand,
+	"export":  export,
+	"import":  importShortens,
+	"apikey":  apiKeyCommand,
+}
+
+func run(args []string) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -34,66 +69,322 @@ func run([]string) error {
 	logger := logging.NewZapLogger(settings.LogLevel())
 	defer logger.Sync()
 
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		err := fmt.Errorf("unknown command %q", name)
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		return err
+	}
+
+	return cmd(ctx, env{settings: settings, logger: logger.WithString("command", name)}, args)
+}
+
+// serve starts API and redirect servers and blocks until the `ctx` is cancelled.
+func serve(ctx context.Context, env env, args []string) error {
+	if err := flag.NewFlagSet("serve", flag.ContinueOnError).Parse(args); err != nil {
+		return err
+	}
+
+	settings, logger := env.settings, env.logger
+
 	logger.WithString("version", internal.Version).
 		WithString("commit_sha", internal.CommitSHA).
 		WithString("build_timestamp", internal.BuildTimestamp).
 		Info("executable build info")
 
-	if err := migrations.Up(settings.StorageFilePath()); err != nil {
-		logger.WithError(err).Error("sqlite3 database migration")
+	sweepArchive, err := settings.SweepArchive()
+	if err != nil {
+		logger.WithError(err).Error("sweep mode")
+		return err
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Exporter:    settings.TracingExporter(),
+		Endpoint:    settings.TracingEndpoint(),
+		SampleRatio: settings.TracingSampleRatio(),
+	})
+	if err != nil {
+		logger.WithError(err).WithString("exporter", settings.TracingExporter()).Error("tracing initialization")
+		return err
+	}
+	defer func() {
+		// the `ctx` is already cancelled, but the collected spans still need to be flushed
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.WithError(err).Error("tracing shutdown")
+		}
+	}()
+
+	db, err := openStorage(settings, logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	prometheus.MustRegister(storage.NewPoolCollector(settings.StorageDriver(), db))
+
+	checker := newHealthChecker(settings, db)
+	ctx = drain(ctx, logger, checker, settings.ShutdownDelay())
+
+	destinations, err := newDestinationPolicy(settings, logger)
+	if err != nil {
 		return err
 	}
+	if settings.PolicyFile() != "" && settings.PolicyReloadInterval() > 0 {
+		go destinations.Watch(logging.ToContext(ctx, logger), settings.PolicyReloadInterval())
+	}
 
-	sqlLite, err := storage.NewSQLLite(settings.StorageFilePath())
+	shortenService, err := newShortenService(settings, logger, db, destinations)
 	if err != nil {
-		logger.WithError(err).Error("sqlite3 connection establishment")
 		return err
 	}
-	defer sqlLite.Close()
 
-	shortenService := shortenserv.NewService(sqlLite, shortenrepo.Repo{})
+	var keyService webhttp.KeyService
+	if settings.AuthEnabled() {
+		keyService = auth.NewService(db, apikeyrepo.Repo{})
+	} else {
+		logger.Info("authorization of API requests is disabled")
+	}
+
+	clicksCtx, stopClicks := context.WithCancel(logging.ToContext(ctx, logger))
+	clicksDone := make(chan struct{})
+	go func() {
+		defer close(clicksDone)
+		shortenService.RecordClicks(clicksCtx)
+	}()
+	// the queued clicks are persisted before the storage is closed
+	defer func() {
+		stopClicks()
+		<-clicksDone
+	}()
+	if settings.SweepInterval() > 0 {
+		go shortenService.SweepExpired(logging.ToContext(ctx, logger), shortenserv.SweepOpts{
+			Interval: settings.SweepInterval(),
+			Grace:    settings.SweepGrace(),
+			Archive:  sweepArchive,
+		})
+	}
+	if settings.TrashRetention() > 0 && settings.TrashPurgeInterval() > 0 {
+		go shortenService.PurgeTrash(logging.ToContext(ctx, logger), shortenserv.PurgeOpts{
+			Interval:  settings.TrashPurgeInterval(),
+			Retention: settings.TrashRetention(),
+		})
+	}
+
+	trustedProxies, err := webhttp.ParseNetworks(settings.TrustedProxies())
+	if err != nil {
+		logger.WithError(err).Error("trusted proxies")
+		return err
+	}
+	limits := ratelimit.NewMemoryBackend()
+	apiLimits := webhttp.RateLimitPolicy{
+		Name:           "api",
+		Backend:        limits,
+		PerIP:          settings.APIRateLimitPerIP(),
+		PerKey:         settings.APIRateLimitPerKey(),
+		TrustedProxies: trustedProxies,
+	}
+	resolverLimits := webhttp.RateLimitPolicy{
+		Name:           "resolver",
+		Backend:        limits,
+		PerIP:          settings.ResolverRateLimitPerIP(),
+		TrustedProxies: trustedProxies,
+	}
+
+	publicBaseURL, err := url.Parse(settings.PublicBaseURL())
+	if err != nil || (publicBaseURL.Scheme != "http" && publicBaseURL.Scheme != "https") || publicBaseURL.Host == "" {
+		err = fmt.Errorf("public base URL %q must be an absolute http(s) URL", settings.PublicBaseURL())
+		logger.WithError(err).Error("public base URL")
+		return err
+	}
+
+	apiRouter := newAPIRouter(logger, shortenService, publicBaseURL.String(), keyService, checker, apiLimits, settings.OpenAPIUIEnabled())
+	resolverRouter := newResolverRouter(logger, shortenService, resolverLimits)
+	apiAddr := net.JoinHostPort(settings.HTTPBindAddress(), strconv.Itoa(settings.HTTPPort()))
 
+	if hosts := settings.ResolverHosts(); len(hosts) > 0 {
+		router := webhttp.NewHostRouter(apiRouter)
+		for _, host := range hosts {
+			router.Handle(host, resolverRouter)
+		}
+		logger.WithString("resolver_hosts", strings.Join(hosts, ",")).Info("serving API and redirects on a single listener")
+		return <-runServer(ctx, logger, router, apiAddr)
+	}
+
+	resolverAddr := net.JoinHostPort(settings.ResolverBindAddress(), strconv.Itoa(settings.ResolverPort()))
 	select {
-	case err := <-runAPI(ctx, logger, shortenService, settings.HTTPPort()):
+	case err := <-runServer(ctx, logger, apiRouter, apiAddr):
 		return err
-	case err := <-runResolver(ctx, logger, shortenService):
+	case err := <-runServer(ctx, logger, resolverRouter, resolverAddr):
 		return err
 	}
 }
 
-func runAPI(ctx context.Context, logger logging.Logger, shorter webhttp.ShortenService, port int) <-chan error {
-	shortenHandler := webhttp.NewShortenHandler(shorter)
-	router := webhttp.NewRouter(logger)
-	shortenHandler.Register(router)
-	infoHandler := webhttp.InfoHandler{}
-	infoHandler.Register(router)
+// openStorage applies pending migrations and returns a connection pool to the database.
+func openStorage(settings config.EnvSettings, logger logging.Logger) (storage.Pool, error) {
+	if err := migrations.Up(settings.StorageDriver(), settings.StorageDSN()); err != nil {
+		logger.WithError(err).WithString("driver", settings.StorageDriver()).Error("database migration")
+		return nil, err
+	}
 
-	srv := webhttp.NewServer(router)
-	errChan := make(chan error)
+	db, err := storage.Open(settings.StorageDriver(), settings.StorageDSN())
+	if err != nil {
+		logger.WithError(err).WithString("driver", settings.StorageDriver()).Error("database connection establishment")
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// newHealthChecker returns a checker of the storage the readiness of the service depends on.
+func newHealthChecker(settings config.EnvSettings, db storage.Pool) *health.Checker {
+	checker := health.NewChecker(settings.HealthCheckTimeout())
+	checker.Add("ping", health.Ping(db))
+	checker.Add("migrations", health.Migrations(db))
+	if settings.StorageDriver() == storage.DriverSQLite {
+		checker.Add("disk", health.DiskSpace(settings.StorageDSN(), settings.HealthMinFreeDisk()))
+	}
+
+	return checker
+}
+
+// drain returns a context cancelled the `delay` after the `ctx` is done.
+// Meanwhile the `checker` reports the service isn't ready, so no new requests are routed to it.
+func drain(ctx context.Context, logger logging.Logger, checker *health.Checker, delay time.Duration) context.Context {
+	dctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
 	go func() {
-		errChan <- webhttp.Serve(ctx, logger, srv, port)
+		<-ctx.Done()
+		checker.Shutdown()
+		logger.WithString("delay", delay.String()).Info("draining before shutdown")
+		time.Sleep(delay)
+		cancel()
 	}()
-	return errChan
+
+	return dctx
+}
+
+// newDestinationPolicy returns a policy of the URLs the shortens could redirect to with the rules loaded
+// from the policy file. The URLs of the public base URL and of the resolver hosts are always blocked.
+func newDestinationPolicy(settings config.EnvSettings, logger logging.Logger) (*policy.Engine, error) {
+	ownHosts := settings.ResolverHosts()
+	if publicBaseURL, err := url.Parse(settings.PublicBaseURL()); err == nil && publicBaseURL.Host != "" {
+		ownHosts = append([]string{publicBaseURL.Host}, ownHosts...)
+	}
+
+	engine := policy.NewEngine(policy.Config{OwnHosts: ownHosts, AllowPrivate: settings.PolicyAllowPrivate()})
+	if settings.PolicyFile() != "" {
+		if err := engine.LoadFile(settings.PolicyFile()); err != nil {
+			logger.WithError(err).WithString("file", settings.PolicyFile()).Error("destination policy initialization")
+			return nil, err
+		}
+	}
+
+	return engine, nil
+}
+
+// newShortenService returns a shorten service configured with the `settings`,
+// the URLs of the new shortens are checked by the `destinations` policy.
+// Background processing of the service is not started.
+func newShortenService(
+	settings config.EnvSettings,
+	logger logging.Logger,
+	db storage.Pool,
+	destinations shortenserv.DestinationPolicy,
+) (*shortenserv.Service, error) {
+	codeGenerator, err := shortenserv.NewCodeGenerator(
+		settings.CodeStrategy(),
+		settings.CodeAlphabet(),
+		settings.CodeLength(),
+		settings.CodeSalt(),
+	)
+	if err != nil {
+		logger.WithError(err).Error("code generator initialization")
+		return nil, err
+	}
+
+	opts := []shortenserv.Option{
+		shortenserv.WithCodeGenerator(codeGenerator),
+		shortenserv.WithAliasPolicy(shortenserv.NewAliasPolicy(
+			settings.AliasMinLength(),
+			settings.AliasMaxLength(),
+			settings.AliasReserved(),
+		)),
+		shortenserv.WithURLPolicy(shortenserv.NewURLPolicy(
+			settings.URLSchemes(),
+			settings.URLSortQuery(),
+			settings.URLStripFragment(),
+		)),
+		shortenserv.WithDestinationPolicy(destinations, settings.PolicyCheckResolve()),
+	}
+	if settings.CacheSize() > 0 {
+		cache := shortenserv.NewResolveCache(settings.CacheSize(), settings.CacheTTL(), settings.CacheNegativeTTL())
+		prometheus.MustRegister(cache)
+		opts = append(opts, shortenserv.WithResolveCache(cache))
+	}
+
+	return shortenserv.NewService(db, shortenrepo.Repo{}, clickrepo.Repo{}, opts...), nil
 }
 
-func runResolver(ctx context.Context, logger logging.Logger, resolver webhttp.Resolver) <-chan error {
+// newAPIRouter returns a router of the API, the short URLs are built from the `publicBaseURL`,
+// nil `keys` disables the authorization of the requests and `docs` enables Swagger UI page of the API specification.
+func newAPIRouter(
+	logger logging.Logger,
+	shorter webhttp.ShortenService,
+	publicBaseURL string,
+	keys webhttp.KeyService,
+	checker webhttp.HealthChecker,
+	limits webhttp.RateLimitPolicy,
+	docs bool,
+) http.Handler {
+	router := webhttp.NewRouter(logger, webhttp.RateLimit(limits))
+
+	var authenticator webhttp.Authenticator
+	if keys != nil {
+		authenticator = keys
+		webhttp.NewKeyHandler(keys).Register(router)
+	}
+
+	shortenHandler := webhttp.NewShortenHandler(shorter, authenticator, publicBaseURL)
+	shortenHandler.Register(router)
+	infoHandler := webhttp.InfoHandler{Health: checker}
+	infoHandler.Register(router)
+	openAPIHandler := webhttp.OpenAPIHandler{UI: docs}
+	openAPIHandler.Register(router)
+
+	return router
+}
+
+// newResolverRouter returns a router of the redirects for short URLs.
+func newResolverRouter(logger logging.Logger, resolver webhttp.Resolver, limits webhttp.RateLimitPolicy) http.Handler {
 	resolverHandler := webhttp.NewResolverHandler(resolver)
-	router := webhttp.NewRouter(logger)
+	router := webhttp.NewRouter(logger, webhttp.RateLimit(limits))
 	resolverHandler.Register(router)
-	srv := webhttp.NewServer(router)
+
+	return router
+}
+
+// runServer starts a server of the `handler` on the `addr` that stops once the `ctx` is cancelled.
+func runServer(ctx context.Context, logger logging.Logger, handler http.Handler, addr string) <-chan error {
+	srv := webhttp.NewServer(handler)
 	errChan := make(chan error)
 	go func() {
-		errChan <- webhttp.Serve(ctx, logger, srv, 80)
+		errChan <- webhttp.Serve(ctx, logger, srv, addr)
 	}()
 	return errChan
 }
 
-// interrupt listens for SIGINT and cancels context.
+// interrupt listens for SIGINT and SIGTERM and cancels context.
 func interrupt(ctx context.Context) context.Context {
 	cctx, cancel := context.WithCancel(ctx)
 
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
 		<-c
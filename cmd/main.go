@@ -3,12 +3,31 @@ package main
 import (
 	"encoding/json"
 	"flag"
+	"fmt"
 	"os"
 
 	"github.com/pavelmemory/jobtome/internal"
 )
 
+const usage = `Usage: jobtome [-v] [command] [arguments]
+
+Commands:
+  serve                                 starts API and redirect servers (default)
+  migrate up|down|status                manages database migrations
+  shorten create|get|list|delete        manages shortens without running server
+  export                                writes all shortens as JSON lines
+  import                                creates shortens from JSON lines
+  apikey issue|list|revoke              manages API keys
+
+Run 'jobtome <command> -h' for the command details.
+All settings are read from environment variables, see README.md.
+`
+
 func main() {
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+	}
+
 	var showVersionLong = flag.Bool("version", false, "")
 	var showVersionShort = flag.Bool("v", false, "")
 	flag.Parse()
@@ -18,7 +37,7 @@ func main() {
 		os.Exit(0)
 	}
 
-	if err := run(os.Args); err != nil {
+	if err := run(flag.Args()); err != nil {
 		os.Exit(1)
 	}
 }
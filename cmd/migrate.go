@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pavelmemory/jobtome/internal/storage/migrations"
+)
+
+// migrate applies, reverts or lists the database migrations.
+func migrate(ctx context.Context, env env, args []string) error {
+	name, args, err := subcommand("migrate", args, "up", "down", "status")
+	if err != nil {
+		return err
+	}
+
+	flags := flag.NewFlagSet("migrate "+name, flag.ContinueOnError)
+	steps := flags.Int("steps", 1, "number of the most recent migrations to revert (down only)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	logger := env.logger.WithString("driver", env.settings.StorageDriver())
+
+	migrator, err := migrations.NewMigrator(env.settings.StorageDriver(), env.settings.StorageDSN())
+	if err != nil {
+		logger.WithError(err).Error("migrator initialization")
+		return err
+	}
+	defer migrator.Close()
+
+	switch name {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			logger.WithError(err).Error("apply migrations")
+			return err
+		}
+		fmt.Printf("applied %d migration(s)\n", applied)
+	case "down":
+		reverted, err := migrator.Down(ctx, *steps)
+		if err != nil {
+			logger.WithError(err).Error("revert migrations")
+			return err
+		}
+		fmt.Printf("reverted %d migration(s)\n", reverted)
+	default:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			logger.WithError(err).Error("migrations status")
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if !status.AppliedAt.IsZero() {
+				appliedAt = status.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return w.Flush()
+	}
+
+	return nil
+}
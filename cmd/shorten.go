@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	shortenserv "github.com/pavelmemory/jobtome/internal/shorten"
+)
+
+// shortenRecord is a JSON representation of the shorten used for the output and export/import.
+type shortenRecord struct {
+	ID        int64      `json:"id,omitempty"`
+	URL       string     `json:"url"`
+	Hash      string     `json:"hash"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	MaxClicks int64      `json:"max_clicks,omitempty"`
+	Tags      []string   `json:"tags,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	Workspace string     `json:"workspace,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	Clicks    int64      `json:"clicks,omitempty"`
+	Owner     string     `json:"owner,omitempty"`
+}
+
+func newShortenRecord(entity shortenserv.Entity) shortenRecord {
+	optional := func(t time.Time) *time.Time {
+		if t.IsZero() {
+			return nil
+		}
+		t = t.UTC()
+		return &t
+	}
+
+	return shortenRecord{
+		ID:        entity.ID,
+		URL:       entity.URL,
+		Hash:      entity.Hash,
+		ExpiresAt: optional(entity.ExpiresAt),
+		MaxClicks: entity.MaxClicks,
+		Tags:      entity.Tags,
+		UpdatedAt: optional(entity.UpdatedAt),
+		Workspace: entity.Workspace,
+		DeletedAt: optional(entity.DeletedAt),
+		Clicks:    entity.Clicks,
+		Owner:     entity.Owner,
+	}
+}
+
+func (r shortenRecord) entity() shortenserv.Entity {
+	entity := shortenserv.Entity{URL: r.URL, Hash: r.Hash, MaxClicks: r.MaxClicks, Tags: r.Tags, Workspace: r.Workspace, Owner: r.Owner}
+	if r.ExpiresAt != nil {
+		entity.ExpiresAt = *r.ExpiresAt
+	}
+
+	return entity
+}
+
+// shortenCommand manages shortens directly in the storage, so the server doesn't need to be running.
+func shortenCommand(ctx context.Context, env env, args []string) error {
+	name, args, err := subcommand("shorten", args, "create", "get", "list", "delete", "trash", "restore")
+	if err != nil {
+		return err
+	}
+
+	flags := flag.NewFlagSet("shorten "+name, flag.ContinueOnError)
+	var (
+		url       = flags.String("url", "", "URL to shorten (create only)")
+		alias     = flags.String("alias", "", "code chosen instead of the generated one (create only)")
+		expiresAt = flags.String("expires-at", "", "RFC3339 moment the shorten stops working (create only)")
+		maxClicks = flags.Int64("max-clicks", 0, "number of redirects allowed, 0 is unlimited (create only)")
+		limit     = flags.Int64("limit", 50, "max number of shortens to list (list and trash only)")
+		offset    = flags.Int64("offset", 0, "number of shortens to skip (list and trash only)")
+		afterID   = flags.Int64("after", 0, "ID of the shorten the page starts after, faster than -offset on large tables (list only)")
+		search    = flags.String("search", "", "part of the URL of the listed shortens (list only)")
+		domain    = flags.String("domain", "", "host of the URL of the listed shortens, its subdomains are matched too (list only)")
+		tag       = flags.String("tag", "", "tag of the listed shortens (list only)")
+		status    = flags.String("status", "", "status of the listed shortens: active, expired or disabled (list only)")
+		owner     = flags.String("owner", "", "owner of the created or the listed shortens (create and list only)")
+		permanent = flags.Bool("permanent", false, "remove the shorten permanently instead of moving it to the trash (delete only)")
+		workspace = flags.String("workspace", shortenserv.DefaultWorkspace, "workspace of the shortens")
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	ctx = shortenserv.WithOwner(shortenserv.WithWorkspace(ctx, *workspace), *owner)
+
+	db, err := openStorage(env.settings, env.logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	destinations, err := newDestinationPolicy(env.settings, env.logger)
+	if err != nil {
+		return err
+	}
+
+	service, err := newShortenService(env.settings, env.logger, db, destinations)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+
+	switch name {
+	case "create":
+		entity := shortenserv.Entity{URL: *url, Hash: *alias, MaxClicks: *maxClicks}
+		if *expiresAt != "" {
+			if entity.ExpiresAt, err = time.Parse(time.RFC3339, *expiresAt); err != nil {
+				return fmt.Errorf("flag -expires-at: %w", err)
+			}
+		}
+
+		id, err := service.Create(ctx, entity)
+		if err != nil {
+			env.logger.WithError(err).Error("creation of the shorten")
+			return err
+		}
+
+		return printShorten(ctx, env, service, encoder, id)
+	case "get":
+		id, err := idArg(flags)
+		if err != nil {
+			return err
+		}
+
+		return printShorten(ctx, env, service, encoder, id)
+	case "list", "trash":
+		pager := shortenserv.Pager{Limit: *limit, Offset: *offset, AfterID: *afterID}
+		filter := shortenserv.Filter{URL: *search, Domain: *domain, Tag: *tag, Status: *status, Owner: *owner}
+
+		var entities []shortenserv.Entity
+		if name == "trash" {
+			entities, err = service.Trash(ctx, pager)
+		} else {
+			entities, err = service.List(ctx, filter, pager)
+		}
+		if err != nil {
+			env.logger.WithError(err).Error("list shortens")
+			return err
+		}
+
+		for _, entity := range entities {
+			if err := encoder.Encode(newShortenRecord(entity)); err != nil {
+				return err
+			}
+		}
+	case "restore":
+		id, err := idArg(flags)
+		if err != nil {
+			return err
+		}
+
+		entity, err := service.Restore(ctx, id)
+		if err != nil {
+			env.logger.WithError(err).WithInt64("id", id).Error("restore shorten")
+			return err
+		}
+
+		return encoder.Encode(newShortenRecord(entity))
+	default:
+		id, err := idArg(flags)
+		if err != nil {
+			return err
+		}
+
+		remove := service.Delete
+		if *permanent {
+			remove = service.Purge
+		}
+
+		if err := remove(ctx, id); err != nil {
+			env.logger.WithError(err).WithInt64("id", id).Error("delete shorten")
+			return err
+		}
+	}
+
+	return nil
+}
+
+func printShorten(ctx context.Context, env env, service *shortenserv.Service, encoder *json.Encoder, id int64) error {
+	entity, err := service.Get(ctx, id)
+	if err != nil {
+		env.logger.WithError(err).WithInt64("id", id).Error(`get shorten by "id"`)
+		return err
+	}
+
+	return encoder.Encode(newShortenRecord(entity))
+}
+
+// idArg returns the ID of the shorten passed as the only positional argument.
+func idArg(flags *flag.FlagSet) (int64, error) {
+	if flags.NArg() != 1 {
+		return 0, errors.New("ID of the shorten is required")
+	}
+
+	id, err := strconv.ParseInt(flags.Arg(0), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("ID of the shorten: %w", err)
+	}
+
+	return id, nil
+}
+
+// subcommand returns a name of the subcommand that must be one of the `names` and its arguments.
+func subcommand(command string, args []string, names ...string) (string, []string, error) {
+	if len(args) > 0 {
+		for _, name := range names {
+			if args[0] == name {
+				return name, args[1:], nil
+			}
+		}
+	}
+
+	err := fmt.Errorf("usage: jobtome %s %v [flags]", command, names)
+	fmt.Fprintln(os.Stderr, err)
+	return "", nil, err
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pavelmemory/jobtome/internal"
+	shortenserv "github.com/pavelmemory/jobtome/internal/shorten"
+)
+
+// exportPageSize is a number of shortens retrieved from the storage at once during the export.
+const exportPageSize = 500
+
+// maxImportLineSize is a maximum size of the JSON line of the imported shorten.
+const maxImportLineSize = 1024 * 1024
+
+// export writes all shortens as JSON lines, so they could be restored with `importShortens`.
+func export(ctx context.Context, env env, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := flags.String("o", "", "file to write into, standard output by default")
+	workspace := flags.String("workspace", shortenserv.DefaultWorkspace, "workspace to export shortens of")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	ctx = shortenserv.WithWorkspace(ctx, *workspace)
+
+	db, err := openStorage(env.settings, env.logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	destinations, err := newDestinationPolicy(env.settings, env.logger)
+	if err != nil {
+		return err
+	}
+
+	service, err := newShortenService(env.settings, env.logger, db, destinations)
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("create output file: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	buffered := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buffered)
+
+	var exported, lastID int64
+	for {
+		entities, err := service.List(ctx, shortenserv.Filter{}, shortenserv.Pager{Limit: exportPageSize, AfterID: lastID})
+		if err != nil {
+			env.logger.WithError(err).Error("list shortens")
+			return err
+		}
+
+		for _, entity := range entities {
+			record := newShortenRecord(entity)
+			record.ID = 0 // IDs are not preserved by the import
+			if err := encoder.Encode(record); err != nil {
+				return fmt.Errorf("encode shorten: %w", err)
+			}
+		}
+		exported += int64(len(entities))
+
+		if len(entities) < exportPageSize {
+			break
+		}
+		lastID = entities[len(entities)-1].ID
+	}
+
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+
+	env.logger.WithInt64("exported", exported).Info("export completed")
+	return nil
+}
+
+// importShortens creates shortens from JSON lines written by `export`.
+func importShortens(ctx context.Context, env env, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	input := flags.String("i", "", "file to read from, standard input by default")
+	skipExisting := flags.Bool("skip-existing", false, "skip shortens with already taken codes instead of failing")
+	workspace := flags.String("workspace", shortenserv.DefaultWorkspace, "workspace of the imported shortens that don't have one")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	ctx = shortenserv.WithWorkspace(ctx, *workspace)
+
+	db, err := openStorage(env.settings, env.logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	destinations, err := newDestinationPolicy(env.settings, env.logger)
+	if err != nil {
+		return err
+	}
+
+	service, err := newShortenService(env.settings, env.logger, db, destinations)
+	if err != nil {
+		return err
+	}
+
+	r := io.Reader(os.Stdin)
+	if *input != "" {
+		file, err := os.Open(*input)
+		if err != nil {
+			return fmt.Errorf("open input file: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	var imported, skipped, line int64
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineSize)
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var record shortenRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("decode shorten on line %d: %w", line, err)
+		}
+
+		if _, err := service.Import(ctx, record.entity()); err != nil {
+			if *skipExisting && errors.Is(err, internal.ErrNotUnique) {
+				skipped++
+				continue
+			}
+			env.logger.WithError(err).WithString("hash", record.Hash).WithInt64("line", line).Error("import shorten")
+			return fmt.Errorf("import shorten on line %d: %w", line, err)
+		}
+		imported++
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read shorten on line %d: %w", line+1, err)
+	}
+
+	env.logger.WithInt64("imported", imported).WithInt64("skipped", skipped).Info("import completed")
+	return nil
+}
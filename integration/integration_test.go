@@ -88,4 +88,12 @@ func TestRedirect(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
 	require.Equal(t, "https://www.google.com", resp.Header.Get("location"))
+
+	// verify statistics
+	statsResp, err := httpClient.Get("http://localhost:8080" + location + "/stats")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, statsResp.StatusCode)
+	var stats map[string]interface{}
+	require.NoError(t, json.NewDecoder(statsResp.Body).Decode(&stats))
+	require.Contains(t, stats, "total")
 }
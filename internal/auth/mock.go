@@ -0,0 +1,132 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+
+// Package auth is a generated GoMock package.
+package auth
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	storage "github.com/pavelmemory/jobtome/internal/storage"
+	apikey "github.com/pavelmemory/jobtome/internal/storage/apikey"
+	reflect "reflect"
+)
+
+// MockTransactioner is a mock of Transactioner interface
+type MockTransactioner struct {
+	ctrl     *gomock.Controller
+	recorder *MockTransactionerMockRecorder
+}
+
+// MockTransactionerMockRecorder is the mock recorder for MockTransactioner
+type MockTransactionerMockRecorder struct {
+	mock *MockTransactioner
+}
+
+// NewMockTransactioner creates a new mock instance
+func NewMockTransactioner(ctrl *gomock.Controller) *MockTransactioner {
+	mock := &MockTransactioner{ctrl: ctrl}
+	mock.recorder = &MockTransactionerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockTransactioner) EXPECT() *MockTransactionerMockRecorder {
+	return m.recorder
+}
+
+// WithoutTx mocks base method
+func (m *MockTransactioner) WithoutTx(arg0 context.Context, arg1 func(storage.Runner) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithoutTx", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithoutTx indicates an expected call of WithoutTx
+func (mr *MockTransactionerMockRecorder) WithoutTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithoutTx", reflect.TypeOf((*MockTransactioner)(nil).WithoutTx), arg0, arg1)
+}
+
+// MockStorage is a mock of Storage interface
+type MockStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockStorageMockRecorder
+}
+
+// MockStorageMockRecorder is the mock recorder for MockStorage
+type MockStorageMockRecorder struct {
+	mock *MockStorage
+}
+
+// NewMockStorage creates a new mock instance
+func NewMockStorage(ctrl *gomock.Controller) *MockStorage {
+	mock := &MockStorage{ctrl: ctrl}
+	mock.recorder = &MockStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockStorage) EXPECT() *MockStorageMockRecorder {
+	return m.recorder
+}
+
+// Persist mocks base method
+func (m *MockStorage) Persist(ctx context.Context, run storage.Runner, key apikey.Entity) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Persist", ctx, run, key)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Persist indicates an expected call of Persist
+func (mr *MockStorageMockRecorder) Persist(ctx, run, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Persist", reflect.TypeOf((*MockStorage)(nil).Persist), ctx, run, key)
+}
+
+// ByHash mocks base method
+func (m *MockStorage) ByHash(ctx context.Context, run storage.Runner, hash string) (apikey.Entity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ByHash", ctx, run, hash)
+	ret0, _ := ret[0].(apikey.Entity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ByHash indicates an expected call of ByHash
+func (mr *MockStorageMockRecorder) ByHash(ctx, run, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ByHash", reflect.TypeOf((*MockStorage)(nil).ByHash), ctx, run, hash)
+}
+
+// List mocks base method
+func (m *MockStorage) List(ctx context.Context, run storage.Runner, workspace string) ([]apikey.Entity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, run, workspace)
+	ret0, _ := ret[0].([]apikey.Entity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List
+func (mr *MockStorageMockRecorder) List(ctx, run, workspace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockStorage)(nil).List), ctx, run, workspace)
+}
+
+// Revoke mocks base method
+func (m *MockStorage) Revoke(ctx context.Context, run storage.Runner, workspace string, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, run, workspace, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke
+func (mr *MockStorageMockRecorder) Revoke(ctx, run, workspace, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockStorage)(nil).Revoke), ctx, run, workspace, id)
+}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pavelmemory/jobtome/internal"
+)
+
+// Scope is a permission granted to the API key.
+type Scope string
+
+const (
+	// ScopeShortenRead allows to get and list shortens and their statistics.
+	ScopeShortenRead Scope = "shorten:read"
+	// ScopeShortenWrite allows to create and update shortens.
+	ScopeShortenWrite Scope = "shorten:write"
+	// ScopeShortenDelete allows to delete shortens.
+	ScopeShortenDelete Scope = "shorten:delete"
+	// ScopeAdmin allows everything including management of the API keys of its workspace.
+	ScopeAdmin Scope = "admin"
+	// ScopeOperator allows everything the admin scope does and management of the API keys of all workspaces.
+	ScopeOperator Scope = "operator"
+)
+
+// Scopes is a list of all known scopes.
+var Scopes = []Scope{ScopeShortenRead, ScopeShortenWrite, ScopeShortenDelete, ScopeAdmin, ScopeOperator}
+
+// ParseScopes converts the `vals` into the scopes, all of them must be known.
+func ParseScopes(vals []string) ([]Scope, error) {
+	scopes := make([]Scope, 0, len(vals))
+	for _, val := range vals {
+		scope, known := Scope(val), false
+		for _, s := range Scopes {
+			known = known || s == scope
+		}
+		if !known {
+			return nil, fmt.Errorf("%w: unknown scope %q", internal.ErrBadInput, val)
+		}
+		scopes = append(scopes, scope)
+	}
+
+	return scopes, nil
+}
+
+type ctxKey struct{}
+
+// ToContext returns a copy of the `ctx` with the authenticated `key`.
+func ToContext(ctx context.Context, key Key) context.Context {
+	return context.WithValue(ctx, ctxKey{}, key)
+}
+
+// FromContext returns the authenticated key and reports if it is present in the `ctx`.
+func FromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(ctxKey{}).(Key)
+	return key, ok
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/storage"
+	"github.com/pavelmemory/jobtome/internal/storage/apikey"
+)
+
+// secretPrefix marks the API keys issued by the service, so they could be recognized by secret scanners.
+const secretPrefix = "jt_"
+
+// workspaceRe defines the allowed names of the workspaces.
+var workspaceRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Key is an API key without its secret.
+type Key struct {
+	ID     int64
+	Name   string
+	Scopes []Scope
+	// Workspace is a workspace the key manages shortens of.
+	Workspace string
+	// CreatedAt is a moment the key was issued.
+	CreatedAt time.Time
+	// RevokedAt is a moment the key was revoked, zero value means the key is active.
+	RevokedAt time.Time
+}
+
+// Allows reports if the key has the `scope` granted explicitly or by the admin scope.
+func (k Key) Allows(scope Scope) bool {
+	for _, granted := range k.Scopes {
+		if granted == scope || granted == ScopeOperator || (granted == ScopeAdmin && scope != ScopeOperator) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Manages reports if the key is allowed to manage the API keys of the `workspace`.
+func (k Key) Manages(workspace string) bool {
+	return k.Allows(ScopeOperator) || (k.Allows(ScopeAdmin) && k.Workspace == workspace)
+}
+
+//go:generate mockgen -source=service.go -destination mock.go -package auth Storage
+
+// Transactioner executes statements without explicitly open transaction.
+type Transactioner interface {
+	// WithoutTx executes provided callback without explicitly open transaction.
+	WithoutTx(context.Context, func(runner storage.Runner) error) error
+}
+
+// Storage is a persistence storage for the API keys.
+type Storage interface {
+	// Persist saves the key and returns it's unique generated ID.
+	Persist(ctx context.Context, run storage.Runner, key apikey.Entity) (int64, error)
+	// ByHash returns the active key by the digest of its secret.
+	ByHash(ctx context.Context, run storage.Runner, hash string) (apikey.Entity, error)
+	// List returns the keys of the workspace including the revoked ones, the empty workspace matches all keys.
+	List(ctx context.Context, run storage.Runner, workspace string) ([]apikey.Entity, error)
+	// Revoke marks the active key of the workspace as revoked, the empty workspace matches all keys.
+	Revoke(ctx context.Context, run storage.Runner, workspace string, id int64) error
+}
+
+// NewService returns initialized API keys service.
+func NewService(tr Transactioner, storage Storage) *Service {
+	return &Service{tr: tr, storage: storage}
+}
+
+// Service issues, authenticates and revokes API keys.
+// Only digests of the keys are stored, so the secret of the key is known only to the caller it was issued to.
+type Service struct {
+	tr      Transactioner
+	storage Storage
+}
+
+// Issue creates a new API key with the `scopes` in the `workspace` and returns it together with its secret.
+// The secret can't be retrieved later.
+func (s *Service) Issue(ctx context.Context, name, workspace string, scopes []Scope) (Key, string, error) {
+	if strings.TrimSpace(name) == "" {
+		return Key{}, "", fmt.Errorf("%w: name is blank", internal.ErrBadInput)
+	}
+
+	if !workspaceRe.MatchString(workspace) {
+		return Key{}, "", fmt.Errorf("%w: workspace must match %s", internal.ErrBadInput, workspaceRe)
+	}
+
+	if len(scopes) == 0 {
+		return Key{}, "", fmt.Errorf("%w: no scopes", internal.ErrBadInput)
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return Key{}, "", fmt.Errorf("generate secret: %w", err)
+	}
+	secret := secretPrefix + base64.RawURLEncoding.EncodeToString(random)
+
+	entity := apikey.Entity{Name: name, Hash: digest(secret), Scopes: make([]string, len(scopes)), Workspace: workspace}
+	for i, scope := range scopes {
+		entity.Scopes[i] = string(scope)
+	}
+
+	if err := s.tr.WithoutTx(ctx, func(runner storage.Runner) (err error) {
+		entity.ID, err = s.storage.Persist(ctx, runner, entity)
+		return err
+	}); err != nil {
+		return Key{}, "", fmt.Errorf("persist api key: %w", err)
+	}
+	entity.CreatedAt = time.Now()
+
+	return serviceKey(entity), secret, nil
+}
+
+// Authenticate returns the active API key with the `secret`.
+// It returns `internal.ErrUnauthenticated` if there is no such key.
+func (s *Service) Authenticate(ctx context.Context, secret string) (Key, error) {
+	if !strings.HasPrefix(secret, secretPrefix) {
+		return Key{}, internal.ErrUnauthenticated
+	}
+
+	var entity apikey.Entity
+	if err := s.tr.WithoutTx(ctx, func(runner storage.Runner) (err error) {
+		entity, err = s.storage.ByHash(ctx, runner, digest(secret))
+		return err
+	}); err != nil {
+		if errors.Is(err, internal.ErrNotFound) {
+			return Key{}, internal.ErrUnauthenticated
+		}
+		return Key{}, fmt.Errorf("retrieve api key: %w", err)
+	}
+
+	return serviceKey(entity), nil
+}
+
+// List returns API keys of the `workspace` including the revoked ones.
+// The empty `workspace` returns the keys of all workspaces.
+func (s *Service) List(ctx context.Context, workspace string) ([]Key, error) {
+	var entities []apikey.Entity
+	if err := s.tr.WithoutTx(ctx, func(runner storage.Runner) (err error) {
+		entities, err = s.storage.List(ctx, runner, workspace)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+
+	keys := make([]Key, len(entities))
+	for i, entity := range entities {
+		keys[i] = serviceKey(entity)
+	}
+
+	return keys, nil
+}
+
+// Revoke disables the API key with the `id` of the `workspace`, the key is kept for the audit purposes.
+// The empty `workspace` matches the key of any workspace.
+// It returns `internal.ErrNotFound` if the key belongs to another workspace.
+func (s *Service) Revoke(ctx context.Context, workspace string, id int64) error {
+	if err := s.tr.WithoutTx(ctx, func(runner storage.Runner) error {
+		return s.storage.Revoke(ctx, runner, workspace, id)
+	}); err != nil {
+		return fmt.Errorf("revoke api key %d: %w", id, err)
+	}
+
+	return nil
+}
+
+// digest returns a hex encoded SHA-256 digest of the `secret`.
+// The secrets are random, so a fast digest without salt is enough to protect them.
+func digest(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func serviceKey(entity apikey.Entity) Key {
+	key := Key{
+		ID:        entity.ID,
+		Name:      entity.Name,
+		Scopes:    make([]Scope, len(entity.Scopes)),
+		Workspace: entity.Workspace,
+		CreatedAt: entity.CreatedAt,
+		RevokedAt: entity.RevokedAt,
+	}
+	for i, scope := range entity.Scopes {
+		key.Scopes[i] = Scope(scope)
+	}
+
+	return key
+}
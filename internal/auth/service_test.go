@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/storage"
+	"github.com/pavelmemory/jobtome/internal/storage/apikey"
+)
+
+func TestService_Issue(t *testing.T) {
+	t.Run("validation", func(t *testing.T) {
+		srv := NewService(nil, nil)
+
+		_, _, err := srv.Issue(context.Background(), " ", "default", []Scope{ScopeAdmin})
+		require.True(t, errors.Is(err, internal.ErrBadInput), err)
+
+		_, _, err = srv.Issue(context.Background(), "ci", "default", nil)
+		require.True(t, errors.Is(err, internal.ErrBadInput), err)
+
+		_, _, err = srv.Issue(context.Background(), "ci", "Marketing Team", []Scope{ScopeAdmin})
+		require.True(t, errors.Is(err, internal.ErrBadInput), err)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var persisted apikey.Entity
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().Persist(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ storage.Runner, entity apikey.Entity) (int64, error) {
+				persisted = entity
+				return 1, nil
+			})
+
+		srv := NewService(testTransactioner{}, mockStorage)
+		key, secret, err := srv.Issue(context.Background(), "ci", "marketing", []Scope{ScopeShortenRead, ScopeShortenWrite})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), key.ID)
+		require.Equal(t, "ci", key.Name)
+		require.Equal(t, []Scope{ScopeShortenRead, ScopeShortenWrite}, key.Scopes)
+		require.Equal(t, "marketing", key.Workspace)
+		require.True(t, strings.HasPrefix(secret, secretPrefix), secret)
+
+		require.Equal(t, []string{"shorten:read", "shorten:write"}, persisted.Scopes)
+		require.Equal(t, digest(secret), persisted.Hash)
+		require.Equal(t, "marketing", persisted.Workspace)
+		require.False(t, strings.Contains(persisted.Hash, secret))
+	})
+}
+
+func TestService_Authenticate(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), digest("jt_secret")).
+			Return(apikey.Entity{ID: 1, Name: "ci", Scopes: []string{"shorten:read"}}, nil)
+
+		srv := NewService(testTransactioner{}, mockStorage)
+		key, err := srv.Authenticate(context.Background(), "jt_secret")
+		require.NoError(t, err)
+		require.Equal(t, Key{ID: 1, Name: "ci", Scopes: []Scope{ScopeShortenRead}}, key)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), gomock.Any()).Return(apikey.Entity{}, internal.ErrNotFound)
+
+		srv := NewService(testTransactioner{}, mockStorage)
+		_, err := srv.Authenticate(context.Background(), "jt_revoked")
+		require.Equal(t, internal.ErrUnauthenticated, err)
+	})
+
+	t.Run("foreign", func(t *testing.T) {
+		srv := NewService(testTransactioner{}, nil)
+		_, err := srv.Authenticate(context.Background(), "secret")
+		require.Equal(t, internal.ErrUnauthenticated, err)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), gomock.Any()).Return(apikey.Entity{}, errors.New("connection lost"))
+
+		srv := NewService(testTransactioner{}, mockStorage)
+		_, err := srv.Authenticate(context.Background(), "jt_secret")
+		require.EqualError(t, err, "retrieve api key: connection lost")
+	})
+}
+
+func TestKey_Allows(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		scopes  []Scope
+		scope   Scope
+		allowed bool
+	}{
+		{name: "granted", scopes: []Scope{ScopeShortenRead}, scope: ScopeShortenRead, allowed: true},
+		{name: "not granted", scopes: []Scope{ScopeShortenRead}, scope: ScopeShortenDelete},
+		{name: "admin", scopes: []Scope{ScopeAdmin}, scope: ScopeShortenDelete, allowed: true},
+		{name: "admin not operator", scopes: []Scope{ScopeAdmin}, scope: ScopeOperator},
+		{name: "operator", scopes: []Scope{ScopeOperator}, scope: ScopeAdmin, allowed: true},
+		{name: "no scopes", scope: ScopeShortenRead},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.allowed, Key{Scopes: tc.scopes}.Allows(tc.scope))
+		})
+	}
+}
+
+func TestKey_Manages(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		scopes    []Scope
+		workspace string
+		manages   bool
+	}{
+		{name: "admin", scopes: []Scope{ScopeAdmin}, workspace: "marketing", manages: true},
+		{name: "admin of other workspace", scopes: []Scope{ScopeAdmin}, workspace: "default"},
+		{name: "operator", scopes: []Scope{ScopeOperator}, workspace: "default", manages: true},
+		{name: "not admin", scopes: []Scope{ScopeShortenWrite}, workspace: "marketing"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.manages, Key{Scopes: tc.scopes, Workspace: "marketing"}.Manages(tc.workspace))
+		})
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := ParseScopes([]string{"shorten:read", "admin"})
+	require.NoError(t, err)
+	require.Equal(t, []Scope{ScopeShortenRead, ScopeAdmin}, scopes)
+
+	_, err = ParseScopes([]string{"shorten:read", "root"})
+	require.True(t, errors.Is(err, internal.ErrBadInput), err)
+}
+
+type testTransactioner struct{}
+
+func (testTransactioner) WithoutTx(_ context.Context, call func(runner storage.Runner) error) error {
+	return call(nil)
+}
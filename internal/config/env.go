@@ -1,7 +1,13 @@
 package config
 
 import (
+	"fmt"
+	"strconv"
+	"time"
+
 	"github.com/kelseyhightower/envconfig"
+
+	"github.com/pavelmemory/jobtome/internal/ratelimit"
 )
 
 // NewEnvSettings returns EnvSettings initialized from environment variables.
@@ -13,9 +19,53 @@ func NewEnvSettings(prefix string) (EnvSettings, error) {
 
 // EnvSettings reads settings from environment variables.
 type EnvSettings struct {
-	EnvHTTPListenPort  int    `envconfig:"HTTP_PORT" default:"8080"`
-	EnvLogLevel        string `envconfig:"LOG_LEVEL" default:"info"`
-	EnvStorageFilePath string `envconfig:"STORAGE_FILEPATH" default:"jobtome.dat"`
+	EnvHTTPListenPort  int           `envconfig:"HTTP_PORT" default:"8080"`
+	EnvHTTPBindAddr    string        `envconfig:"HTTP_BIND_ADDRESS"`
+	EnvResolverPort    int           `envconfig:"RESOLVER_PORT" default:"80"`
+	EnvResolverAddr    string        `envconfig:"RESOLVER_BIND_ADDRESS"`
+	EnvResolverHosts   []string      `envconfig:"RESOLVER_HOSTS"`
+	EnvPublicBaseURL   string        `envconfig:"PUBLIC_BASE_URL"`
+	EnvLogLevel        string        `envconfig:"LOG_LEVEL" default:"info"`
+	EnvStorageDriver   string        `envconfig:"STORAGE_DRIVER" default:"sqlite3"`
+	EnvStorageDSN      string        `envconfig:"STORAGE_DSN"`
+	EnvStorageFilePath string        `envconfig:"STORAGE_FILEPATH" default:"jobtome.dat"`
+	EnvCodeStrategy    string        `envconfig:"SHORTEN_CODE_STRATEGY" default:"md5"`
+	EnvCodeAlphabet    string        `envconfig:"SHORTEN_CODE_ALPHABET"`
+	EnvCodeLength      int           `envconfig:"SHORTEN_CODE_LENGTH" default:"7"`
+	EnvCodeSalt        string        `envconfig:"SHORTEN_CODE_SALT"`
+	EnvAliasMinLength  int           `envconfig:"SHORTEN_ALIAS_MIN_LENGTH" default:"3"`
+	EnvAliasMaxLength  int           `envconfig:"SHORTEN_ALIAS_MAX_LENGTH" default:"64"`
+	EnvAliasReserved   []string      `envconfig:"SHORTEN_ALIAS_RESERVED"`
+	EnvURLSchemes      []string      `envconfig:"SHORTEN_URL_SCHEMES" default:"http,https"`
+	EnvURLSortQuery    bool          `envconfig:"SHORTEN_URL_SORT_QUERY" default:"false"`
+	EnvURLStripFrag    bool          `envconfig:"SHORTEN_URL_STRIP_FRAGMENT" default:"false"`
+	EnvSweepInterval   time.Duration `envconfig:"SHORTEN_SWEEP_INTERVAL" default:"1m"`
+	EnvSweepGrace      time.Duration `envconfig:"SHORTEN_SWEEP_GRACE" default:"24h"`
+	EnvSweepMode       string        `envconfig:"SHORTEN_SWEEP_MODE" default:"archive"`
+	EnvTrashRetention  time.Duration `envconfig:"SHORTEN_TRASH_RETENTION" default:"720h"`
+	EnvTrashPurge      time.Duration `envconfig:"SHORTEN_TRASH_PURGE_INTERVAL" default:"1h"`
+	EnvCacheSize       int           `envconfig:"SHORTEN_CACHE_SIZE" default:"10000"`
+	EnvCacheTTL        time.Duration `envconfig:"SHORTEN_CACHE_TTL" default:"1m"`
+	EnvCacheNegTTL     time.Duration `envconfig:"SHORTEN_CACHE_NEGATIVE_TTL" default:"5s"`
+	EnvAuthEnabled     bool          `envconfig:"API_AUTH_ENABLED" default:"true"`
+	EnvTraceExporter   string        `envconfig:"TRACING_EXPORTER" default:"none"`
+	EnvTraceEndpoint   string        `envconfig:"TRACING_ENDPOINT"`
+	EnvTraceSample     float64       `envconfig:"TRACING_SAMPLE_RATIO" default:"1"`
+	EnvAPIIPRate       float64       `envconfig:"RATE_LIMIT_API_IP_RATE" default:"50"`
+	EnvAPIIPBurst      int           `envconfig:"RATE_LIMIT_API_IP_BURST" default:"100"`
+	EnvAPIKeyRate      float64       `envconfig:"RATE_LIMIT_API_KEY_RATE" default:"20"`
+	EnvAPIKeyBurst     int           `envconfig:"RATE_LIMIT_API_KEY_BURST" default:"40"`
+	EnvResolverIPRate  float64       `envconfig:"RATE_LIMIT_RESOLVER_IP_RATE" default:"10"`
+	EnvResolverIPBurst int           `envconfig:"RATE_LIMIT_RESOLVER_IP_BURST" default:"20"`
+	EnvTrustedProxies  []string      `envconfig:"RATE_LIMIT_TRUSTED_PROXIES"`
+	EnvOpenAPIUI       bool          `envconfig:"OPENAPI_UI_ENABLED" default:"false"`
+	EnvHealthTimeout   time.Duration `envconfig:"HEALTH_CHECK_TIMEOUT" default:"2s"`
+	EnvHealthMinDisk   uint64        `envconfig:"HEALTH_MIN_FREE_DISK_MB" default:"64"`
+	EnvShutdownDelay   time.Duration `envconfig:"SHUTDOWN_DELAY" default:"5s"`
+	EnvPolicyFile      string        `envconfig:"POLICY_FILE"`
+	EnvPolicyReload    time.Duration `envconfig:"POLICY_RELOAD_INTERVAL" default:"10s"`
+	EnvPolicyResolve   bool          `envconfig:"POLICY_CHECK_RESOLVE" default:"true"`
+	EnvPolicyPrivate   bool          `envconfig:"POLICY_ALLOW_PRIVATE" default:"false"`
 }
 
 // HTTPPort returns a port number to listening for incoming HTTP connections.
@@ -23,12 +73,251 @@ func (es EnvSettings) HTTPPort() int {
 	return es.EnvHTTPListenPort
 }
 
+// HTTPBindAddress returns an IP or a host name of the interface the API listens on, empty means all interfaces.
+func (es EnvSettings) HTTPBindAddress() string {
+	return es.EnvHTTPBindAddr
+}
+
+// ResolverPort returns port number to listen for the redirect requests.
+func (es EnvSettings) ResolverPort() int {
+	return es.EnvResolverPort
+}
+
+// ResolverBindAddress returns an IP or a host name of the interface the redirects are served on, empty means all interfaces.
+func (es EnvSettings) ResolverBindAddress() string {
+	return es.EnvResolverAddr
+}
+
+// ResolverHosts returns the short domains of the redirects. If set, the API and the redirects are served
+// on the API port and dispatched by the `Host` header of the request, the `ResolverPort` isn't used.
+func (es EnvSettings) ResolverHosts() []string {
+	return es.EnvResolverHosts
+}
+
+// PublicBaseURL returns a URL of the resolver the short URLs are built from, e.g. `https://sho.rt`.
+// By default it is the first of `ResolverHosts` or the local resolver port.
+func (es EnvSettings) PublicBaseURL() string {
+	switch {
+	case es.EnvPublicBaseURL != "":
+		return es.EnvPublicBaseURL
+	case len(es.EnvResolverHosts) > 0:
+		return "http://" + es.EnvResolverHosts[0]
+	case es.EnvResolverPort == 80:
+		return "http://localhost"
+	default:
+		return "http://localhost:" + strconv.Itoa(es.EnvResolverPort)
+	}
+}
+
 // LogLevel returns a logging level.
 func (es EnvSettings) LogLevel() string {
 	return es.EnvLogLevel
 }
 
+// StorageDriver returns a name of the database driver: `sqlite3` or `postgres`.
+func (es EnvSettings) StorageDriver() string {
+	return es.EnvStorageDriver
+}
+
+// StorageDSN returns a data source name to connect to the database.
+// For SQLite it defaults to the `StorageFilePath`.
+func (es EnvSettings) StorageDSN() string {
+	if es.EnvStorageDSN == "" && es.EnvStorageDriver == "sqlite3" {
+		return es.EnvStorageFilePath
+	}
+	return es.EnvStorageDSN
+}
+
 // StorageFilePath returns path to the file to use as persisted storage.
 func (es EnvSettings) StorageFilePath() string {
 	return es.EnvStorageFilePath
 }
+
+// CodeStrategy returns a name of the strategy used to generate codes for the new shortens.
+func (es EnvSettings) CodeStrategy() string {
+	return es.EnvCodeStrategy
+}
+
+// CodeAlphabet returns a set of characters the codes consist of.
+// An empty value means the default alphabet of the code strategy.
+func (es EnvSettings) CodeAlphabet() string {
+	return es.EnvCodeAlphabet
+}
+
+// CodeLength returns a length of the generated codes.
+func (es EnvSettings) CodeLength() int {
+	return es.EnvCodeLength
+}
+
+// CodeSalt returns a secret used to obfuscate the generated codes.
+func (es EnvSettings) CodeSalt() string {
+	return es.EnvCodeSalt
+}
+
+// AliasMinLength returns a minimal length of the alias chosen by the user.
+func (es EnvSettings) AliasMinLength() int {
+	return es.EnvAliasMinLength
+}
+
+// AliasMaxLength returns a maximal length of the alias chosen by the user.
+func (es EnvSettings) AliasMaxLength() int {
+	return es.EnvAliasMaxLength
+}
+
+// AliasReserved returns a list of words that can't be used as aliases in addition to the default ones.
+func (es EnvSettings) AliasReserved() []string {
+	return es.EnvAliasReserved
+}
+
+// URLSchemes returns the schemes of the URLs allowed to be shortened.
+func (es EnvSettings) URLSchemes() []string {
+	return es.EnvURLSchemes
+}
+
+// URLSortQuery reports if the query parameters of the URLs are sorted by name before shortening.
+func (es EnvSettings) URLSortQuery() bool {
+	return es.EnvURLSortQuery
+}
+
+// URLStripFragment reports if the fragments of the URLs are removed before shortening.
+func (es EnvSettings) URLStripFragment() bool {
+	return es.EnvURLStripFrag
+}
+
+// SweepInterval returns an interval between removals of the expired shortens.
+// Non-positive value disables the removal.
+func (es EnvSettings) SweepInterval() time.Duration {
+	return es.EnvSweepInterval
+}
+
+// SweepGrace returns a period the expired shorten is kept before removal.
+func (es EnvSettings) SweepGrace() time.Duration {
+	return es.EnvSweepGrace
+}
+
+// SweepArchive reports if the expired shortens are moved into the archive instead of being deleted.
+// It returns an error if the sweep mode is neither `archive` nor `delete`.
+func (es EnvSettings) SweepArchive() (bool, error) {
+	switch es.EnvSweepMode {
+	case "archive":
+		return true, nil
+	case "delete":
+		return false, nil
+	default:
+		return false, fmt.Errorf("sweep mode %q is unknown, archive or delete is expected", es.EnvSweepMode)
+	}
+}
+
+// TrashRetention returns a period the deleted shortens are kept in the trash before they are purged,
+// zero value keeps them forever.
+func (es EnvSettings) TrashRetention() time.Duration {
+	return es.EnvTrashRetention
+}
+
+// TrashPurgeInterval returns an interval between removals of the deleted shortens from the trash.
+// Non-positive value disables the removal.
+func (es EnvSettings) TrashPurgeInterval() time.Duration {
+	return es.EnvTrashPurge
+}
+
+// CacheSize returns a number of shortens cached for redirects.
+// Non-positive value disables the cache.
+func (es EnvSettings) CacheSize() int {
+	return es.EnvCacheSize
+}
+
+// CacheTTL returns a period the shorten is cached for redirects.
+func (es EnvSettings) CacheTTL() time.Duration {
+	return es.EnvCacheTTL
+}
+
+// CacheNegativeTTL returns a period the unknown code is cached for redirects.
+// Non-positive value disables caching of the unknown codes.
+func (es EnvSettings) CacheNegativeTTL() time.Duration {
+	return es.EnvCacheNegTTL
+}
+
+// TracingExporter returns a name of the exporter of the traces: none, stdout, file or otlp.
+func (es EnvSettings) TracingExporter() string {
+	return es.EnvTraceExporter
+}
+
+// TracingEndpoint returns a path to the traces file or a URL of the OpenTelemetry collector.
+func (es EnvSettings) TracingEndpoint() string {
+	return es.EnvTraceEndpoint
+}
+
+// TracingSampleRatio returns a fraction of the traces started by the service that are exported.
+func (es EnvSettings) TracingSampleRatio() float64 {
+	return es.EnvTraceSample
+}
+
+// AuthEnabled reports if the API requests must be made with API keys.
+func (es EnvSettings) AuthEnabled() bool {
+	return es.EnvAuthEnabled
+}
+
+// APIRateLimitPerIP returns a limit of the API requests made from the same client IP.
+// Non-positive rate or burst disables the limit.
+func (es EnvSettings) APIRateLimitPerIP() ratelimit.Policy {
+	return ratelimit.Policy{Rate: es.EnvAPIIPRate, Burst: es.EnvAPIIPBurst}
+}
+
+// APIRateLimitPerKey returns a limit of the API requests made with the same API key.
+// Non-positive rate or burst disables the limit.
+func (es EnvSettings) APIRateLimitPerKey() ratelimit.Policy {
+	return ratelimit.Policy{Rate: es.EnvAPIKeyRate, Burst: es.EnvAPIKeyBurst}
+}
+
+// ResolverRateLimitPerIP returns a limit of the redirects made for the same client IP.
+// Non-positive rate or burst disables the limit.
+func (es EnvSettings) ResolverRateLimitPerIP() ratelimit.Policy {
+	return ratelimit.Policy{Rate: es.EnvResolverIPRate, Burst: es.EnvResolverIPBurst}
+}
+
+// TrustedProxies returns CIDRs or IPs of the reverse proxies allowed to set the client IP with `X-Forwarded-For` header.
+func (es EnvSettings) TrustedProxies() []string {
+	return es.EnvTrustedProxies
+}
+
+// OpenAPIUIEnabled reports if Swagger UI page of the API specification is served.
+func (es EnvSettings) OpenAPIUIEnabled() bool {
+	return es.EnvOpenAPIUI
+}
+
+// HealthCheckTimeout returns a duration after which a health check is considered failed.
+func (es EnvSettings) HealthCheckTimeout() time.Duration {
+	return es.EnvHealthTimeout
+}
+
+// HealthMinFreeDisk returns a number of bytes that must be available on the disk holding the SQLite database file.
+func (es EnvSettings) HealthMinFreeDisk() uint64 {
+	return es.EnvHealthMinDisk << 20
+}
+
+// ShutdownDelay returns a duration between the readiness starts failing and the servers stop,
+// so the load balancers have time to stop routing new requests to the service.
+func (es EnvSettings) ShutdownDelay() time.Duration {
+	return es.EnvShutdownDelay
+}
+
+// PolicyFile returns a path to the file with the rules of the destination policy, empty means no rules.
+func (es EnvSettings) PolicyFile() string {
+	return es.EnvPolicyFile
+}
+
+// PolicyReloadInterval returns how often the policy file is checked for changes, zero disables the reload.
+func (es EnvSettings) PolicyReloadInterval() time.Duration {
+	return es.EnvPolicyReload
+}
+
+// PolicyCheckResolve reports if the destinations are checked on each redirect,
+// so the shortens created before the rules changed are blocked too.
+func (es EnvSettings) PolicyCheckResolve() bool {
+	return es.EnvPolicyResolve
+}
+
+// PolicyAllowPrivate reports if the shortens could redirect to `localhost` and private IP addresses.
+func (es EnvSettings) PolicyAllowPrivate() bool {
+	return es.EnvPolicyPrivate
+}
@@ -13,3 +13,18 @@ var ErrNotUnique = errors.New("not unique")
 
 // ErrNotFound shows that the requested value was not found (or doesn't exist).
 var ErrNotFound = errors.New("not found")
+
+// ErrExpired shows that the requested value existed but is not available anymore.
+var ErrExpired = errors.New("expired")
+
+// ErrUnauthenticated shows that the caller didn't provide valid credentials.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// ErrForbidden shows that the caller is not allowed to perform the operation.
+var ErrForbidden = errors.New("forbidden")
+
+// ErrRateLimited shows that the caller made too many requests and needs to retry later.
+var ErrRateLimited = errors.New("rate limited")
+
+// ErrBlocked shows that the value is not allowed by the policy of the service, e.g. it is used for phishing.
+var ErrBlocked = errors.New("blocked")
@@ -0,0 +1,57 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/pavelmemory/jobtome/internal/storage"
+	"github.com/pavelmemory/jobtome/internal/storage/migrations"
+)
+
+// Ping returns a check that verifies the database of the `pool` is reachable.
+func Ping(pool storage.Pool) Check {
+	return func(ctx context.Context) error {
+		if err := pool.Ping(ctx); err != nil {
+			return fmt.Errorf("ping: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// Migrations returns a check that verifies all known migrations are applied to the database of the `pool`.
+// As it reads from the database it also fails if the database file is locked by another process.
+func Migrations(pool storage.Pool) Check {
+	return func(ctx context.Context) error {
+		return pool.WithoutTx(ctx, func(runner storage.Runner) error {
+			current, err := migrations.Current(ctx, runner)
+			if err != nil {
+				return err
+			}
+
+			if latest := migrations.Latest(); current != latest {
+				return fmt.Errorf("migrations are at version %d, expected %d", current, latest)
+			}
+
+			return nil
+		})
+	}
+}
+
+// DiskSpace returns a check that verifies the disk holding the `path` file has at least `minFree` bytes available.
+func DiskSpace(path string, minFree uint64) Check {
+	dir := filepath.Dir(path)
+	return func(context.Context) error {
+		free, err := freeSpace(dir)
+		if err != nil {
+			return fmt.Errorf("free disk space of %q: %w", dir, err)
+		}
+
+		if free < minFree {
+			return fmt.Errorf("%d bytes available on disk, at least %d required", free, minFree)
+		}
+
+		return nil
+	}
+}
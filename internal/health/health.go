@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Statuses of the checks and of the service.
+const (
+	// StatusUp means the check passed.
+	StatusUp = "up"
+	// StatusDown means the check failed, so the service can't serve requests.
+	StatusDown = "down"
+)
+
+// ErrShuttingDown shows that the service stops and must not receive new requests.
+var ErrShuttingDown = errors.New("shutting down")
+
+// Check verifies a single dependency of the service, a nil error means it is healthy.
+type Check func(ctx context.Context) error
+
+// Result is an outcome of a single check.
+type Result struct {
+	Name    string
+	Status  string
+	Latency time.Duration
+	// Err is a failure of the check, nil if the check passed.
+	Err error
+}
+
+// Report is an outcome of all checks.
+type Report struct {
+	// Status is `StatusUp` only if all the checks passed.
+	Status string
+	// Checks are the results in order the checks were added.
+	Checks []Result
+}
+
+// NewChecker returns a checker that fails the checks running longer than the `timeout`.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout, now: time.Now}
+}
+
+// Checker runs a set of checks to tell if the service is ready to serve requests.
+type Checker struct {
+	timeout      time.Duration
+	now          func() time.Time
+	names        []string
+	checks       []Check
+	shuttingDown int32
+}
+
+// Add registers the `check` under the `name`, all checks must be added before the first run.
+func (c *Checker) Add(name string, check Check) {
+	c.names = append(c.names, name)
+	c.checks = append(c.checks, check)
+}
+
+// Shutdown makes all further runs fail, so the load balancers stop routing new requests to the service
+// while the ongoing ones are finished.
+func (c *Checker) Shutdown() {
+	atomic.StoreInt32(&c.shuttingDown, 1)
+}
+
+// Run runs all the checks concurrently and returns their results.
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{Status: StatusUp, Checks: make([]Result, len(c.checks)+1)}
+
+	report.Checks[0] = Result{Name: "shutdown", Status: StatusUp}
+	if atomic.LoadInt32(&c.shuttingDown) == 1 {
+		report.Checks[0].Status, report.Checks[0].Err = StatusDown, ErrShuttingDown
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := range c.checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			report.Checks[i+1] = c.run(ctx, c.names[i], c.checks[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+
+	return report
+}
+
+// run runs a single `check`, the check that doesn't finish in time is reported as failed without waiting for it.
+func (c *Checker) run(ctx context.Context, name string, check Check) Result {
+	start := c.now()
+	done := make(chan error, 1)
+	go func() { done <- check(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := Result{Name: name, Status: StatusUp, Latency: c.now().Sub(start), Err: err}
+	if err != nil {
+		result.Status = StatusDown
+	}
+
+	return result
+}
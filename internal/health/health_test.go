@@ -0,0 +1,97 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pavelmemory/jobtome/internal/storage"
+	"github.com/pavelmemory/jobtome/internal/storage/migrations"
+)
+
+func TestChecker_Run(t *testing.T) {
+	pass := func(context.Context) error { return nil }
+	fail := func(context.Context) error { return errors.New("unreachable") }
+	hang := func(ctx context.Context) error { <-ctx.Done(); time.Sleep(time.Second); return nil }
+
+	t.Run("up", func(t *testing.T) {
+		checker := NewChecker(time.Second)
+		checker.Add("ping", pass)
+		checker.Add("migrations", pass)
+
+		report := checker.Run(context.Background())
+		require.Equal(t, StatusUp, report.Status)
+		require.Len(t, report.Checks, 3)
+		for i, name := range []string{"shutdown", "ping", "migrations"} {
+			require.Equal(t, name, report.Checks[i].Name)
+			require.Equal(t, StatusUp, report.Checks[i].Status)
+			require.NoError(t, report.Checks[i].Err)
+		}
+	})
+
+	t.Run("failed check", func(t *testing.T) {
+		checker := NewChecker(time.Second)
+		checker.Add("ping", pass)
+		checker.Add("disk", fail)
+
+		report := checker.Run(context.Background())
+		require.Equal(t, StatusDown, report.Status)
+		require.Equal(t, StatusUp, report.Checks[1].Status)
+		require.Equal(t, StatusDown, report.Checks[2].Status)
+		require.EqualError(t, report.Checks[2].Err, "unreachable")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		checker := NewChecker(10 * time.Millisecond)
+		checker.Add("ping", hang)
+
+		start := time.Now()
+		report := checker.Run(context.Background())
+		require.Less(t, time.Since(start), time.Second)
+		require.Equal(t, StatusDown, report.Status)
+		require.ErrorIs(t, report.Checks[1].Err, context.DeadlineExceeded)
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		checker := NewChecker(time.Second)
+		checker.Add("ping", pass)
+		checker.Shutdown()
+
+		report := checker.Run(context.Background())
+		require.Equal(t, StatusDown, report.Status)
+		require.Equal(t, StatusDown, report.Checks[0].Status)
+		require.ErrorIs(t, report.Checks[0].Err, ErrShuttingDown)
+		require.Equal(t, StatusUp, report.Checks[1].Status)
+	})
+}
+
+func TestChecks(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "health.dat")
+
+	pool, err := storage.Open(storage.DriverSQLite, path)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	t.Run("ping", func(t *testing.T) {
+		require.NoError(t, Ping(pool)(ctx))
+	})
+
+	t.Run("migrations pending", func(t *testing.T) {
+		require.Error(t, Migrations(pool)(ctx))
+	})
+
+	t.Run("migrations applied", func(t *testing.T) {
+		require.NoError(t, migrations.Up(storage.DriverSQLite, path))
+		require.NoError(t, Migrations(pool)(ctx))
+	})
+
+	t.Run("disk space", func(t *testing.T) {
+		require.NoError(t, DiskSpace(path, 1)(ctx))
+		require.Error(t, DiskSpace(path, 1<<62)(ctx))
+	})
+}
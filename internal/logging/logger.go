@@ -19,6 +19,8 @@ type Logger interface {
 	Debug(msg string)
 	// Info flushes logging context with "info" severity level.
 	Info(msg string)
+	// Warn flushes logging context with "warn" severity level.
+	Warn(msg string)
 	// Error flushes logging context with "error" severity level.
 	Error(msg string)
 	// IsDebug reports if logging level severity is higher than 'debug' level.
@@ -96,6 +98,10 @@ func (tl *TestLogger) Info(msg string) {
 	tl.level("info", msg)
 }
 
+func (tl *TestLogger) Warn(msg string) {
+	tl.level("warn", msg)
+}
+
 func (tl *TestLogger) Error(msg string) {
 	tl.level("error", msg)
 }
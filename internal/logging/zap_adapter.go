@@ -55,6 +55,10 @@ func (zw ZapWrapper) Info(msg string) {
 	zw.Logger.Info(msg)
 }
 
+func (zw ZapWrapper) Warn(msg string) {
+	zw.Logger.Warn(msg)
+}
+
 func (zw ZapWrapper) Error(msg string) {
 	zw.Logger.Error(msg)
 }
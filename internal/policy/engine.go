@@ -0,0 +1,204 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/logging"
+)
+
+// Config is the part of the policy that doesn't depend on the rules.
+type Config struct {
+	// OwnHosts are the short domains of the service, the URLs pointing to them are blocked
+	// to prevent redirect chains and loops. The port of the host is ignored.
+	OwnHosts []string
+	// AllowPrivate allows the URLs of `localhost`, loopback, private, link-local and unspecified IP addresses.
+	AllowPrivate bool
+}
+
+// NewEngine returns a policy engine without any rules.
+func NewEngine(cfg Config) *Engine {
+	e := &Engine{ownHosts: make(map[string]struct{}, len(cfg.OwnHosts)), allowPrivate: cfg.AllowPrivate}
+	for _, host := range cfg.OwnHosts {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			e.ownHosts[host] = struct{}{}
+		}
+	}
+
+	return e
+}
+
+// Engine decides what URLs the shortens could redirect to. It is safe for concurrent use.
+type Engine struct {
+	ownHosts     map[string]struct{}
+	allowPrivate bool
+
+	mu    sync.RWMutex
+	rules Rules
+	// path is a file of the rules, see `LoadFile`.
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// SetRules replaces the rules of the engine.
+func (e *Engine) SetRules(rules Rules) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules = rules
+}
+
+// LoadFile replaces the rules of the engine with the rules from the file located at `path`, see `Parse`.
+// The file is reloaded by `Watch` once it is changed.
+func (e *Engine) LoadFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("stat rules file: %w", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open rules file: %w", err)
+	}
+	defer f.Close()
+
+	rules, err := Parse(f)
+	if err != nil {
+		return fmt.Errorf("parse rules file %q: %w", path, err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules, e.path, e.modTime, e.size = rules, path, info.ModTime(), info.Size()
+
+	return nil
+}
+
+// Watch reloads the rules file loaded by `LoadFile` each time its modification time or size changes.
+// The file is checked every `interval` until the `ctx` is cancelled.
+// If the changed file can't be loaded the previous rules are kept.
+func (e *Engine) Watch(ctx context.Context, interval time.Duration) {
+	logger := logging.FromContext(ctx).WithString("component", "Engine").WithString("method", "Watch")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := e.reload()
+			if err != nil {
+				logger.WithError(err).Error("reload policy rules")
+				continue
+			}
+
+			if reloaded {
+				logger.Info("policy rules reloaded")
+			}
+		}
+	}
+}
+
+// reload loads the rules file again if it was changed since the last load.
+func (e *Engine) reload() (bool, error) {
+	e.mu.RLock()
+	path, modTime, size := e.path, e.modTime, e.size
+	e.mu.RUnlock()
+
+	if path == "" {
+		return false, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, fmt.Errorf("stat rules file: %w", err)
+	}
+
+	if info.ModTime().Equal(modTime) && info.Size() == size {
+		return false, nil
+	}
+
+	// the state of the failed load is remembered, so the same broken file is not reported again
+	if err := e.LoadFile(path); err != nil {
+		e.mu.Lock()
+		e.modTime, e.size = info.ModTime(), info.Size()
+		e.mu.Unlock()
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Check returns an error wrapping `internal.ErrBlocked` with the reason if the shortens must not redirect to the `rawURL`.
+// The URLs of the own hosts and of the private addresses are blocked regardless of the rules.
+// Otherwise the URL matched by any allow rule is allowed, the URL matched by any deny rule is blocked
+// and the rest are allowed unless the rules deny them by default.
+func (e *Engine) Check(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: not a valid URL", internal.ErrBlocked)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if ip := ParseIPv4(host); ip != nil {
+		// the browsers resolve the short and numeric forms of the address without a lookup
+		host = ip.String()
+	}
+
+	if _, ok := e.ownHosts[host]; ok {
+		return fmt.Errorf("%w: points to the short domain", internal.ErrBlocked)
+	}
+
+	if !e.allowPrivate && isPrivate(host) {
+		return fmt.Errorf("%w: points to a private address", internal.ErrBlocked)
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for _, rule := range e.rules.Allow {
+		if rule.matches(rawURL, host) {
+			return nil
+		}
+	}
+
+	for _, rule := range e.rules.Deny {
+		if rule.matches(rawURL, host) {
+			return fmt.Errorf("%w: denied by %s rule %q", internal.ErrBlocked, rule.Kind, rule.Pattern)
+		}
+	}
+
+	if e.rules.DefaultDeny {
+		return fmt.Errorf("%w: not allowed by any rule", internal.ErrBlocked)
+	}
+
+	return nil
+}
+
+// isPrivate reports if the `host` is `localhost` or an IP address not routable in the internet.
+// Host names are not resolved, so the names of the private addresses are not detected.
+func isPrivate(host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
@@ -0,0 +1,107 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/logging"
+)
+
+func TestEngine_Check(t *testing.T) {
+	rules, err := Parse(strings.NewReader(`
+deny domain evil.com
+allow domain docs.evil.com
+deny regex [?&]redirect=
+`))
+	require.NoError(t, err)
+
+	engine := NewEngine(Config{OwnHosts: []string{"sho.rt", "Go.Example.com:8081"}})
+	engine.SetRules(rules)
+
+	for _, u := range []string{
+		"https://example.com",
+		"https://docs.evil.com/guide",
+		"https://notevil.com",
+		"https://8.8.8.8/",
+		"https://api.sho.rt/",
+	} {
+		require.NoError(t, engine.Check(u), u)
+	}
+
+	for u, reason := range map[string]string{
+		"https://sho.rt/abc":                      "points to the short domain",
+		"http://SHO.RT./abc":                      "points to the short domain",
+		"https://go.example.com/abc":              "points to the short domain",
+		"http://localhost:8080/api":               "points to a private address",
+		"http://app.localhost/":                   "points to a private address",
+		"http://127.0.0.1/":                       "points to a private address",
+		"http://10.1.2.3/":                        "points to a private address",
+		"http://192.168.0.1/":                     "points to a private address",
+		"http://169.254.169.254/latest/meta-data": "points to a private address",
+		"http://[::1]/":                           "points to a private address",
+		"http://[fd00::1]/":                       "points to a private address",
+		"http://0.0.0.0/":                         "points to a private address",
+		"http://2130706433/":                      "points to a private address",
+		"http://127.1/":                           "points to a private address",
+		"http://0x7f.1/":                          "points to a private address",
+		"http://0177.0.0.1/":                      "points to a private address",
+		"http://0xa9fea9fe/latest/meta-data":      "points to a private address",
+		"https://evil.com/login":                  `denied by domain rule "evil.com"`,
+		"https://login.evil.com/":                 `denied by domain rule "evil.com"`,
+		"https://example.com/out?redirect=x":      `denied by regex rule "[?&]redirect="`,
+	} {
+		err := engine.Check(u)
+		require.ErrorIs(t, err, internal.ErrBlocked, u)
+		require.EqualError(t, err, "blocked: "+reason, u)
+	}
+
+	t.Run("allow private", func(t *testing.T) {
+		engine := NewEngine(Config{AllowPrivate: true})
+		require.NoError(t, engine.Check("http://localhost:8080/api"))
+		require.NoError(t, engine.Check("http://10.1.2.3/"))
+	})
+
+	t.Run("default deny", func(t *testing.T) {
+		engine := NewEngine(Config{})
+		engine.SetRules(Rules{DefaultDeny: true, Allow: rules.Allow})
+
+		require.NoError(t, engine.Check("https://docs.evil.com/"))
+		require.EqualError(t, engine.Check("https://example.com"), "blocked: not allowed by any rule")
+	})
+}
+
+func TestEngine_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.rules")
+	require.NoError(t, os.WriteFile(path, []byte("deny domain evil.com\n"), 0o600))
+
+	engine := NewEngine(Config{})
+	require.NoError(t, engine.LoadFile(path))
+	require.ErrorIs(t, engine.Check("https://evil.com"), internal.ErrBlocked)
+
+	ctx, cancel := context.WithCancel(logging.ToContext(context.Background(), logging.NewTestLogger()))
+	defer cancel()
+	go engine.Watch(ctx, 5*time.Millisecond)
+
+	// the broken file is reported and the previous rules are kept
+	require.NoError(t, os.WriteFile(path, []byte("deny evil.com\n"), 0o600))
+	time.Sleep(50 * time.Millisecond)
+	require.ErrorIs(t, engine.Check("https://evil.com"), internal.ErrBlocked)
+
+	require.NoError(t, os.WriteFile(path, []byte("deny domain phish.com\n# evil.com is clean now\n"), 0o600))
+	require.Eventually(t, func() bool {
+		return engine.Check("https://evil.com") == nil
+	}, time.Second, 5*time.Millisecond)
+	require.ErrorIs(t, engine.Check("https://phish.com"), internal.ErrBlocked)
+}
+
+func TestNewEngine_missingFile(t *testing.T) {
+	engine := NewEngine(Config{})
+	require.Error(t, engine.LoadFile(filepath.Join(t.TempDir(), "missing.rules")))
+}
@@ -0,0 +1,67 @@
+package policy
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ParseIPv4 parses the `host` as an IPv4 address in any form the browsers accept: besides the dotted decimal
+// `127.0.0.1` the address could have less than 4 parts `127.1`, a single number `2130706433`
+// and hexadecimal `0x7f.1` or octal `0177.1` parts. It returns nil if the `host` isn't such an address.
+func ParseIPv4(host string) net.IP {
+	parts := strings.Split(strings.TrimSuffix(host, "."), ".")
+	if len(parts) > 4 {
+		return nil
+	}
+
+	var addr uint64
+	for i, part := range parts {
+		n, ok := parseIPv4Part(part)
+		if !ok {
+			return nil
+		}
+
+		if i < len(parts)-1 {
+			if n > 255 {
+				return nil
+			}
+			addr |= n << (8 * (3 - i))
+			continue
+		}
+
+		// the last part fills all the remaining bytes
+		if n >= 1<<(8*(4-len(parts)+1)) {
+			return nil
+		}
+		addr |= n
+	}
+
+	return net.IPv4(byte(addr>>24), byte(addr>>16), byte(addr>>8), byte(addr))
+}
+
+// parseIPv4Part parses a part of the IPv4 address, it is hexadecimal with `0x` prefix, octal with `0` prefix
+// and decimal otherwise.
+func parseIPv4Part(part string) (uint64, bool) {
+	if part == "" {
+		return 0, false
+	}
+
+	base := 10
+	switch {
+	case len(part) >= 2 && (part[:2] == "0x" || part[:2] == "0X"):
+		base, part = 16, part[2:]
+		if part == "" {
+			return 0, true
+		}
+	case len(part) >= 2 && part[0] == '0':
+		base, part = 8, part[1:]
+	}
+
+	n, err := strconv.ParseUint(part, base, 32)
+	if err != nil {
+		return 0, false
+	}
+
+	return n, true
+}
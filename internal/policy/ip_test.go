@@ -0,0 +1,45 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIPv4(t *testing.T) {
+	for host, exp := range map[string]string{
+		"127.0.0.1":   "127.0.0.1",
+		"2130706433":  "127.0.0.1",
+		"127.1":       "127.0.0.1",
+		"0x7f.1":      "127.0.0.1",
+		"0X7F.0.0.1":  "127.0.0.1",
+		"0177.0.0.01": "127.0.0.1",
+		"0x7f000001":  "127.0.0.1",
+		"10.65536":    "10.1.0.0",
+		"192.168.257": "192.168.1.1",
+		"0":           "0.0.0.0",
+		"0x":          "0.0.0.0",
+		"8.8.8.8.":    "8.8.8.8",
+	} {
+		ip := ParseIPv4(host)
+		require.NotNil(t, ip, host)
+		require.Equal(t, exp, ip.String(), host)
+	}
+
+	for _, host := range []string{
+		"example.com",
+		"0x7f.example",
+		"1.2.3.4.5",
+		"256.0.0.1",
+		"1.2.65536",
+		"4294967296",
+		"08.0.0.1",
+		"0xg.1",
+		"1..1",
+		"+1.2.3.4",
+		"",
+		"::1",
+	} {
+		require.Nil(t, ParseIPv4(host), host)
+	}
+}
@@ -0,0 +1,133 @@
+package policy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// Actions of the rules.
+const (
+	// ActionAllow allows the URLs matched by the rule, even if they are matched by a deny rule.
+	ActionAllow = "allow"
+	// ActionDeny blocks the URLs matched by the rule.
+	ActionDeny = "deny"
+)
+
+// Kinds of the rules.
+const (
+	// KindDomain matches the URLs with the host equal to the pattern or to its subdomain.
+	KindDomain = "domain"
+	// KindRegex matches the URLs that contain a match of the regular expression.
+	KindRegex = "regex"
+)
+
+// Rule matches the URLs by the host or by the regular expression.
+type Rule struct {
+	Action  string
+	Kind    string
+	Pattern string
+	re      *regexp.Regexp
+}
+
+// matches reports if the `u` with the lower-cased `host` is matched by the rule.
+func (r Rule) matches(u, host string) bool {
+	if r.Kind == KindRegex {
+		return r.re.MatchString(u)
+	}
+
+	return host == r.Pattern || strings.HasSuffix(host, "."+r.Pattern)
+}
+
+// Rules are the lists of the allowed and the denied URLs.
+type Rules struct {
+	// DefaultDeny blocks the URLs not matched by any allow rule, so the allow rules become an allowlist.
+	DefaultDeny bool
+	Allow       []Rule
+	Deny        []Rule
+}
+
+// Parse reads the rules, one per line. Empty lines and lines starting with `#` are ignored.
+// The patterns can't contain spaces, `\s` could be used in the regular expressions instead.
+//
+//	# block everything not allowed explicitly
+//	default deny
+//	# evil.com and all its subdomains except docs.evil.com
+//	deny domain evil.com
+//	allow domain docs.evil.com
+//	deny regex ^https?://[^/]+/wp-admin/
+func Parse(r io.Reader) (Rules, error) {
+	var rules Rules
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		if len(fields) == 2 && fields[0] == "default" {
+			switch fields[1] {
+			case ActionAllow:
+				rules.DefaultDeny = false
+			case ActionDeny:
+				rules.DefaultDeny = true
+			default:
+				return Rules{}, fmt.Errorf("line %d: unknown default action %q", line, fields[1])
+			}
+			continue
+		}
+
+		rule, err := parseRule(fields)
+		if err != nil {
+			return Rules{}, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if rule.Action == ActionAllow {
+			rules.Allow = append(rules.Allow, rule)
+		} else {
+			rules.Deny = append(rules.Deny, rule)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return Rules{}, fmt.Errorf("read rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// parseRule parses the `fields` of the `<action> <kind> <pattern>` line.
+func parseRule(fields []string) (Rule, error) {
+	if len(fields) != 3 {
+		return Rule{}, fmt.Errorf("expected '<action> <kind> <pattern>', got %q", strings.Join(fields, " "))
+	}
+
+	rule := Rule{Action: fields[0], Kind: fields[1], Pattern: fields[2]}
+	if rule.Action != ActionAllow && rule.Action != ActionDeny {
+		return Rule{}, fmt.Errorf("unknown action %q", rule.Action)
+	}
+
+	switch rule.Kind {
+	case KindDomain:
+		domain, err := idna.Lookup.ToASCII(strings.TrimPrefix(rule.Pattern, "*."))
+		if err != nil {
+			return Rule{}, fmt.Errorf("invalid domain %q: %w", rule.Pattern, err)
+		}
+		rule.Pattern = domain
+	case KindRegex:
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return Rule{}, fmt.Errorf("invalid regex %q: %w", rule.Pattern, err)
+		}
+		rule.re = re
+	default:
+		return Rule{}, fmt.Errorf("unknown kind %q", rule.Kind)
+	}
+
+	return rule, nil
+}
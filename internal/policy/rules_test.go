@@ -0,0 +1,42 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		rules, err := Parse(strings.NewReader(`
+# phishing campaigns
+default deny
+deny domain *.Evil.com
+  allow domain bücher.example
+deny regex ^https?://[^/]+/wp-admin/#
+`))
+		require.NoError(t, err)
+		require.True(t, rules.DefaultDeny)
+		require.Len(t, rules.Allow, 1)
+		require.Equal(t, "xn--bcher-kva.example", rules.Allow[0].Pattern)
+		require.Len(t, rules.Deny, 2)
+		require.Equal(t, "evil.com", rules.Deny[0].Pattern)
+		require.Equal(t, KindRegex, rules.Deny[1].Kind)
+		require.Equal(t, "^https?://[^/]+/wp-admin/#", rules.Deny[1].Pattern)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for rules, exp := range map[string]string{
+			"default block":              `line 1: unknown default action "block"`,
+			"deny evil.com":              `line 1: expected '<action> <kind> <pattern>', got "deny evil.com"`,
+			"\nblock domain evil.com":    `line 2: unknown action "block"`,
+			"deny host evil.com":         `line 1: unknown kind "host"`,
+			"deny regex ^(http":          "line 1: invalid regex \"^(http\": error parsing regexp: missing closing ): `^(http`",
+			"deny domain evil.com extra": `line 1: expected '<action> <kind> <pattern>', got "deny domain evil.com extra"`,
+		} {
+			_, err := Parse(strings.NewReader(rules))
+			require.EqualError(t, err, exp, rules)
+		}
+	})
+}
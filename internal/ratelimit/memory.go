@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is a period between removals of the buckets that are full again.
+const sweepInterval = time.Minute
+
+// NewMemoryBackend returns a backend that keeps the buckets in the memory of the process,
+// so each instance of the service limits the requests on its own.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		now:     time.Now,
+		buckets: make(map[string]*memoryBucket),
+	}
+}
+
+// MemoryBackend is an in-memory `Backend`.
+// Buckets that are full again are removed periodically, so the memory is bounded by the number of recent clients.
+type MemoryBackend struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	policy Policy
+}
+
+// Take implements `Backend`.
+func (m *MemoryBackend) Take(_ context.Context, key string, policy Policy) (Decision, error) {
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		m.sweep(now)
+	}
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &memoryBucket{bucket: bucket{tokens: float64(policy.Burst), updated: now}}
+		m.buckets[key] = b
+	}
+	b.policy = policy
+
+	return b.take(policy, now), nil
+}
+
+// Len returns a number of the buckets kept in the memory.
+func (m *MemoryBackend) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.buckets)
+}
+
+// sweep removes the buckets that are full by the moment `now`, the caller must hold the lock.
+func (m *MemoryBackend) sweep(now time.Time) {
+	for key, b := range m.buckets {
+		if b.full(b.policy, now) {
+			delete(m.buckets, key)
+		}
+	}
+	m.lastSweep = now
+}
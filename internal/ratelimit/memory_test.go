@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryBackend_Take(t *testing.T) {
+	policy := Policy{Rate: 2, Burst: 3}
+	now := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	backend := NewMemoryBackend()
+	backend.now = func() time.Time { return now }
+
+	take := func(key string) Decision {
+		decision, err := backend.Take(context.Background(), key, policy)
+		require.NoError(t, err)
+		return decision
+	}
+
+	t.Run("burst", func(t *testing.T) {
+		for remaining := 2; remaining >= 0; remaining-- {
+			decision := take("a")
+			require.True(t, decision.Allowed)
+			require.Equal(t, 3, decision.Limit)
+			require.Equal(t, remaining, decision.Remaining)
+		}
+
+		decision := take("a")
+		require.False(t, decision.Allowed)
+		require.Equal(t, 0, decision.Remaining)
+		require.Equal(t, 500*time.Millisecond, decision.RetryAfter)
+		require.Equal(t, 1500*time.Millisecond, decision.Reset)
+	})
+
+	t.Run("separate keys", func(t *testing.T) {
+		require.True(t, take("b").Allowed)
+	})
+
+	t.Run("refill", func(t *testing.T) {
+		now = now.Add(500 * time.Millisecond)
+		require.True(t, take("a").Allowed)
+		require.False(t, take("a").Allowed)
+
+		now = now.Add(time.Hour)
+		decision := take("a")
+		require.True(t, decision.Allowed)
+		require.Equal(t, 2, decision.Remaining)
+	})
+
+	t.Run("sweep", func(t *testing.T) {
+		take("d")
+		// "b" is full again and removed by the sweep during the refill
+		require.Equal(t, 2, backend.Len())
+
+		now = now.Add(sweepInterval)
+		take("c")
+		require.Equal(t, 1, backend.Len())
+	})
+}
+
+func TestPolicy_Enabled(t *testing.T) {
+	require.True(t, Policy{Rate: 0.5, Burst: 1}.Enabled())
+	require.False(t, Policy{Rate: 0, Burst: 1}.Enabled())
+	require.False(t, Policy{Rate: 1, Burst: 0}.Enabled())
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Policy defines a token bucket: it holds up to `Burst` tokens and is refilled with `Rate` tokens per second.
+// Each request takes a token from the bucket, requests made when the bucket is empty are rejected.
+type Policy struct {
+	// Rate is a number of requests per second allowed in the long run.
+	Rate float64
+	// Burst is a number of requests allowed at once after a period of inactivity.
+	Burst int
+}
+
+// Enabled reports if the policy limits anything, non-positive rate or burst disables it.
+func (p Policy) Enabled() bool {
+	return p.Rate > 0 && p.Burst > 0
+}
+
+// Decision is an outcome of taking a token from the bucket.
+type Decision struct {
+	// Allowed reports if the token was taken.
+	Allowed bool
+	// Limit is a capacity of the bucket.
+	Limit int
+	// Remaining is a number of tokens left in the bucket.
+	Remaining int
+	// RetryAfter is a time until the next token is available, zero if the token was taken.
+	RetryAfter time.Duration
+	// Reset is a time until the bucket is full again.
+	Reset time.Duration
+}
+
+// Backend keeps the state of the buckets.
+// Instances of the service that share the backend share the limits as well.
+type Backend interface {
+	// Take takes a token from the bucket identified by the `key` and limited by the `policy`.
+	Take(ctx context.Context, key string, policy Policy) (Decision, error)
+}
+
+// bucket is a state of the token bucket.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// take refills the bucket for the time passed since the last update and takes a token from it if there is any.
+func (b *bucket) take(policy Policy, now time.Time) Decision {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(policy.Burst), b.tokens+elapsed*policy.Rate)
+		b.updated = now
+	}
+
+	decision := Decision{Limit: policy.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = seconds((1 - b.tokens) / policy.Rate)
+	}
+
+	decision.Remaining = int(b.tokens)
+	decision.Reset = seconds((float64(policy.Burst) - b.tokens) / policy.Rate)
+
+	return decision
+}
+
+// full reports if the bucket is refilled up to the `policy` burst by the moment `now`,
+// such a bucket is not distinguishable from the new one.
+func (b *bucket) full(policy Policy, now time.Time) bool {
+	return b.tokens+now.Sub(b.updated).Seconds()*policy.Rate >= float64(policy.Burst)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
@@ -0,0 +1,74 @@
+package shorten
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pavelmemory/jobtome/internal"
+)
+
+// DefaultReservedAliases are the words that can't be used as aliases
+// because they clash with the service endpoints or could mislead users.
+var DefaultReservedAliases = []string{
+	"-", "_", "api", "admin", "health", "healthz", "metrics", "static", "assets", "login", "logout", "robots.txt", "favicon.ico",
+}
+
+// DefaultAliasPolicy is used if no other policy is configured.
+var DefaultAliasPolicy = NewAliasPolicy(3, 64, nil)
+
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// NewAliasPolicy returns a policy that allows aliases of the length in range [minLength, maxLength].
+// `reserved` words are forbidden in addition to `DefaultReservedAliases`.
+func NewAliasPolicy(minLength, maxLength int, reserved []string) AliasPolicy {
+	policy := AliasPolicy{
+		MinLength: minLength,
+		MaxLength: maxLength,
+		Reserved:  make(map[string]struct{}, len(DefaultReservedAliases)+len(reserved)),
+	}
+
+	for _, words := range [][]string{DefaultReservedAliases, reserved} {
+		for _, word := range words {
+			if word = strings.TrimSpace(word); word != "" {
+				policy.Reserved[strings.ToLower(word)] = struct{}{}
+			}
+		}
+	}
+
+	return policy
+}
+
+// AliasPolicy defines what codes could be chosen by the callers.
+// An alias consists of latin letters, digits, '-' and '_' characters.
+type AliasPolicy struct {
+	MinLength int
+	MaxLength int
+	// Reserved are lower-cased words that can't be used as aliases (case-insensitive).
+	Reserved map[string]struct{}
+}
+
+// Validate returns `ValidationError` if the alias doesn't satisfy the policy.
+func (ap AliasPolicy) Validate(alias string) error {
+	var reason string
+	switch {
+	case len(alias) < ap.MinLength || len(alias) > ap.MaxLength:
+		reason = fmt.Sprintf("length is out of range [%d, %d]", ap.MinLength, ap.MaxLength)
+	case !aliasPattern.MatchString(alias):
+		reason = "only latin letters, digits, '-' and '_' are allowed"
+	case ap.isReserved(alias):
+		reason = "reserved"
+	default:
+		return nil
+	}
+
+	return ValidationError{
+		Cause:   internal.ErrBadInput,
+		Details: map[string]interface{}{"alias": reason},
+	}
+}
+
+func (ap AliasPolicy) isReserved(alias string) bool {
+	_, ok := ap.Reserved[strings.ToLower(alias)]
+	return ok
+}
@@ -0,0 +1,35 @@
+package shorten
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pavelmemory/jobtome/internal"
+)
+
+func TestAliasPolicy_Validate(t *testing.T) {
+	policy := NewAliasPolicy(3, 10, []string{"Promo", " "})
+
+	for _, alias := range []string{"abc", "A_b-1", "0123456789"} {
+		require.NoError(t, policy.Validate(alias), alias)
+	}
+
+	for alias, reason := range map[string]string{
+		"ab":          "length is out of range [3, 10]",
+		"summer-sale": "length is out of range [3, 10]",
+		"a/b":         "only latin letters, digits, '-' and '_' are allowed",
+		"a b":         "only latin letters, digits, '-' and '_' are allowed",
+		"привет":      "length is out of range [3, 10]",
+		"при":         "only latin letters, digits, '-' and '_' are allowed",
+		"API":         "reserved",
+		"health":      "reserved",
+		"promo":       "reserved",
+	} {
+		exp := ValidationError{Cause: internal.ErrBadInput, Details: map[string]interface{}{"alias": reason}}
+		require.Equal(t, exp, policy.Validate(alias), alias)
+	}
+
+	_, ok := policy.Reserved[""]
+	require.False(t, ok, "blank words must be ignored")
+}
@@ -0,0 +1,214 @@
+package shorten
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/storage/shorten"
+)
+
+// cacheLoadTimeout limits the time of the storage lookup shared by the concurrent misses of the same hash.
+const cacheLoadTimeout = 5 * time.Second
+
+// CacheStats is a snapshot of the resolve cache counters.
+type CacheStats struct {
+	// Hits is a number of lookups answered from the cache, including the cached unknown hashes.
+	Hits int64
+	// Misses is a number of lookups that required a query to the storage.
+	Misses int64
+	// Entries is a number of hashes currently cached.
+	Entries int
+}
+
+// NewResolveCache returns a cache of up to `size` shortens looked up by `Resolve`.
+// Found shortens are kept for `ttl`, unknown hashes are kept for `negativeTTL`,
+// a non-positive `negativeTTL` disables caching of unknown hashes.
+// The TTLs bound how long changes made by other instances of the service stay unnoticed,
+// changes made by the same instance are applied to the cache immediately.
+func NewResolveCache(size int, ttl, negativeTTL time.Duration) *ResolveCache {
+	return &ResolveCache{
+		size:        size,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		now:         time.Now,
+		order:       list.New(),
+		byHash:      make(map[string]*list.Element),
+		byID:        make(map[int64]*list.Element),
+	}
+}
+
+// ResolveCache is a size-bounded read-through LRU cache of the shortens by their hashes.
+// Concurrent misses for the same hash are resolved with a single query to the storage.
+type ResolveCache struct {
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+
+	loads  singleflight.Group
+	hits   int64
+	misses int64
+
+	mu sync.Mutex
+	// order holds the entries from the most to the least recently used.
+	order  *list.List
+	byHash map[string]*list.Element
+	// byID indexes the found shortens, so they could be invalidated without knowing their hashes.
+	byID map[int64]*list.Element
+	// generation is increased on each invalidation,
+	// results of the loads started before it are not cached as they could be stale.
+	generation uint64
+}
+
+type cacheEntry struct {
+	hash      string
+	short     shorten.Entity
+	found     bool
+	expiresAt time.Time
+}
+
+// Stats returns the current values of the cache counters.
+func (c *ResolveCache) Stats() CacheStats {
+	c.mu.Lock()
+	entries := c.order.Len()
+	c.mu.Unlock()
+
+	return CacheStats{
+		Hits:    atomic.LoadInt64(&c.hits),
+		Misses:  atomic.LoadInt64(&c.misses),
+		Entries: entries,
+	}
+}
+
+// get returns the shorten with the `hash` from the cache or loads it with the `load` on a miss.
+// It returns `internal.ErrNotFound` for the cached unknown hashes, other errors of `load` are not cached.
+func (c *ResolveCache) get(ctx context.Context, hash string, load func(context.Context) (shorten.Entity, error)) (shorten.Entity, error) {
+	if short, found, ok := c.lookup(hash); ok {
+		atomic.AddInt64(&c.hits, 1)
+		if !found {
+			return shorten.Entity{}, internal.ErrNotFound
+		}
+		return short, nil
+	}
+
+	atomic.AddInt64(&c.misses, 1)
+
+	loaded := c.loads.DoChan(hash, func() (interface{}, error) {
+		// the load is shared by all callers of the hash, so it must not be cancelled with the caller that started it
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cacheLoadTimeout)
+		defer cancel()
+
+		c.mu.Lock()
+		generation := c.generation
+		c.mu.Unlock()
+
+		short, err := load(ctx)
+		switch {
+		case err == nil:
+			c.store(generation, cacheEntry{hash: hash, short: short, found: true, expiresAt: c.now().Add(c.ttl)})
+		case errors.Is(err, internal.ErrNotFound) && c.negativeTTL > 0:
+			c.store(generation, cacheEntry{hash: hash, expiresAt: c.now().Add(c.negativeTTL)})
+		}
+		return short, err
+	})
+
+	select {
+	case <-ctx.Done():
+		return shorten.Entity{}, ctx.Err()
+	case res := <-loaded:
+		if res.Err != nil {
+			return shorten.Entity{}, res.Err
+		}
+		return res.Val.(shorten.Entity), nil
+	}
+}
+
+// lookup returns the cached shorten and reports if it was found in the storage.
+// The last result is false if the `hash` is not cached or its entry is expired.
+func (c *ResolveCache) lookup(hash string) (shorten.Entity, bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.byHash[hash]
+	if !ok {
+		return shorten.Entity{}, false, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.remove(elem)
+		return shorten.Entity{}, false, false
+	}
+
+	c.order.MoveToFront(elem)
+
+	return entry.short, entry.found, true
+}
+
+// store puts the `entry` into the cache unless the cache was invalidated after the `generation`.
+// The least recently used entries are evicted to keep the size of the cache.
+func (c *ResolveCache) store(generation uint64, entry cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation || c.size < 1 {
+		return
+	}
+
+	if elem, ok := c.byHash[entry.hash]; ok {
+		c.remove(elem)
+	}
+	if elem, ok := c.byID[entry.short.ID]; ok && entry.found {
+		c.remove(elem)
+	}
+
+	elem := c.order.PushFront(&entry)
+	c.byHash[entry.hash] = elem
+	if entry.found {
+		c.byID[entry.short.ID] = elem
+	}
+
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// invalidate removes the `hashes` from the cache.
+func (c *ResolveCache) invalidate(hashes ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for _, hash := range hashes {
+		if elem, ok := c.byHash[hash]; ok {
+			c.remove(elem)
+		}
+	}
+}
+
+// invalidateID removes the shorten with the `id` from the cache.
+func (c *ResolveCache) invalidateID(id int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if elem, ok := c.byID[id]; ok {
+		c.remove(elem)
+	}
+}
+
+// remove deletes the `elem` from the cache, the caller must hold the lock.
+func (c *ResolveCache) remove(elem *list.Element) {
+	entry := c.order.Remove(elem).(*cacheEntry)
+	delete(c.byHash, entry.hash)
+	if entry.found {
+		delete(c.byID, entry.short.ID)
+	}
+}
@@ -0,0 +1,347 @@
+package shorten
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/storage/shorten"
+)
+
+func TestResolveCache(t *testing.T) {
+	loader := func(short shorten.Entity, err error, calls *int) func(context.Context) (shorten.Entity, error) {
+		return func(context.Context) (shorten.Entity, error) {
+			*calls++
+			return short, err
+		}
+	}
+
+	t.Run("hit", func(t *testing.T) {
+		cache := NewResolveCache(10, time.Minute, time.Minute)
+		existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567", Workspace: DefaultWorkspace}
+
+		var calls int
+		for i := 0; i < 3; i++ {
+			actual, err := cache.get(Context(), existing.Hash, loader(existing, nil, &calls))
+			require.NoError(t, err)
+			require.Equal(t, existing, actual)
+		}
+		require.Equal(t, 1, calls)
+		require.Equal(t, CacheStats{Hits: 2, Misses: 1, Entries: 1}, cache.Stats())
+	})
+
+	t.Run("not existing", func(t *testing.T) {
+		cache := NewResolveCache(10, time.Minute, time.Minute)
+
+		var calls int
+		for i := 0; i < 2; i++ {
+			_, err := cache.get(Context(), "1", loader(shorten.Entity{}, internal.ErrNotFound, &calls))
+			require.True(t, errors.Is(err, internal.ErrNotFound), err)
+		}
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("not existing without negative ttl", func(t *testing.T) {
+		cache := NewResolveCache(10, time.Minute, 0)
+
+		var calls int
+		for i := 0; i < 2; i++ {
+			_, err := cache.get(Context(), "1", loader(shorten.Entity{}, internal.ErrNotFound, &calls))
+			require.True(t, errors.Is(err, internal.ErrNotFound), err)
+		}
+		require.Equal(t, 2, calls)
+	})
+
+	t.Run("failure is not cached", func(t *testing.T) {
+		cache := NewResolveCache(10, time.Minute, time.Minute)
+
+		var calls int
+		for i := 0; i < 2; i++ {
+			_, err := cache.get(Context(), "1", loader(shorten.Entity{}, errors.New("boom"), &calls))
+			require.EqualError(t, err, "boom")
+		}
+		require.Equal(t, 2, calls)
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		now := time.Now()
+		cache := NewResolveCache(10, time.Minute, time.Second)
+		cache.now = func() time.Time { return now }
+
+		var calls, negativeCalls int
+		existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567", Workspace: DefaultWorkspace}
+		_, err := cache.get(Context(), existing.Hash, loader(existing, nil, &calls))
+		require.NoError(t, err)
+		_, err = cache.get(Context(), "1", loader(shorten.Entity{}, internal.ErrNotFound, &negativeCalls))
+		require.True(t, errors.Is(err, internal.ErrNotFound), err)
+
+		now = now.Add(time.Second)
+		_, err = cache.get(Context(), existing.Hash, loader(existing, nil, &calls))
+		require.NoError(t, err)
+		_, err = cache.get(Context(), "1", loader(shorten.Entity{}, internal.ErrNotFound, &negativeCalls))
+		require.True(t, errors.Is(err, internal.ErrNotFound), err)
+		require.Equal(t, 1, calls)
+		require.Equal(t, 2, negativeCalls)
+
+		now = now.Add(time.Minute)
+		_, err = cache.get(Context(), existing.Hash, loader(existing, nil, &calls))
+		require.NoError(t, err)
+		require.Equal(t, 2, calls)
+	})
+
+	t.Run("least recently used is evicted", func(t *testing.T) {
+		cache := NewResolveCache(2, time.Minute, time.Minute)
+
+		var calls int
+		for _, hash := range []string{"a", "b", "a", "c"} {
+			_, err := cache.get(Context(), hash, loader(shorten.Entity{ID: int64(hash[0]), Hash: hash, Workspace: DefaultWorkspace}, nil, &calls))
+			require.NoError(t, err)
+		}
+		require.Equal(t, 3, calls)
+		require.Equal(t, 2, cache.Stats().Entries)
+
+		_, err := cache.get(Context(), "a", loader(shorten.Entity{ID: 'a', Hash: "a", Workspace: DefaultWorkspace}, nil, &calls))
+		require.NoError(t, err)
+		require.Equal(t, 3, calls)
+
+		_, err = cache.get(Context(), "b", loader(shorten.Entity{ID: 'b', Hash: "b", Workspace: DefaultWorkspace}, nil, &calls))
+		require.NoError(t, err)
+		require.Equal(t, 4, calls)
+	})
+
+	t.Run("invalidate", func(t *testing.T) {
+		cache := NewResolveCache(10, time.Minute, time.Minute)
+		existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567", Workspace: DefaultWorkspace}
+
+		var calls int
+		_, err := cache.get(Context(), existing.Hash, loader(existing, nil, &calls))
+		require.NoError(t, err)
+		_, err = cache.get(Context(), "1", loader(shorten.Entity{}, internal.ErrNotFound, &calls))
+		require.True(t, errors.Is(err, internal.ErrNotFound), err)
+
+		cache.invalidate("1")
+		cache.invalidateID(existing.ID)
+		require.Equal(t, 0, cache.Stats().Entries)
+
+		_, err = cache.get(Context(), existing.Hash, loader(existing, nil, &calls))
+		require.NoError(t, err)
+		_, err = cache.get(Context(), "1", loader(existing, nil, &calls))
+		require.NoError(t, err)
+		require.Equal(t, 4, calls)
+	})
+
+	t.Run("invalidated during load", func(t *testing.T) {
+		cache := NewResolveCache(10, time.Minute, time.Minute)
+		existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567", Workspace: DefaultWorkspace}
+
+		var calls int
+		_, err := cache.get(Context(), existing.Hash, func(context.Context) (shorten.Entity, error) {
+			calls++
+			cache.invalidateID(existing.ID)
+			return existing, nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 0, cache.Stats().Entries)
+	})
+
+	t.Run("concurrent misses", func(t *testing.T) {
+		cache := NewResolveCache(10, time.Minute, time.Minute)
+		existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567", Workspace: DefaultWorkspace}
+
+		const callers = 10
+		var loads int
+		var started sync.WaitGroup
+		started.Add(callers)
+		release := make(chan struct{})
+		load := func(context.Context) (shorten.Entity, error) {
+			loads++
+			<-release
+			return existing, nil
+		}
+
+		var wg sync.WaitGroup
+		results := make(chan shorten.Entity, callers)
+		for i := 0; i < callers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				started.Done()
+				actual, err := cache.get(Context(), existing.Hash, load)
+				require.NoError(t, err)
+				results <- actual
+			}()
+		}
+
+		started.Wait()
+		time.Sleep(10 * time.Millisecond) // lets the callers to join the load
+		close(release)
+		wg.Wait()
+		close(results)
+
+		for actual := range results {
+			require.Equal(t, existing, actual)
+		}
+		require.Equal(t, 1, loads)
+	})
+
+	t.Run("first caller cancelled", func(t *testing.T) {
+		cache := NewResolveCache(10, time.Minute, time.Minute)
+		existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567", Workspace: DefaultWorkspace}
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		load := func(ctx context.Context) (shorten.Entity, error) {
+			close(started)
+			<-release
+			return existing, ctx.Err()
+		}
+
+		ctx, cancel := context.WithCancel(Context())
+		first := make(chan error, 1)
+		go func() {
+			_, err := cache.get(ctx, existing.Hash, load)
+			first <- err
+		}()
+		<-started
+
+		second := make(chan error, 1)
+		go func() {
+			actual, err := cache.get(Context(), existing.Hash, load)
+			require.Equal(t, existing, actual)
+			second <- err
+		}()
+		time.Sleep(10 * time.Millisecond) // lets the second caller to join the load
+
+		cancel()
+		require.True(t, errors.Is(<-first, context.Canceled))
+
+		close(release)
+		require.NoError(t, <-second)
+	})
+}
+
+func TestService_Resolve_cached(t *testing.T) {
+	existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567", Workspace: DefaultWorkspace}
+
+	t.Run("hit", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), existing.Hash).Return(existing, nil)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil, WithResolveCache(NewResolveCache(10, time.Minute, time.Minute)))
+		for i := 0; i < 2; i++ {
+			actual, err := srv.Resolve(Context(), existing.Hash, Click{})
+			require.NoError(t, err)
+			require.Equal(t, existing.URL, actual)
+		}
+		require.Len(t, srv.clickQueue, 2)
+	})
+
+	t.Run("limited", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		limited := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567", MaxClicks: 1, Workspace: DefaultWorkspace}
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), limited.Hash).Return(limited, nil)
+		gomock.InOrder(
+			mockStorage.EXPECT().IncrementClicks(gomock.Any(), gomock.Any(), limited.ID).Return(nil),
+			mockStorage.EXPECT().IncrementClicks(gomock.Any(), gomock.Any(), limited.ID).Return(internal.ErrExpired),
+		)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil, WithResolveCache(NewResolveCache(10, time.Minute, time.Minute)))
+		_, err := srv.Resolve(Context(), limited.Hash, Click{})
+		require.NoError(t, err)
+
+		_, err = srv.Resolve(Context(), limited.Hash, Click{})
+		require.True(t, errors.Is(err, internal.ErrExpired), err)
+	})
+
+	t.Run("created after miss", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		gomock.InOrder(
+			mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), "summer-sale").Return(shorten.Entity{}, internal.ErrNotFound),
+			mockStorage.EXPECT().Persist(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(1), nil),
+			mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), "summer-sale").Return(existing, nil),
+		)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil, WithResolveCache(NewResolveCache(10, time.Minute, time.Minute)))
+		_, err := srv.Resolve(Context(), "summer-sale", Click{})
+		require.True(t, errors.Is(err, internal.ErrNotFound), err)
+
+		_, err = srv.Create(Context(), Entity{URL: existing.URL, Hash: "summer-sale"})
+		require.NoError(t, err)
+
+		_, err = srv.Resolve(Context(), "summer-sale", Click{})
+		require.NoError(t, err)
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		gomock.InOrder(
+			mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), existing.Hash).Return(existing, nil),
+			mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), existing.ID).Return(existing, nil),
+			mockStorage.EXPECT().Delete(gomock.Any(), gomock.Any(), existing.ID).Return(nil),
+			mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), existing.Hash).Return(shorten.Entity{}, internal.ErrNotFound),
+		)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil, WithResolveCache(NewResolveCache(10, time.Minute, time.Minute)))
+		_, err := srv.Resolve(Context(), existing.Hash, Click{})
+		require.NoError(t, err)
+
+		require.NoError(t, srv.Delete(Context(), existing.ID))
+
+		_, err = srv.Resolve(Context(), existing.Hash, Click{})
+		require.True(t, errors.Is(err, internal.ErrNotFound), err)
+	})
+
+	t.Run("updated", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		updated := existing
+		updated.URL = "https://example.com/updated"
+		updated.Hash = "winter-sale"
+
+		mockStorage := NewMockStorage(ctrl)
+		gomock.InOrder(
+			mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), existing.Hash).Return(existing, nil),
+			mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), updated.Hash).Return(shorten.Entity{}, internal.ErrNotFound),
+			mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), existing.ID).Return(existing, nil),
+			mockStorage.EXPECT().Update(gomock.Any(), gomock.Any(), updated).Return(nil),
+			mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), existing.ID).Return(updated, nil),
+			mockStorage.EXPECT().Tags(gomock.Any(), gomock.Any(), existing.ID).Return(nil, nil),
+			mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), existing.Hash).Return(shorten.Entity{}, internal.ErrNotFound),
+			mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), updated.Hash).Return(updated, nil),
+		)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil, WithResolveCache(NewResolveCache(10, time.Minute, time.Minute)))
+		_, err := srv.Resolve(Context(), existing.Hash, Click{})
+		require.NoError(t, err)
+		_, err = srv.Resolve(Context(), updated.Hash, Click{})
+		require.True(t, errors.Is(err, internal.ErrNotFound), err)
+
+		_, err = srv.Update(Context(), existing.ID, Patch{URL: &updated.URL, Alias: &updated.Hash})
+		require.NoError(t, err)
+
+		_, err = srv.Resolve(Context(), existing.Hash, Click{})
+		require.True(t, errors.Is(err, internal.ErrNotFound), err)
+		actual, err := srv.Resolve(Context(), updated.Hash, Click{})
+		require.NoError(t, err)
+		require.Equal(t, updated.URL, actual)
+	})
+}
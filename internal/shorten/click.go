@@ -0,0 +1,194 @@
+package shorten
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/logging"
+	"github.com/pavelmemory/jobtome/internal/storage"
+	"github.com/pavelmemory/jobtome/internal/storage/click"
+	"github.com/pavelmemory/jobtome/internal/tracing"
+)
+
+const (
+	// clickQueueSize is a number of clicks that could wait to be persisted.
+	// Clicks made while the queue is full are dropped.
+	clickQueueSize = 1024
+	// clickDrainTimeout limits the time the queued clicks are persisted for after `RecordClicks` is cancelled.
+	clickDrainTimeout = 5 * time.Second
+	// droppedClicksReportInterval is a period the dropped clicks are reported with in the log.
+	droppedClicksReportInterval = 10 * time.Second
+	// topStatsLimit is a number of entries returned for each top-N statistics.
+	topStatsLimit = 10
+	// maxStatsDays is the longest period daily statistics could be requested for.
+	maxStatsDays = 366
+)
+
+// Click holds details about a single redirect made with the shorten.
+type Click struct {
+	ShortenID int64
+	ClickedAt time.Time
+	Referer   string
+	UserAgent string
+	IP        string
+	// counted is set if the click is already included into the shorten's number of clicks.
+	counted bool
+}
+
+type (
+	Counter      = click.Counter
+	DailyCounter = click.DailyCounter
+)
+
+// Stats is a summary of clicks made with the shorten.
+type Stats struct {
+	Total         int64
+	Daily         []DailyCounter
+	TopReferers   []Counter
+	TopUserAgents []Counter
+}
+
+// enqueueClick schedules the click to be persisted without blocking the caller.
+// The click is dropped if the queue is full, so redirects are never slowed down by statistics.
+func (s *Service) enqueueClick(c Click) {
+	select {
+	case s.clickQueue <- c:
+	default:
+		s.dropClicks(1)
+	}
+}
+
+// dropClicks counts the clicks that won't be persisted, they are reported by `RecordClicks`.
+func (s *Service) dropClicks(n int64) {
+	s.droppedClicks.Add(n)
+	clicksDroppedTotal.Add(float64(n))
+}
+
+// RecordClicks persists clicks registered by `Resolve` until the `ctx` is cancelled.
+// Once the `ctx` is cancelled the clicks left in the queue are persisted within `clickDrainTimeout`.
+// It is blocking, so it should be run in a separate goroutine.
+// The `ctx` must have a logger injected into it.
+func (s *Service) RecordClicks(ctx context.Context) {
+	logger := logging.FromContext(ctx).WithString("component", "Service").WithString("method", "RecordClicks")
+
+	ticker := time.NewTicker(droppedClicksReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.drainClicks(ctx, logger)
+			return
+		case <-ticker.C:
+			s.reportDroppedClicks(logger)
+		case c := <-s.clickQueue:
+			if ctx.Err() != nil {
+				// the click is taken while the shutdown begins, so it is persisted together with the queued ones
+				s.drainClicks(ctx, logger, c)
+				return
+			}
+			s.recordClick(ctx, logger, c)
+		}
+	}
+}
+
+// drainClicks persists the `taken` clicks and the clicks left in the queue.
+// The clicks not persisted within `clickDrainTimeout` are dropped.
+func (s *Service) drainClicks(ctx context.Context, logger logging.Logger, taken ...Click) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), clickDrainTimeout)
+	defer func() {
+		cancel()
+		s.reportDroppedClicks(logger)
+	}()
+
+	for _, c := range taken {
+		s.recordClick(ctx, logger, c)
+	}
+
+	for ctx.Err() == nil {
+		select {
+		case c := <-s.clickQueue:
+			s.recordClick(ctx, logger, c)
+		default:
+			return
+		}
+	}
+
+	s.dropClicks(int64(len(s.clickQueue)))
+}
+
+// reportDroppedClicks logs the number of clicks dropped since the previous report, if any.
+func (s *Service) reportDroppedClicks(logger logging.Logger) {
+	if dropped := s.droppedClicks.Swap(0); dropped > 0 {
+		logger.WithInt64("dropped", dropped).Warn("clicks are dropped")
+	}
+}
+
+// recordClick persists the click and increases the number of clicks of the shorten unless it is already counted.
+func (s *Service) recordClick(ctx context.Context, logger logging.Logger, c Click) {
+	entity := click.Entity{
+		ShortenID: c.ShortenID,
+		ClickedAt: c.ClickedAt,
+		Referer:   c.Referer,
+		UserAgent: c.UserAgent,
+		IP:        c.IP,
+	}
+
+	if err := s.tr.WithTx(ctx, func(runner storage.Runner) error {
+		if _, err := s.clicks.Persist(ctx, runner, entity); err != nil {
+			return err
+		}
+
+		if c.counted {
+			return nil
+		}
+
+		return s.storage.IncrementClicks(ctx, runner, c.ShortenID)
+	}); err != nil {
+		logger.WithError(err).WithInt64("shorten_id", c.ShortenID).Error("persist click")
+	}
+}
+
+// Stats returns statistics of clicks made with the shorten.
+// Daily statistics includes only the last `days` days.
+func (s *Service) Stats(ctx context.Context, id int64, days int) (_ Stats, err error) {
+	ctx, span := startSpan(ctx, "Stats")
+	defer func() { tracing.End(span, err) }()
+
+	if days < 1 || days > maxStatsDays {
+		return Stats{}, ValidationError{
+			Cause:   internal.ErrBadInput,
+			Details: map[string]interface{}{"days": fmt.Sprintf("is out of range [1, %d]", maxStatsDays)},
+		}
+	}
+
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+
+	var stats Stats
+	if err := s.tr.WithTx(ctx, func(runner storage.Runner) (err error) {
+		if _, err = s.retrieveOwned(ctx, runner, id); err != nil {
+			return err
+		}
+
+		if stats.Total, err = s.clicks.Count(ctx, runner, id); err != nil {
+			return err
+		}
+
+		if stats.Daily, err = s.clicks.Daily(ctx, runner, id, since); err != nil {
+			return err
+		}
+
+		if stats.TopReferers, err = s.clicks.TopReferers(ctx, runner, id, topStatsLimit); err != nil {
+			return err
+		}
+
+		stats.TopUserAgents, err = s.clicks.TopUserAgents(ctx, runner, id, topStatsLimit)
+		return err
+	}); err != nil {
+		return Stats{}, fmt.Errorf("stats of shorten %d: %w", id, err)
+	}
+
+	return stats, nil
+}
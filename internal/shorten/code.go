@@ -0,0 +1,319 @@
+package shorten
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	// HexAlphabet is a default alphabet for the codes derived from the URL hashes.
+	HexAlphabet = "0123456789abcdef"
+	// Base62Alphabet is a default alphabet for all other codes.
+	Base62Alphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+)
+
+var defaultCodeGenerator = HashCodeGenerator{newHash: md5.New, alphabet: HexAlphabet, length: 7, width: 32}
+
+// CodeKind defines how the generated code depends on the shorten.
+type CodeKind int
+
+const (
+	// ContentCode is derived from the URL, so shortens of the same URL share the code.
+	ContentCode CodeKind = iota + 1
+	// IDCode is derived from the unique ID of the persisted shorten.
+	IDCode
+	// RandomCode doesn't depend on the shorten at all.
+	RandomCode
+)
+
+// CodeGenerator generates short codes for the new shortens.
+type CodeGenerator interface {
+	// Kind returns how generated codes depend on the shorten.
+	Kind() CodeKind
+	// Generate returns a short code for the shorten with the `url` and `id`.
+	// The `id` is provided only to the generators of `IDCode` kind, for others it is always 0.
+	// The `attempt` is a number of collisions already detected for the shorten,
+	// for each attempt a different code must be returned.
+	Generate(url string, id int64, attempt int) (string, error)
+}
+
+// Code generation strategies supported by `NewCodeGenerator`.
+const (
+	StrategyMD5        = "md5"
+	StrategySHA256     = "sha256"
+	StrategyFNV        = "fnv"
+	StrategyBase62     = "base62"
+	StrategyObfuscated = "obfuscated"
+	StrategyRandom     = "random"
+)
+
+// NewCodeGenerator returns a code generator for the strategy.
+// An empty `alphabet` means a default one for the strategy: `HexAlphabet` for the hashes and `Base62Alphabet` for the rest.
+// The `length` is an exact length of the code for hashes and random codes and a minimal length for the codes derived from IDs.
+// The `salt` is used only by the obfuscated strategy.
+func NewCodeGenerator(strategy, alphabet string, length int, salt string) (CodeGenerator, error) {
+	switch strategy {
+	case StrategyMD5, StrategySHA256, StrategyFNV:
+		if alphabet == "" {
+			alphabet = HexAlphabet
+		}
+	default:
+		if alphabet == "" {
+			alphabet = Base62Alphabet
+		}
+	}
+
+	switch strategy {
+	case StrategyMD5:
+		return NewHashCodeGenerator(md5.New, alphabet, length)
+	case StrategySHA256:
+		return NewHashCodeGenerator(sha256.New, alphabet, length)
+	case StrategyFNV:
+		return NewHashCodeGenerator(func() hash.Hash { return fnv.New128a() }, alphabet, length)
+	case StrategyBase62:
+		return NewSequenceCodeGenerator(alphabet, length)
+	case StrategyObfuscated:
+		return NewObfuscatedCodeGenerator(alphabet, length, salt)
+	case StrategyRandom:
+		return NewRandomCodeGenerator(alphabet, length)
+	default:
+		return nil, fmt.Errorf("unknown code generation strategy %q", strategy)
+	}
+}
+
+// NewHashCodeGenerator returns a generator of the codes derived from the hash of the URL.
+// The digest is encoded with the `alphabet` and truncated to the `length`.
+// After collision the URL is salted with the attempt number.
+func NewHashCodeGenerator(newHash func() hash.Hash, alphabet string, length int) (HashCodeGenerator, error) {
+	if err := validateAlphabet(alphabet); err != nil {
+		return HashCodeGenerator{}, err
+	}
+
+	width := encodedWidth(newHash().Size()*8, len(alphabet))
+	if length < 1 || length > width {
+		return HashCodeGenerator{}, fmt.Errorf("length %d is out of range [1, %d]", length, width)
+	}
+
+	return HashCodeGenerator{newHash: newHash, alphabet: alphabet, length: length, width: width}, nil
+}
+
+// HashCodeGenerator generates codes of `ContentCode` kind.
+type HashCodeGenerator struct {
+	newHash  func() hash.Hash
+	alphabet string
+	length   int
+	width    int
+}
+
+func (HashCodeGenerator) Kind() CodeKind {
+	return ContentCode
+}
+
+func (g HashCodeGenerator) Generate(url string, _ int64, attempt int) (string, error) {
+	if attempt > 0 {
+		url += "#" + strconv.Itoa(attempt)
+	}
+
+	h := g.newHash()
+	_, _ = h.Write([]byte(url)) // never returns an error
+	digest := new(big.Int).SetBytes(h.Sum(nil))
+
+	return encodeBig(digest, g.alphabet, g.width)[:g.length], nil
+}
+
+// NewSequenceCodeGenerator returns a generator of the codes derived from the shorten's ID
+// by encoding it with the `alphabet`, base62 by default.
+// Codes shorter than `minLength` are left-padded with the first character of the alphabet.
+func NewSequenceCodeGenerator(alphabet string, minLength int) (SequenceCodeGenerator, error) {
+	if err := validateAlphabet(alphabet); err != nil {
+		return SequenceCodeGenerator{}, err
+	}
+
+	if minLength < 1 {
+		return SequenceCodeGenerator{}, fmt.Errorf("length %d is lesser then 1", minLength)
+	}
+
+	return SequenceCodeGenerator{alphabet: alphabet, minLength: minLength}, nil
+}
+
+// SequenceCodeGenerator generates predictable codes of `IDCode` kind.
+type SequenceCodeGenerator struct {
+	alphabet  string
+	minLength int
+}
+
+func (SequenceCodeGenerator) Kind() CodeKind {
+	return IDCode
+}
+
+func (g SequenceCodeGenerator) Generate(_ string, id int64, attempt int) (string, error) {
+	if id < 1 {
+		return "", fmt.Errorf("id %d is lesser then 1", id)
+	}
+
+	return encodeBig(big.NewInt(id), g.alphabet, g.minLength) + attemptSuffix(g.alphabet, attempt), nil
+}
+
+// NewObfuscatedCodeGenerator returns a generator of the codes derived from the shorten's ID
+// that don't look sequential (similar to Hashids).
+// The first character of the code selects an alphabet permutation used to encode the ID,
+// all permutations depend on the `salt`, so the codes can't be guessed without knowing it.
+func NewObfuscatedCodeGenerator(alphabet string, minLength int, salt string) (ObfuscatedCodeGenerator, error) {
+	if err := validateAlphabet(alphabet); err != nil {
+		return ObfuscatedCodeGenerator{}, err
+	}
+
+	if minLength < 2 {
+		return ObfuscatedCodeGenerator{}, fmt.Errorf("length %d is lesser then 2", minLength)
+	}
+
+	return ObfuscatedCodeGenerator{
+		alphabet:  shuffle(alphabet, salt),
+		minLength: minLength,
+		salt:      salt,
+	}, nil
+}
+
+// ObfuscatedCodeGenerator generates unpredictable codes of `IDCode` kind.
+type ObfuscatedCodeGenerator struct {
+	alphabet  string
+	minLength int
+	salt      string
+}
+
+func (ObfuscatedCodeGenerator) Kind() CodeKind {
+	return IDCode
+}
+
+func (g ObfuscatedCodeGenerator) Generate(_ string, id int64, attempt int) (string, error) {
+	if id < 1 {
+		return "", fmt.Errorf("id %d is lesser then 1", id)
+	}
+
+	lottery := g.alphabet[id%int64(len(g.alphabet))]
+	alphabet := shuffle(g.alphabet, string(lottery)+g.salt)
+
+	return string(lottery) + encodeBig(big.NewInt(id), alphabet, g.minLength-1) + attemptSuffix(alphabet, attempt), nil
+}
+
+// NewRandomCodeGenerator returns a generator of cryptographically random codes of the `length`.
+func NewRandomCodeGenerator(alphabet string, length int) (RandomCodeGenerator, error) {
+	if err := validateAlphabet(alphabet); err != nil {
+		return RandomCodeGenerator{}, err
+	}
+
+	if length < 1 {
+		return RandomCodeGenerator{}, fmt.Errorf("length %d is lesser then 1", length)
+	}
+
+	return RandomCodeGenerator{alphabet: alphabet, length: length}, nil
+}
+
+// RandomCodeGenerator generates unguessable codes of `RandomCode` kind.
+type RandomCodeGenerator struct {
+	alphabet string
+	length   int
+}
+
+func (RandomCodeGenerator) Kind() CodeKind {
+	return RandomCode
+}
+
+func (g RandomCodeGenerator) Generate(string, int64, int) (string, error) {
+	return randomString(g.alphabet, g.length)
+}
+
+func randomString(alphabet string, length int) (string, error) {
+	max := big.NewInt(int64(len(alphabet)))
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("random: %w", err)
+		}
+		code[i] = alphabet[n.Int64()]
+	}
+
+	return string(code), nil
+}
+
+func validateAlphabet(alphabet string) error {
+	if len(alphabet) < 2 {
+		return errors.New("alphabet must have at least 2 characters")
+	}
+
+	for i := range alphabet {
+		if alphabet[i] > 127 {
+			return errors.New("alphabet must consist of ASCII characters")
+		}
+		if strings.IndexByte(alphabet[i+1:], alphabet[i]) >= 0 {
+			return fmt.Errorf("alphabet has duplicated character %q", alphabet[i])
+		}
+	}
+
+	return nil
+}
+
+// encodedWidth returns a number of characters required to encode any number of `bits` size with `base` characters.
+func encodedWidth(bits, base int) int {
+	return int(math.Ceil(float64(bits) / math.Log2(float64(base))))
+}
+
+// encodeBig encodes non-negative `n` with the `alphabet` (the most significant character first)
+// and left-pads the result with the first character of the alphabet up to `width`.
+func encodeBig(n *big.Int, alphabet string, width int) string {
+	base := big.NewInt(int64(len(alphabet)))
+	n = new(big.Int).Set(n)
+	mod := new(big.Int)
+
+	var encoded []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, base, mod)
+		encoded = append(encoded, alphabet[mod.Int64()])
+	}
+
+	for len(encoded) < width {
+		encoded = append(encoded, alphabet[0])
+	}
+
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+
+	return string(encoded)
+}
+
+// attemptSuffix returns a suffix that distinguishes the code generated for the `attempt`.
+func attemptSuffix(alphabet string, attempt int) string {
+	if attempt < 1 {
+		return ""
+	}
+
+	return encodeBig(big.NewInt(int64(attempt)), alphabet, 1)
+}
+
+// shuffle returns a permutation of the `alphabet` that depends only on the `salt`.
+func shuffle(alphabet, salt string) string {
+	if salt == "" {
+		return alphabet
+	}
+
+	shuffled := []byte(alphabet)
+	for i, v, p := len(shuffled)-1, 0, 0; i > 0; i, v = i-1, v+1 {
+		v %= len(salt)
+		p += int(salt[v])
+		j := (int(salt[v]) + v + p) % i
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	}
+
+	return string(shuffled)
+}
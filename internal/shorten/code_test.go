@@ -0,0 +1,138 @@
+package shorten
+
+import (
+	"crypto/md5"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCodeGenerator(t *testing.T) {
+	t.Run("kinds", func(t *testing.T) {
+		for strategy, kind := range map[string]CodeKind{
+			StrategyMD5:        ContentCode,
+			StrategySHA256:     ContentCode,
+			StrategyFNV:        ContentCode,
+			StrategyBase62:     IDCode,
+			StrategyObfuscated: IDCode,
+			StrategyRandom:     RandomCode,
+		} {
+			generator, err := NewCodeGenerator(strategy, "", 7, "salt")
+			require.NoError(t, err, strategy)
+			require.Equal(t, kind, generator.Kind(), strategy)
+		}
+	})
+
+	t.Run("unknown strategy", func(t *testing.T) {
+		_, err := NewCodeGenerator("unknown", "", 7, "")
+		require.EqualError(t, err, `unknown code generation strategy "unknown"`)
+	})
+
+	t.Run("bad alphabet", func(t *testing.T) {
+		_, err := NewCodeGenerator(StrategyRandom, "a", 7, "")
+		require.EqualError(t, err, "alphabet must have at least 2 characters")
+
+		_, err = NewCodeGenerator(StrategyRandom, "abca", 7, "")
+		require.EqualError(t, err, `alphabet has duplicated character 'a'`)
+	})
+
+	t.Run("bad length", func(t *testing.T) {
+		_, err := NewCodeGenerator(StrategyMD5, "", 33, "")
+		require.EqualError(t, err, "length 33 is out of range [1, 32]")
+
+		_, err = NewCodeGenerator(StrategyBase62, "", 0, "")
+		require.EqualError(t, err, "length 0 is lesser then 1")
+	})
+}
+
+func TestHashCodeGenerator(t *testing.T) {
+	t.Run("compatible with hex digest", func(t *testing.T) {
+		generator, err := NewHashCodeGenerator(md5.New, HexAlphabet, 32)
+		require.NoError(t, err)
+
+		code, err := generator.Generate("https://www.google.com", 0, 0)
+		require.NoError(t, err)
+		require.Equal(t, "8ffdefbdec956b595d257f0aaeefd623", code)
+		require.Equal(t, "8ffdefb", code[:7])
+	})
+
+	t.Run("attempts", func(t *testing.T) {
+		generator, err := NewCodeGenerator(StrategySHA256, Base62Alphabet, 10, "")
+		require.NoError(t, err)
+
+		first, err := generator.Generate("https://example.com", 0, 0)
+		require.NoError(t, err)
+		require.Len(t, first, 10)
+
+		again, err := generator.Generate("https://example.com", 0, 0)
+		require.NoError(t, err)
+		require.Equal(t, first, again)
+
+		second, err := generator.Generate("https://example.com", 0, 1)
+		require.NoError(t, err)
+		require.Len(t, second, 10)
+		require.NotEqual(t, first, second)
+	})
+}
+
+func TestSequenceCodeGenerator(t *testing.T) {
+	generator, err := NewSequenceCodeGenerator(Base62Alphabet, 3)
+	require.NoError(t, err)
+
+	for id, exp := range map[int64]string{1: "001", 61: "00Z", 62: "010", 238328: "1000"} {
+		code, err := generator.Generate("", id, 0)
+		require.NoError(t, err)
+		require.Equal(t, exp, code)
+	}
+
+	code, err := generator.Generate("", 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, "0011", code)
+
+	_, err = generator.Generate("", 0, 0)
+	require.EqualError(t, err, "id 0 is lesser then 1")
+}
+
+func TestObfuscatedCodeGenerator(t *testing.T) {
+	generator, err := NewObfuscatedCodeGenerator(Base62Alphabet, 5, "secret")
+	require.NoError(t, err)
+
+	codes := map[string]int64{}
+	for id := int64(1); id <= 10000; id++ {
+		code, err := generator.Generate("", id, 0)
+		require.NoError(t, err)
+		require.Len(t, code, 5)
+		require.Equal(t, "", strings.Trim(code, Base62Alphabet))
+
+		prev, ok := codes[code]
+		require.False(t, ok, "ids %d and %d share the code %q", prev, id, code)
+		codes[code] = id
+	}
+
+	first, err := generator.Generate("", 1, 0)
+	require.NoError(t, err)
+	second, err := generator.Generate("", 2, 0)
+	require.NoError(t, err)
+	require.NotEqual(t, first[1:], second[1:], "neighbour ids have to be encoded with different alphabets")
+
+	other, err := NewObfuscatedCodeGenerator(Base62Alphabet, 5, "another")
+	require.NoError(t, err)
+	otherFirst, err := other.Generate("", 1, 0)
+	require.NoError(t, err)
+	require.NotEqual(t, first, otherFirst)
+}
+
+func TestRandomCodeGenerator(t *testing.T) {
+	generator, err := NewRandomCodeGenerator("ab", 32)
+	require.NoError(t, err)
+
+	first, err := generator.Generate("https://example.com", 0, 0)
+	require.NoError(t, err)
+	require.Len(t, first, 32)
+	require.Equal(t, "", strings.Trim(first, "ab"))
+
+	second, err := generator.Generate("https://example.com", 0, 0)
+	require.NoError(t, err)
+	require.NotEqual(t, first, second)
+}
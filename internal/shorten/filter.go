@@ -0,0 +1,84 @@
+package shorten
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/storage/shorten"
+)
+
+// minSearchLength is the shortest part of the URL the shortens are searched by.
+// Shorter parts can't be looked up in the trigram index, so they would scan all shortens.
+// The part is matched literally, so `%` and `_` are counted as any other character.
+const minSearchLength = 3
+
+// Filter narrows the listed shortens, see `List`.
+type Filter = shorten.Filter
+
+// validateFilter verifies the `filter` and returns it normalized the same way as the matched fields of the shortens.
+func validateFilter(filter Filter) (Filter, error) {
+	if filter.Domain != "" {
+		domain, err := normalizeHost(strings.TrimSuffix(strings.TrimSpace(filter.Domain), "."))
+		if err != nil || domain == "" {
+			return Filter{}, ValidationError{
+				Cause:   internal.ErrBadInput,
+				Details: map[string]interface{}{"domain": "not a valid domain"},
+			}
+		}
+		filter.Domain = domain
+	}
+
+	if filter.URL = strings.TrimSpace(filter.URL); filter.URL != "" && utf8.RuneCountInString(filter.URL) < minSearchLength {
+		return Filter{}, ValidationError{
+			Cause:   internal.ErrBadInput,
+			Details: map[string]interface{}{"url": "is shorter then 3 characters"},
+		}
+	}
+
+	if !filter.CreatedFrom.IsZero() && !filter.CreatedTo.IsZero() && !filter.CreatedFrom.Before(filter.CreatedTo) {
+		return Filter{}, ValidationError{
+			Cause:   internal.ErrBadInput,
+			Details: map[string]interface{}{"created_to": "not after created_from"},
+		}
+	}
+
+	filter.Tag = strings.TrimSpace(filter.Tag)
+
+	switch filter.Status {
+	case "", shorten.StatusActive, shorten.StatusExpired, shorten.StatusDisabled:
+	default:
+		return Filter{}, ValidationError{
+			Cause:   internal.ErrBadInput,
+			Details: map[string]interface{}{"status": "is unknown"},
+		}
+	}
+
+	return filter, nil
+}
+
+// validateSort verifies the shortens could be ordered by the `sort`.
+func validateSort(sort string) error {
+	switch sort {
+	case "", shorten.SortCreatedAt, shorten.SortCreatedAtDesc, shorten.SortClicks, shorten.SortClicksDesc:
+		return nil
+	default:
+		return ValidationError{
+			Cause:   internal.ErrBadInput,
+			Details: map[string]interface{}{"sort": "is unknown"},
+		}
+	}
+}
+
+// NextPage returns the pager of the page following the page of the `pager` that ended with the `last` shorten.
+func NextPage(pager Pager, last Entity) Pager {
+	next := Pager{Limit: pager.Limit, Sort: pager.Sort, AfterID: last.ID}
+	switch strings.TrimPrefix(pager.Sort, "-") {
+	case shorten.SortCreatedAt:
+		next.AfterKey = last.CreatedAt.Unix()
+	case shorten.SortClicks:
+		next.AfterKey = last.Clicks
+	}
+
+	return next
+}
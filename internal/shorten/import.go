@@ -0,0 +1,68 @@
+package shorten
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pavelmemory/jobtome/internal/storage"
+	"github.com/pavelmemory/jobtome/internal/storage/shorten"
+	"github.com/pavelmemory/jobtome/internal/tracing"
+)
+
+// Import creates a shorten exactly as it is provided and returns back its unique ID.
+// Unlike `Create` it keeps the code as is without applying the alias policy and accepts expired shortens,
+// so the shortens exported from another instance could be restored.
+// The URL is normalized and checked against the URL and destination policies the same way as by `Create`.
+// The ID of the imported shorten is not preserved, the owner is preserved, the workspace is preserved if it is set,
+// otherwise the shorten is imported into the workspace of the `ctx`.
+func (s *Service) Import(ctx context.Context, short Entity) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "Import")
+	defer func() { tracing.End(span, err) }()
+
+	if short.URL, err = s.urlPolicy.Normalize(short.URL); err != nil {
+		return 0, err
+	}
+
+	if err := s.checkDestination(short.URL); err != nil {
+		return 0, err
+	}
+
+	if err := isNotBlank(short.Hash, "hash"); err != nil {
+		return 0, err
+	}
+
+	tags, err := normalizeTags(short.Tags)
+	if err != nil {
+		return 0, err
+	}
+
+	workspace := short.Workspace
+	if workspace == "" {
+		workspace = WorkspaceFrom(ctx)
+	}
+
+	var id int64
+	if err := s.tr.WithTx(ctx, func(runner storage.Runner) (err error) {
+		id, err = s.storage.Persist(ctx, runner, shorten.Entity{
+			URL:       short.URL,
+			Hash:      short.Hash,
+			CreatedAt: time.Now(),
+			ExpiresAt: short.ExpiresAt,
+			MaxClicks: short.MaxClicks,
+			Workspace: workspace,
+			Owner:     short.Owner,
+		})
+		if err != nil || len(tags) == 0 {
+			return err
+		}
+
+		return s.storage.SetTags(ctx, runner, id, tags)
+	}); err != nil {
+		return 0, fmt.Errorf("import shorten %q: %w", short.Hash, err)
+	}
+
+	s.invalidate(short.Hash)
+
+	return id, nil
+}
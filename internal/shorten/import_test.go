@@ -0,0 +1,100 @@
+package shorten
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/storage"
+	"github.com/pavelmemory/jobtome/internal/storage/shorten"
+)
+
+func TestService_Import(t *testing.T) {
+	t.Run("validation", func(t *testing.T) {
+		srv := NewService(nil, nil, nil)
+		_, err := srv.Import(Context(), Entity{URL: "https://example.com"})
+		exp := ValidationError{Cause: internal.ErrBadInput, Details: map[string]interface{}{"hash": "blank or empty"}}
+		require.Equal(t, exp, err)
+
+		for _, url := range []string{"javascript:alert(1)", "not a url", "ftp://example.com"} {
+			_, err = srv.Import(Context(), Entity{URL: url, Hash: "api"})
+			var verr ValidationError
+			require.True(t, errors.As(err, &verr), url)
+			require.Contains(t, verr.Details, "url")
+		}
+	})
+
+	t.Run("blocked url", func(t *testing.T) {
+		srv := NewService(nil, nil, nil, WithDestinationPolicy(testDestinationPolicy{"evil.com"}, false))
+		_, err := srv.Import(Context(), Entity{URL: "https://evil.com/login", Hash: "api"})
+		exp := ValidationError{Cause: internal.ErrBadInput, Details: map[string]interface{}{"url": "blocked: denied by test"}}
+		require.Equal(t, exp, err)
+	})
+
+	t.Run("normalized url", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().
+			Persist(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ storage.Runner, short shorten.Entity) (int64, error) {
+				require.Equal(t, "https://example.com", short.URL)
+				return 1, nil
+			})
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		_, err := srv.Import(Context(), Entity{URL: "HTTPS://Example.COM:443/", Hash: "api"})
+		require.NoError(t, err)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		expiresAt := time.Now().Add(-time.Hour)
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().
+			Persist(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ storage.Runner, short shorten.Entity) (int64, error) {
+				require.Equal(t, "api", short.Hash)
+				require.Equal(t, expiresAt, short.ExpiresAt)
+				return 1, nil
+			})
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		id, err := srv.Import(Context(), Entity{URL: "https://example.com", Hash: "api", ExpiresAt: expiresAt})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), id)
+	})
+
+	t.Run("tags", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().Persist(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(1), nil)
+		mockStorage.EXPECT().SetTags(gomock.Any(), gomock.Any(), int64(1), []string{"a", "b"}).Return(nil)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		_, err := srv.Import(Context(), Entity{URL: "https://example.com", Hash: "1234567", Tags: []string{"b", "a"}})
+		require.NoError(t, err)
+	})
+
+	t.Run("exists", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().Persist(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(0), internal.ErrNotUnique)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		_, err := srv.Import(Context(), Entity{URL: "https://example.com", Hash: "1234567"})
+		require.True(t, errors.Is(err, internal.ErrNotUnique), err)
+	})
+}
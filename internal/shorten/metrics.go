@@ -0,0 +1,91 @@
+package shorten
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/pavelmemory/jobtome/internal"
+)
+
+// Outcomes of `Resolve` used as a label of the resolves metric.
+const (
+	// outcomeHit means the shorten was found and the caller is redirected.
+	outcomeHit = "hit"
+	// outcomeMiss means the shorten doesn't exist.
+	outcomeMiss = "miss"
+	// outcomeExpired means the shorten exists but it is expired or has no clicks left.
+	outcomeExpired = "expired"
+	// outcomeBlocked means the shorten exists but its URL is blocked by the destination policy.
+	outcomeBlocked = "blocked"
+	// outcomeError means the shorten couldn't be resolved because of the failure.
+	outcomeError = "error"
+)
+
+var (
+	createdTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "jobtome",
+		Subsystem: "shorten",
+		Name:      "created_total",
+		Help:      "Number of shortens created, the existing ones returned for the same URL are not counted.",
+	})
+	resolvesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "jobtome",
+		Subsystem: "shorten",
+		Name:      "resolves_total",
+		Help:      "Number of resolved codes by the outcome.",
+	}, []string{"outcome"})
+	clicksDroppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "jobtome",
+		Subsystem: "shorten",
+		Name:      "clicks_dropped_total",
+		Help:      "Number of clicks not persisted because the queue was full or the shutdown timed out.",
+	})
+)
+
+// observeResolve records the outcome of `Resolve` that returned the `err`.
+func observeResolve(err error) {
+	outcome := outcomeError
+	switch {
+	case err == nil:
+		outcome = outcomeHit
+	case errors.Is(err, internal.ErrNotFound):
+		outcome = outcomeMiss
+	case errors.Is(err, internal.ErrExpired):
+		outcome = outcomeExpired
+	case errors.Is(err, internal.ErrBlocked):
+		outcome = outcomeBlocked
+	case errors.Is(err, internal.ErrBadInput):
+		outcome = outcomeMiss
+	}
+
+	resolvesTotal.WithLabelValues(outcome).Inc()
+}
+
+var (
+	cacheHitsDesc = prometheus.NewDesc(
+		"jobtome_shorten_cache_hits_total", "Number of resolves answered from the cache.", nil, nil,
+	)
+	cacheMissesDesc = prometheus.NewDesc(
+		"jobtome_shorten_cache_misses_total", "Number of resolves that required a query to the database.", nil, nil,
+	)
+	cacheEntriesDesc = prometheus.NewDesc(
+		"jobtome_shorten_cache_entries", "Number of codes currently cached.", nil, nil,
+	)
+)
+
+// Describe implements `prometheus.Collector`, so the cache counters could be exported.
+func (c *ResolveCache) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheHitsDesc
+	ch <- cacheMissesDesc
+	ch <- cacheEntriesDesc
+}
+
+// Collect implements `prometheus.Collector`.
+func (c *ResolveCache) Collect(ch chan<- prometheus.Metric) {
+	stats := c.Stats()
+	ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(cacheEntriesDesc, prometheus.GaugeValue, float64(stats.Entries))
+}
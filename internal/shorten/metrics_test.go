@@ -0,0 +1,60 @@
+package shorten
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/storage/shorten"
+)
+
+func TestObserveResolve(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err     error
+		outcome string
+	}{
+		"resolved":  {err: nil, outcome: outcomeHit},
+		"not found": {err: fmt.Errorf("retrieve: %w", internal.ErrNotFound), outcome: outcomeMiss},
+		"expired":   {err: fmt.Errorf("retrieve: %w", internal.ErrExpired), outcome: outcomeExpired},
+		"failure":   {err: errors.New("boom"), outcome: outcomeError},
+	} {
+		t.Run(name, func(t *testing.T) {
+			counter := resolvesTotal.WithLabelValues(tc.outcome)
+			exp := testutil.ToFloat64(counter) + 1
+
+			observeResolve(tc.err)
+			require.Equal(t, exp, testutil.ToFloat64(counter))
+		})
+	}
+}
+
+func TestResolveCache_Collect(t *testing.T) {
+	cache := NewResolveCache(10, time.Minute, time.Minute)
+	existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567", Workspace: DefaultWorkspace}
+	for i := 0; i < 3; i++ {
+		_, err := cache.get(Context(), existing.Hash, func(context.Context) (shorten.Entity, error) {
+			return existing, nil
+		})
+		require.NoError(t, err)
+	}
+
+	const exp = `
+		# HELP jobtome_shorten_cache_hits_total Number of resolves answered from the cache.
+		# TYPE jobtome_shorten_cache_hits_total counter
+		jobtome_shorten_cache_hits_total 2
+		# HELP jobtome_shorten_cache_misses_total Number of resolves that required a query to the database.
+		# TYPE jobtome_shorten_cache_misses_total counter
+		jobtome_shorten_cache_misses_total 1
+		# HELP jobtome_shorten_cache_entries Number of codes currently cached.
+		# TYPE jobtome_shorten_cache_entries gauge
+		jobtome_shorten_cache_entries 1
+	`
+	require.NoError(t, testutil.CollectAndCompare(cache, strings.NewReader(exp)))
+}
@@ -6,12 +6,12 @@ package shorten
 
 import (
 	context "context"
-	reflect "reflect"
-
 	gomock "github.com/golang/mock/gomock"
-
 	storage "github.com/pavelmemory/jobtome/internal/storage"
+	click "github.com/pavelmemory/jobtome/internal/storage/click"
 	shorten "github.com/pavelmemory/jobtome/internal/storage/shorten"
+	reflect "reflect"
+	time "time"
 )
 
 // MockTransactioner is a mock of Transactioner interface
@@ -119,18 +119,33 @@ func (mr *MockStorageMockRecorder) Retrieve(ctx, run, id interface{}) *gomock.Ca
 }
 
 // List mocks base method
-func (m *MockStorage) List(ctx context.Context, run storage.Runner, pager shorten.Pager) ([]shorten.Entity, error) {
+func (m *MockStorage) List(ctx context.Context, run storage.Runner, workspace string, filter shorten.Filter, pager shorten.Pager) ([]shorten.Entity, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "List", ctx, run, pager)
+	ret := m.ctrl.Call(m, "List", ctx, run, workspace, filter, pager)
 	ret0, _ := ret[0].([]shorten.Entity)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // List indicates an expected call of List
-func (mr *MockStorageMockRecorder) List(ctx, run, pager interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) List(ctx, run, workspace, filter, pager interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockStorage)(nil).List), ctx, run, pager)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockStorage)(nil).List), ctx, run, workspace, filter, pager)
+}
+
+// Trash mocks base method
+func (m *MockStorage) Trash(ctx context.Context, run storage.Runner, workspace string, pager shorten.Pager) ([]shorten.Entity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Trash", ctx, run, workspace, pager)
+	ret0, _ := ret[0].([]shorten.Entity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Trash indicates an expected call of Trash
+func (mr *MockStorageMockRecorder) Trash(ctx, run, workspace, pager interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Trash", reflect.TypeOf((*MockStorage)(nil).Trash), ctx, run, workspace, pager)
 }
 
 // Delete mocks base method
@@ -147,6 +162,49 @@ func (mr *MockStorageMockRecorder) Delete(ctx, runner, id interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStorage)(nil).Delete), ctx, runner, id)
 }
 
+// Restore mocks base method
+func (m *MockStorage) Restore(ctx context.Context, runner storage.Runner, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, runner, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Restore indicates an expected call of Restore
+func (mr *MockStorageMockRecorder) Restore(ctx, runner, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockStorage)(nil).Restore), ctx, runner, id)
+}
+
+// Purge mocks base method
+func (m *MockStorage) Purge(ctx context.Context, runner storage.Runner, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", ctx, runner, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Purge indicates an expected call of Purge
+func (mr *MockStorageMockRecorder) Purge(ctx, runner, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockStorage)(nil).Purge), ctx, runner, id)
+}
+
+// PurgeDeleted mocks base method
+func (m *MockStorage) PurgeDeleted(ctx context.Context, runner storage.Runner, deadline time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeleted", ctx, runner, deadline)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeleted indicates an expected call of PurgeDeleted
+func (mr *MockStorageMockRecorder) PurgeDeleted(ctx, runner, deadline interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeleted", reflect.TypeOf((*MockStorage)(nil).PurgeDeleted), ctx, runner, deadline)
+}
+
 // ByHash mocks base method
 func (m *MockStorage) ByHash(ctx context.Context, runner storage.Runner, hash string) (shorten.Entity, error) {
 	m.ctrl.T.Helper()
@@ -161,3 +219,259 @@ func (mr *MockStorageMockRecorder) ByHash(ctx, runner, hash interface{}) *gomock
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ByHash", reflect.TypeOf((*MockStorage)(nil).ByHash), ctx, runner, hash)
 }
+
+// HashTaken mocks base method
+func (m *MockStorage) HashTaken(ctx context.Context, runner storage.Runner, hash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HashTaken", ctx, runner, hash)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HashTaken indicates an expected call of HashTaken
+func (mr *MockStorageMockRecorder) HashTaken(ctx, runner, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HashTaken", reflect.TypeOf((*MockStorage)(nil).HashTaken), ctx, runner, hash)
+}
+
+// UpdateHash mocks base method
+func (m *MockStorage) UpdateHash(ctx context.Context, runner storage.Runner, id int64, hash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateHash", ctx, runner, id, hash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateHash indicates an expected call of UpdateHash
+func (mr *MockStorageMockRecorder) UpdateHash(ctx, runner, id, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateHash", reflect.TypeOf((*MockStorage)(nil).UpdateHash), ctx, runner, id, hash)
+}
+
+// Update mocks base method
+func (m *MockStorage) Update(ctx context.Context, runner storage.Runner, shorten shorten.Entity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, runner, shorten)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update
+func (mr *MockStorageMockRecorder) Update(ctx, runner, shorten interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockStorage)(nil).Update), ctx, runner, shorten)
+}
+
+// Tags mocks base method
+func (m *MockStorage) Tags(ctx context.Context, runner storage.Runner, ids ...int64) (map[int64][]string, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, runner}
+	for _, a := range ids {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Tags", varargs...)
+	ret0, _ := ret[0].(map[int64][]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Tags indicates an expected call of Tags
+func (mr *MockStorageMockRecorder) Tags(ctx, runner interface{}, ids ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, runner}, ids...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Tags", reflect.TypeOf((*MockStorage)(nil).Tags), varargs...)
+}
+
+// SetTags mocks base method
+func (m *MockStorage) SetTags(ctx context.Context, runner storage.Runner, id int64, tags []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTags", ctx, runner, id, tags)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTags indicates an expected call of SetTags
+func (mr *MockStorageMockRecorder) SetTags(ctx, runner, id, tags interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTags", reflect.TypeOf((*MockStorage)(nil).SetTags), ctx, runner, id, tags)
+}
+
+// IncrementClicks mocks base method
+func (m *MockStorage) IncrementClicks(ctx context.Context, runner storage.Runner, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementClicks", ctx, runner, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrementClicks indicates an expected call of IncrementClicks
+func (mr *MockStorageMockRecorder) IncrementClicks(ctx, runner, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementClicks", reflect.TypeOf((*MockStorage)(nil).IncrementClicks), ctx, runner, id)
+}
+
+// DeleteExpired mocks base method
+func (m *MockStorage) DeleteExpired(ctx context.Context, runner storage.Runner, deadline time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpired", ctx, runner, deadline)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpired indicates an expected call of DeleteExpired
+func (mr *MockStorageMockRecorder) DeleteExpired(ctx, runner, deadline interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpired", reflect.TypeOf((*MockStorage)(nil).DeleteExpired), ctx, runner, deadline)
+}
+
+// ArchiveExpired mocks base method
+func (m *MockStorage) ArchiveExpired(ctx context.Context, runner storage.Runner, deadline time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArchiveExpired", ctx, runner, deadline)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ArchiveExpired indicates an expected call of ArchiveExpired
+func (mr *MockStorageMockRecorder) ArchiveExpired(ctx, runner, deadline interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArchiveExpired", reflect.TypeOf((*MockStorage)(nil).ArchiveExpired), ctx, runner, deadline)
+}
+
+// MockClickStorage is a mock of ClickStorage interface
+type MockClickStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockClickStorageMockRecorder
+}
+
+// MockClickStorageMockRecorder is the mock recorder for MockClickStorage
+type MockClickStorageMockRecorder struct {
+	mock *MockClickStorage
+}
+
+// NewMockClickStorage creates a new mock instance
+func NewMockClickStorage(ctrl *gomock.Controller) *MockClickStorage {
+	mock := &MockClickStorage{ctrl: ctrl}
+	mock.recorder = &MockClickStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockClickStorage) EXPECT() *MockClickStorageMockRecorder {
+	return m.recorder
+}
+
+// Persist mocks base method
+func (m *MockClickStorage) Persist(ctx context.Context, run storage.Runner, click click.Entity) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Persist", ctx, run, click)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Persist indicates an expected call of Persist
+func (mr *MockClickStorageMockRecorder) Persist(ctx, run, click interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Persist", reflect.TypeOf((*MockClickStorage)(nil).Persist), ctx, run, click)
+}
+
+// Count mocks base method
+func (m *MockClickStorage) Count(ctx context.Context, run storage.Runner, shortenID int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx, run, shortenID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count
+func (mr *MockClickStorageMockRecorder) Count(ctx, run, shortenID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockClickStorage)(nil).Count), ctx, run, shortenID)
+}
+
+// Daily mocks base method
+func (m *MockClickStorage) Daily(ctx context.Context, run storage.Runner, shortenID int64, since time.Time) ([]click.DailyCounter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Daily", ctx, run, shortenID, since)
+	ret0, _ := ret[0].([]click.DailyCounter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Daily indicates an expected call of Daily
+func (mr *MockClickStorageMockRecorder) Daily(ctx, run, shortenID, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Daily", reflect.TypeOf((*MockClickStorage)(nil).Daily), ctx, run, shortenID, since)
+}
+
+// TopReferers mocks base method
+func (m *MockClickStorage) TopReferers(ctx context.Context, run storage.Runner, shortenID, limit int64) ([]click.Counter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TopReferers", ctx, run, shortenID, limit)
+	ret0, _ := ret[0].([]click.Counter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TopReferers indicates an expected call of TopReferers
+func (mr *MockClickStorageMockRecorder) TopReferers(ctx, run, shortenID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TopReferers", reflect.TypeOf((*MockClickStorage)(nil).TopReferers), ctx, run, shortenID, limit)
+}
+
+// TopUserAgents mocks base method
+func (m *MockClickStorage) TopUserAgents(ctx context.Context, run storage.Runner, shortenID, limit int64) ([]click.Counter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TopUserAgents", ctx, run, shortenID, limit)
+	ret0, _ := ret[0].([]click.Counter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TopUserAgents indicates an expected call of TopUserAgents
+func (mr *MockClickStorageMockRecorder) TopUserAgents(ctx, run, shortenID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TopUserAgents", reflect.TypeOf((*MockClickStorage)(nil).TopUserAgents), ctx, run, shortenID, limit)
+}
+
+// MockDestinationPolicy is a mock of DestinationPolicy interface
+type MockDestinationPolicy struct {
+	ctrl     *gomock.Controller
+	recorder *MockDestinationPolicyMockRecorder
+}
+
+// MockDestinationPolicyMockRecorder is the mock recorder for MockDestinationPolicy
+type MockDestinationPolicyMockRecorder struct {
+	mock *MockDestinationPolicy
+}
+
+// NewMockDestinationPolicy creates a new mock instance
+func NewMockDestinationPolicy(ctrl *gomock.Controller) *MockDestinationPolicy {
+	mock := &MockDestinationPolicy{ctrl: ctrl}
+	mock.recorder = &MockDestinationPolicyMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockDestinationPolicy) EXPECT() *MockDestinationPolicyMockRecorder {
+	return m.recorder
+}
+
+// Check mocks base method
+func (m *MockDestinationPolicy) Check(url string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check", url)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Check indicates an expected call of Check
+func (mr *MockDestinationPolicyMockRecorder) Check(url interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockDestinationPolicy)(nil).Check), url)
+}
@@ -2,26 +2,50 @@ package shorten
 
 import (
 	"context"
-	"crypto/md5"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/pavelmemory/jobtome/internal"
 	"github.com/pavelmemory/jobtome/internal/storage"
+	"github.com/pavelmemory/jobtome/internal/storage/click"
 	"github.com/pavelmemory/jobtome/internal/storage/shorten"
+	"github.com/pavelmemory/jobtome/internal/tracing"
 )
 
 const (
-	hashLen = 7 // TODO: this should be configurable
+	// maxCodeAttempts is a number of attempts to find a unique code for the shorten.
+	maxCodeAttempts = 8
+	// MaxPageLimit is the largest number of shortens returned at once, larger limits are reduced to it,
+	// so larger sets must be walked page by page.
+	MaxPageLimit = 1000
 )
 
 type Entity struct {
 	ID   int64
 	URL  string
 	Hash string
+	// CreatedAt is a moment the shorten was created, it is ignored by `Create`.
+	CreatedAt time.Time
+	// ExpiresAt is a moment the shorten stops working, zero value means it never expires.
+	ExpiresAt time.Time
+	// MaxClicks is a number of redirects allowed for the shorten, 0 means it is unlimited.
+	MaxClicks int64
+	// Tags are labels assigned to the shorten, see `Update`.
+	Tags []string
+	// UpdatedAt is a moment of the last update, zero value means the shorten was never updated.
+	UpdatedAt time.Time
+	// Workspace is a tenant the shorten belongs to, see `WithWorkspace`.
+	Workspace string
+	// DeletedAt is a moment the shorten was moved to the trash, zero value means it is not deleted.
+	DeletedAt time.Time
+	// Clicks is a number of redirects already made with the shorten, it is ignored by `Create`.
+	Clicks int64
+	// Owner is a name of the API key the shorten was created with, see `WithOwner`.
+	Owner string
 }
 
 type Pager = shorten.Pager
@@ -44,150 +68,546 @@ type Storage interface {
 	// Retrieve returns shorten by supplied 'id'.
 	// If shorten doesn't exist it returns an error.
 	Retrieve(ctx context.Context, run storage.Runner, id int64) (shorten.Entity, error)
-	List(ctx context.Context, run storage.Runner, pager shorten.Pager) ([]shorten.Entity, error)
-	// Delete deletes shorten entity by its identifier.
+	// List returns a page of the shortens of the workspace matched by the filter, the deleted shortens are not included.
+	List(ctx context.Context, run storage.Runner, workspace string, filter shorten.Filter, pager shorten.Pager) ([]shorten.Entity, error)
+	// Trash returns a page of the deleted shortens of the workspace.
+	Trash(ctx context.Context, run storage.Runner, workspace string, pager shorten.Pager) ([]shorten.Entity, error)
+	// Delete moves the shorten to the trash.
 	Delete(ctx context.Context, runner storage.Runner, id int64) error
-	// ByHash returns shorten by supplied 'hash'.
+	// Restore returns the deleted shorten from the trash.
+	Restore(ctx context.Context, runner storage.Runner, id int64) error
+	// Purge permanently removes the shorten, deleted or not.
+	Purge(ctx context.Context, runner storage.Runner, id int64) error
+	// PurgeDeleted permanently removes shortens deleted before `deadline` and returns their amount.
+	PurgeDeleted(ctx context.Context, runner storage.Runner, deadline time.Time) (int64, error)
+	// ByHash returns not deleted shorten by supplied 'hash'.
 	ByHash(ctx context.Context, runner storage.Runner, hash string) (shorten.Entity, error)
+	// HashTaken reports if the 'hash' is used by any shorten including the deleted ones.
+	HashTaken(ctx context.Context, runner storage.Runner, hash string) (bool, error)
+	// UpdateHash replaces the hash of the shorten.
+	UpdateHash(ctx context.Context, runner storage.Runner, id int64, hash string) error
+	// Update replaces the URL, hash and limits of the shorten.
+	Update(ctx context.Context, runner storage.Runner, shorten shorten.Entity) error
+	// Tags returns tags of the shortens with the `ids` grouped by the shorten ID.
+	Tags(ctx context.Context, runner storage.Runner, ids ...int64) (map[int64][]string, error)
+	// SetTags replaces all tags of the shorten.
+	SetTags(ctx context.Context, runner storage.Runner, id int64, tags []string) error
+	// IncrementClicks increases a number of clicks made with the shorten if it has clicks left.
+	IncrementClicks(ctx context.Context, runner storage.Runner, id int64) error
+	// DeleteExpired removes shortens expired before `deadline` and returns their amount.
+	DeleteExpired(ctx context.Context, runner storage.Runner, deadline time.Time) (int64, error)
+	// ArchiveExpired moves shortens expired before `deadline` into the archive and returns their amount.
+	ArchiveExpired(ctx context.Context, runner storage.Runner, deadline time.Time) (int64, error)
+}
+
+// ClickStorage is a persistence storage for the clicks made with shortens.
+type ClickStorage interface {
+	// Persist saves the click and returns it's unique generated ID.
+	Persist(ctx context.Context, run storage.Runner, click click.Entity) (int64, error)
+	// Count returns total amount of clicks made for the shorten.
+	Count(ctx context.Context, run storage.Runner, shortenID int64) (int64, error)
+	// Daily returns amount of clicks made for the shorten grouped by days starting from `since`.
+	Daily(ctx context.Context, run storage.Runner, shortenID int64, since time.Time) ([]click.DailyCounter, error)
+	// TopReferers returns up to `limit` the most frequent referers of the shorten's clicks.
+	TopReferers(ctx context.Context, run storage.Runner, shortenID, limit int64) ([]click.Counter, error)
+	// TopUserAgents returns up to `limit` the most frequent user agents of the shorten's clicks.
+	TopUserAgents(ctx context.Context, run storage.Runner, shortenID, limit int64) ([]click.Counter, error)
+}
+
+// DestinationPolicy decides what URLs the shortens could redirect to.
+type DestinationPolicy interface {
+	// Check returns an error wrapping `internal.ErrBlocked` if the shortens must not redirect to the `url`.
+	Check(url string) error
+}
+
+// allowAll is a policy that allows all URLs.
+type allowAll struct{}
+
+func (allowAll) Check(string) error {
+	return nil
+}
+
+// Option allows to change the default behaviour of the Service.
+type Option func(*Service)
+
+// WithCodeGenerator sets the generator of codes for the new shortens.
+// By default codes are the first 7 characters of hex encoded MD5 digest of the URL.
+func WithCodeGenerator(generator CodeGenerator) Option {
+	return func(s *Service) {
+		s.generator = generator
+	}
+}
+
+// WithAliasPolicy sets the rules for the codes chosen by the callers. By default it is `DefaultAliasPolicy`.
+func WithAliasPolicy(policy AliasPolicy) Option {
+	return func(s *Service) {
+		s.aliasPolicy = policy
+	}
+}
+
+// WithURLPolicy sets the rules for the URLs of the shortens. By default it is `DefaultURLPolicy`.
+func WithURLPolicy(policy URLPolicy) Option {
+	return func(s *Service) {
+		s.urlPolicy = policy
+	}
+}
+
+// WithDestinationPolicy sets the policy the URLs of the created and updated shortens must satisfy.
+// If `onResolve` is set the policy is also checked on each redirect, so the shortens blocked after their creation
+// stop redirecting. By default all URLs are allowed.
+func WithDestinationPolicy(policy DestinationPolicy, onResolve bool) Option {
+	return func(s *Service) {
+		s.destinations = policy
+		s.checkOnResolve = onResolve
+	}
+}
+
+// WithResolveCache sets the cache of the shortens looked up by `Resolve`. By default nothing is cached.
+func WithResolveCache(cache *ResolveCache) Option {
+	return func(s *Service) {
+		s.cache = cache
+	}
 }
 
 // NewService returns initialized shorten service.
-func NewService(tr Transactioner, storage Storage) *Service {
-	return &Service{tr: tr, storage: storage}
+func NewService(tr Transactioner, storage Storage, clicks ClickStorage, opts ...Option) *Service {
+	s := &Service{
+		tr:           tr,
+		storage:      storage,
+		clicks:       clicks,
+		clickQueue:   make(chan Click, clickQueueSize),
+		generator:    defaultCodeGenerator,
+		aliasPolicy:  DefaultAliasPolicy,
+		urlPolicy:    DefaultURLPolicy,
+		destinations: allowAll{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
-// Service allows to CR_D shorten entity.
+// Service allows to CRUD shorten entity.
 type Service struct {
-	tr      Transactioner
-	storage Storage
+	tr         Transactioner
+	storage    Storage
+	clicks     ClickStorage
+	clickQueue chan Click
+	// droppedClicks is a number of clicks dropped since it was last reported by `RecordClicks`.
+	droppedClicks atomic.Int64
+	generator     CodeGenerator
+	aliasPolicy   AliasPolicy
+	urlPolicy     URLPolicy
+	cache         *ResolveCache
+	// destinations are checked on each redirect if `checkOnResolve` is set.
+	destinations   DestinationPolicy
+	checkOnResolve bool
 }
 
-// Create creates a new shorten entity and returns back its unique ID.
-func (s *Service) Create(ctx context.Context, short Entity) (int64, error) {
-	if err := isNotBlank(short.URL, "url"); err != nil {
+// Create creates a new shorten entity in the workspace of the `ctx` and returns back its unique ID.
+// The URL must satisfy the URL policy and it is stored normalized.
+// If the `Hash` is set it is used as a code of the shorten (alias), it must satisfy the alias policy.
+// Otherwise, if the code generator derives codes from the URLs and the shorten for the same normalized URL
+// already exists in the same workspace its ID is returned.
+func (s *Service) Create(ctx context.Context, short Entity) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "Create")
+	defer func() { tracing.End(span, err) }()
+
+	if short.URL, err = s.urlPolicy.Normalize(short.URL); err != nil {
 		return 0, err
 	}
 
-	if strings.TrimSpace(short.Hash) != "" {
-		return 0, ValidationError{
-			Cause:   internal.ErrBadInput,
-			Details: map[string]interface{}{"hash": "not empty"},
+	if err := s.checkDestination(short.URL); err != nil {
+		return 0, err
+	}
+
+	if err := validateLimits(short); err != nil {
+		return 0, err
+	}
+
+	template := shorten.Entity{
+		URL:       short.URL,
+		Hash:      short.Hash,
+		CreatedAt: time.Now(),
+		ExpiresAt: short.ExpiresAt,
+		MaxClicks: short.MaxClicks,
+		Workspace: WorkspaceFrom(ctx),
+		Owner:     OwnerFrom(ctx),
+	}
+
+	if template.Hash != "" {
+		return s.createAliased(ctx, template)
+	}
+
+	var id int64
+	var hash string
+	// created is false when the existing shorten is returned instead of a new one
+	created := true
+	persist := func(runner storage.Runner) (err error) {
+		switch s.generator.Kind() {
+		case ContentCode:
+			id, hash, created, err = s.persistContentCoded(ctx, runner, template)
+		case IDCode:
+			id, hash, err = s.persistIDCoded(ctx, runner, template)
+		default:
+			id, hash, err = s.persistRandomCoded(ctx, runner, template)
 		}
+		return err
+	}
+
+	if s.generator.Kind() == IDCode {
+		// the shorten is persisted with a temporary code and updated once the ID is known
+		err = s.tr.WithTx(ctx, persist)
+	} else {
+		err = s.tr.WithoutTx(ctx, persist)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("persist short: %w", err)
 	}
 
-	short.Hash = s.computeHash(short.URL)
+	s.invalidate(hash)
+	if created {
+		createdTotal.Inc()
+	}
+
+	return id, nil
+}
+
+// createAliased creates a new shorten with the code chosen by the caller.
+func (s *Service) createAliased(ctx context.Context, short shorten.Entity) (int64, error) {
+	if err := s.aliasPolicy.Validate(short.Hash); err != nil {
+		return 0, err
+	}
 
 	var id int64
-	if err := s.tr.WithoutTx(ctx, func(runner storage.Runner) error {
-		// check if such URL already exists
-		existing, err := s.storage.ByHash(ctx, runner, short.Hash)
+	if err := s.tr.WithoutTx(ctx, func(runner storage.Runner) (err error) {
+		id, err = s.storage.Persist(ctx, runner, short)
+		return err
+	}); err != nil {
+		return 0, fmt.Errorf("persist aliased short %q: %w", short.Hash, err)
+	}
+
+	s.invalidate(short.Hash)
+	createdTotal.Inc()
+
+	return id, nil
+}
+
+// persistContentCoded persists a new shorten for the URL or returns ID of the existing one with the same limits
+// in the same workspace.
+// Different URLs could have the same code, so on collision a code for the next attempt is used.
+// It returns the ID and the code of the shorten and whether it was persisted by the call.
+func (s *Service) persistContentCoded(ctx context.Context, runner storage.Runner, template shorten.Entity) (int64, string, bool, error) {
+	lookup := func(hash string) (shorten.Entity, bool, error) {
+		existing, err := s.storage.ByHash(ctx, runner, hash)
+		if err != nil {
+			if errors.Is(err, internal.ErrNotFound) {
+				return shorten.Entity{}, false, nil
+			}
+			return shorten.Entity{}, false, fmt.Errorf("lookup by hash %q: %w", hash, err)
+		}
+
+		return existing, true, nil
+	}
+
+	for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+		hash, err := s.generator.Generate(template.URL, 0, attempt)
+		if err != nil {
+			return 0, "", false, fmt.Errorf("generate code: %w", err)
+		}
+
+		existing, found, err := lookup(hash)
 		if err != nil {
-			if !errors.Is(err, internal.ErrNotFound) {
-				return fmt.Errorf("lookup by hash %q: %w", short.Hash, err)
+			return 0, "", false, err
+		}
+
+		if !found {
+			// the code of the deleted shorten stays taken until it is purged
+			taken, err := s.storage.HashTaken(ctx, runner, hash)
+			if err != nil {
+				return 0, "", false, fmt.Errorf("check hash %q: %w", hash, err)
+			}
+			if taken {
+				continue
 			}
 
-			newShort := shorten.Entity{
-				URL:       short.URL,
-				Hash:      short.Hash,
-				CreatedAt: time.Now(),
+			short := template
+			short.Hash = hash
+			id, err := s.storage.Persist(ctx, runner, short)
+			if !errors.Is(err, internal.ErrNotUnique) {
+				return id, hash, true, err
+			}
+
+			// the shorten with the same hash was created concurrently
+			if existing, _, err = lookup(hash); err != nil {
+				return 0, "", false, err
 			}
-			id, err = s.storage.Persist(ctx, runner, newShort)
-			return err
 		}
 
-		id = existing.ID
-		return nil
-	}); err != nil {
-		return 0, fmt.Errorf("persist short: %w", err)
+		if existing.URL == template.URL &&
+			existing.ExpiresAt.Equal(template.ExpiresAt) &&
+			existing.MaxClicks == template.MaxClicks &&
+			existing.Workspace == template.Workspace {
+			return existing.ID, existing.Hash, false, nil
+		}
+		// the hash is already taken by another URL, the same URL with different limits or in another workspace
 	}
 
-	return id, nil
+	return 0, "", false, fmt.Errorf("hash collision for %d attempts", maxCodeAttempts)
+}
+
+// persistIDCoded persists a new shorten with a temporary code and replaces it with the code derived from the ID.
+// It must be called inside of the transaction.
+// It returns the ID and the code of the shorten.
+func (s *Service) persistIDCoded(ctx context.Context, runner storage.Runner, template shorten.Entity) (int64, string, error) {
+	placeholder, err := randomString(HexAlphabet, 32)
+	if err != nil {
+		return 0, "", fmt.Errorf("generate temporary code: %w", err)
+	}
+
+	short := template
+	short.Hash = "~" + placeholder
+	id, err := s.storage.Persist(ctx, runner, short)
+	if err != nil {
+		return 0, "", err
+	}
+
+	for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+		hash, err := s.generator.Generate(template.URL, id, attempt)
+		if err != nil {
+			return 0, "", fmt.Errorf("generate code: %w", err)
+		}
+
+		// the code could be already taken by the shorten created with another strategy or by the deleted one
+		taken, err := s.storage.HashTaken(ctx, runner, hash)
+		if err != nil {
+			return 0, "", fmt.Errorf("check hash %q: %w", hash, err)
+		}
+		if taken {
+			continue
+		}
+
+		if err := s.storage.UpdateHash(ctx, runner, id, hash); err != nil {
+			return 0, "", fmt.Errorf("update hash: %w", err)
+		}
+
+		return id, hash, nil
+	}
+
+	return 0, "", fmt.Errorf("hash collision for %d attempts", maxCodeAttempts)
 }
 
-func (s *Service) computeHash(long string) string {
-	return fmt.Sprintf("%x", md5.Sum([]byte(long)))[:hashLen]
+// persistRandomCoded persists a new shorten with a random code.
+// It returns the ID and the code of the shorten.
+func (s *Service) persistRandomCoded(ctx context.Context, runner storage.Runner, template shorten.Entity) (int64, string, error) {
+	for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+		hash, err := s.generator.Generate(template.URL, 0, attempt)
+		if err != nil {
+			return 0, "", fmt.Errorf("generate code: %w", err)
+		}
+
+		short := template
+		short.Hash = hash
+		id, err := s.storage.Persist(ctx, runner, short)
+		if !errors.Is(err, internal.ErrNotUnique) {
+			return id, hash, err
+		}
+	}
+
+	return 0, "", fmt.Errorf("hash collision for %d attempts", maxCodeAttempts)
 }
 
-func (s *Service) Get(ctx context.Context, id int64) (Entity, error) {
-	var short shorten.Entity
+func (s *Service) Get(ctx context.Context, id int64) (_ Entity, err error) {
+	ctx, span := startSpan(ctx, "Get")
+	defer func() { tracing.End(span, err) }()
+
+	var entity Entity
 	if err := s.tr.WithoutTx(ctx, func(runner storage.Runner) (err error) {
-		short, err = s.storage.Retrieve(ctx, runner, id)
+		entity, err = s.retrieve(ctx, runner, id)
 		return err
 	}); err != nil {
 		return Entity{}, fmt.Errorf("retrieve shorten by id %q: %w", id, err)
 	}
 
-	return serviceEntity(short), nil
+	return entity, nil
 }
 
-func (s *Service) List(ctx context.Context, pager Pager) ([]Entity, error) {
-	if pager.Limit < 1 {
-		return nil, ValidationError{
-			Cause:   internal.ErrBadInput,
-			Details: map[string]interface{}{"limit": "is lesser then 1"},
-		}
+// retrieve returns the shorten of the workspace with its tags.
+func (s *Service) retrieve(ctx context.Context, runner storage.Runner, id int64) (Entity, error) {
+	short, err := s.retrieveOwned(ctx, runner, id)
+	if err != nil {
+		return Entity{}, err
 	}
 
-	if pager.Offset < 0 {
-		return nil, ValidationError{
-			Cause:   internal.ErrBadInput,
-			Details: map[string]interface{}{"offset": "is negative"},
-		}
+	tags, err := s.storage.Tags(ctx, runner, id)
+	if err != nil {
+		return Entity{}, fmt.Errorf("retrieve tags: %w", err)
 	}
 
+	entity := serviceEntity(short)
+	entity.Tags = tags[id]
+
+	return entity, nil
+}
+
+// List returns a page of the shortens of the workspace matched by the `filter`, see `Filter` and `Pager`.
+func (s *Service) List(ctx context.Context, filter Filter, pager Pager) (_ []Entity, err error) {
+	ctx, span := startSpan(ctx, "List")
+	defer func() { tracing.End(span, err) }()
+
+	if pager, err = validatePager(pager); err != nil {
+		return nil, err
+	}
+
+	if err := validateSort(pager.Sort); err != nil {
+		return nil, err
+	}
+
+	if filter, err = validateFilter(filter); err != nil {
+		return nil, err
+	}
+
+	entities, err := s.list(ctx, pager, func(ctx context.Context, run storage.Runner, workspace string, pager shorten.Pager) ([]shorten.Entity, error) {
+		return s.storage.List(ctx, run, workspace, filter, pager)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list shortens: %w", err)
+	}
+
+	return entities, nil
+}
+
+// list returns the shortens of the workspace fetched by the `fetch` with their tags.
+func (s *Service) list(
+	ctx context.Context,
+	pager Pager,
+	fetch func(ctx context.Context, run storage.Runner, workspace string, pager shorten.Pager) ([]shorten.Entity, error),
+) ([]Entity, error) {
 	var entities []Entity
 	err := s.tr.WithoutTx(ctx, func(runner storage.Runner) error {
-		shortens, err := s.storage.List(ctx, runner, pager)
-		if err != nil {
+		shortens, err := fetch(ctx, runner, WorkspaceFrom(ctx), pager)
+		if err != nil || len(shortens) == 0 {
 			return err
 		}
 
+		ids := make([]int64, len(shortens))
+		for i, short := range shortens {
+			ids[i] = short.ID
+		}
+
+		tags, err := s.storage.Tags(ctx, runner, ids...)
+		if err != nil {
+			return fmt.Errorf("retrieve tags: %w", err)
+		}
+
 		entities = make([]Entity, len(shortens))
 		for i, short := range shortens {
 			entities[i] = serviceEntity(short)
+			entities[i].Tags = tags[short.ID]
 		}
 
 		return nil
 	})
 
-	if err != nil {
-		return nil, fmt.Errorf("list shortens: %w", err)
-	}
-
-	return entities, nil
+	return entities, err
 }
 
-func (s *Service) Delete(ctx context.Context, id int64) error {
-	if err := s.tr.WithoutTx(ctx, func(runner storage.Runner) error {
+// Delete moves the shorten to the trash, so it stops redirecting but could be restored until it is purged.
+func (s *Service) Delete(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "Delete")
+	defer func() { tracing.End(span, err) }()
+
+	if err := s.tr.WithTx(ctx, func(runner storage.Runner) error {
+		if _, err := s.retrieveOwned(ctx, runner, id); err != nil {
+			return err
+		}
+
 		return s.storage.Delete(ctx, runner, id)
 	}); err != nil {
 		return fmt.Errorf("delete shorten %d: %w", id, err)
 	}
 
+	if s.cache != nil {
+		s.cache.invalidateID(id)
+	}
+
 	return nil
 }
 
-func (s *Service) Resolve(ctx context.Context, hash string) (string, error) {
+// Resolve returns a full URL of the shorten with the `hash`.
+// The click is registered asynchronously, see `RecordClicks`.
+func (s *Service) Resolve(ctx context.Context, hash string, click Click) (url string, err error) {
+	ctx, span := startSpan(ctx, "Resolve")
+	defer func() {
+		observeResolve(err)
+		tracing.End(span, err)
+	}()
+
 	if err := isNotBlank(hash, "hash"); err != nil {
 		return "", err
 	}
 
-	var url string
-	err := s.tr.WithoutTx(ctx, func(runner storage.Runner) error {
-		short, err := s.storage.ByHash(ctx, runner, hash)
-		if err != nil {
-			return err
+	short, err := s.byHash(ctx, hash)
+	if err != nil {
+		return "", fmt.Errorf("retrieve shorten by hash %q: %w", hash, err)
+	}
+
+	if !short.ExpiresAt.IsZero() && !time.Now().Before(short.ExpiresAt) {
+		return "", fmt.Errorf("retrieve shorten by hash %q: %w", hash, internal.ErrExpired)
+	}
+
+	if s.checkOnResolve {
+		if err := s.destinations.Check(short.URL); err != nil {
+			return "", fmt.Errorf("retrieve shorten by hash %q: %w", hash, err)
 		}
+	}
 
-		url = short.URL
+	if short.MaxClicks > 0 {
+		// limited shortens are counted synchronously, so the limit can't be exceeded
+		// even if the number of clicks of the cached shorten is outdated
+		if short.Clicks >= short.MaxClicks {
+			return "", fmt.Errorf("retrieve shorten by hash %q: %w", hash, internal.ErrExpired)
+		}
 
-		return nil
-	})
-	if err != nil {
-		return "", fmt.Errorf("retrieve shorten by hash %q: %w", hash, err)
+		if err := s.tr.WithoutTx(ctx, func(runner storage.Runner) error {
+			return s.storage.IncrementClicks(ctx, runner, short.ID)
+		}); err != nil {
+			return "", fmt.Errorf("retrieve shorten by hash %q: %w", hash, err)
+		}
+		click.counted = true
+	}
+
+	click.ShortenID = short.ID
+	click.ClickedAt = time.Now()
+	s.enqueueClick(click)
+
+	return short.URL, nil
+}
+
+// byHash returns the shorten with the `hash` from the cache if it is set or from the storage.
+func (s *Service) byHash(ctx context.Context, hash string) (shorten.Entity, error) {
+	load := func(ctx context.Context) (short shorten.Entity, err error) {
+		err = s.tr.WithoutTx(ctx, func(runner storage.Runner) error {
+			short, err = s.storage.ByHash(ctx, runner, hash)
+			return err
+		})
+		return short, err
+	}
+
+	if s.cache == nil {
+		return load(ctx)
 	}
 
-	return url, nil
+	return s.cache.get(ctx, hash, load)
+}
+
+// invalidate removes the shortens with the `hashes` from the cache if it is set.
+func (s *Service) invalidate(hashes ...string) {
+	if s.cache != nil {
+		s.cache.invalidate(hashes...)
+	}
 }
 
 // ValidationError encapsulates in it validation failure details.
@@ -213,6 +633,22 @@ func (ve ValidationError) Is(err error) bool {
 	return errors.Is(ve.Cause, err)
 }
 
+// checkDestination returns `ValidationError` if the shortens must not redirect to the `url`.
+func (s *Service) checkDestination(url string) error {
+	if err := s.destinations.Check(url); err != nil {
+		if !errors.Is(err, internal.ErrBlocked) {
+			return fmt.Errorf("check destination: %w", err)
+		}
+
+		return ValidationError{
+			Cause:   internal.ErrBadInput,
+			Details: map[string]interface{}{"url": err.Error()},
+		}
+	}
+
+	return nil
+}
+
 func isNotBlank(v, name string) error {
 	if strings.TrimSpace(v) == "" {
 		return ValidationError{
@@ -226,8 +662,65 @@ func isNotBlank(v, name string) error {
 
 func serviceEntity(u shorten.Entity) Entity {
 	return Entity{
-		ID:   u.ID,
-		URL:  u.URL,
-		Hash: u.Hash,
+		ID:        u.ID,
+		URL:       u.URL,
+		Hash:      u.Hash,
+		CreatedAt: u.CreatedAt,
+		ExpiresAt: u.ExpiresAt,
+		MaxClicks: u.MaxClicks,
+		UpdatedAt: u.UpdatedAt,
+		Workspace: u.Workspace,
+		DeletedAt: u.DeletedAt,
+		Clicks:    u.Clicks,
+		Owner:     u.Owner,
+	}
+}
+
+// validatePager verifies the page could be fetched and returns it with the `Limit` reduced to `MaxPageLimit`.
+func validatePager(pager Pager) (Pager, error) {
+	if pager.Limit < 1 {
+		return Pager{}, ValidationError{
+			Cause:   internal.ErrBadInput,
+			Details: map[string]interface{}{"limit": "is lesser then 1"},
+		}
+	}
+
+	if pager.Offset < 0 {
+		return Pager{}, ValidationError{
+			Cause:   internal.ErrBadInput,
+			Details: map[string]interface{}{"offset": "is negative"},
+		}
+	}
+
+	if pager.AfterID < 0 {
+		return Pager{}, ValidationError{
+			Cause:   internal.ErrBadInput,
+			Details: map[string]interface{}{"after_id": "is negative"},
+		}
+	}
+
+	if pager.Limit > MaxPageLimit {
+		pager.Limit = MaxPageLimit
 	}
+
+	return pager, nil
+}
+
+// validateLimits verifies the shorten could be used at least once.
+func validateLimits(short Entity) error {
+	if !short.ExpiresAt.IsZero() && !short.ExpiresAt.After(time.Now()) {
+		return ValidationError{
+			Cause:   internal.ErrBadInput,
+			Details: map[string]interface{}{"expires_at": "not in the future"},
+		}
+	}
+
+	if short.MaxClicks < 0 {
+		return ValidationError{
+			Cause:   internal.ErrBadInput,
+			Details: map[string]interface{}{"max_clicks": "is negative"},
+		}
+	}
+
+	return nil
 }
@@ -3,12 +3,19 @@ package shorten
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 
 	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/logging"
+	"github.com/pavelmemory/jobtome/internal/storage/click"
 	"github.com/pavelmemory/jobtome/internal/storage/shorten"
 
 	"github.com/pavelmemory/jobtome/internal/storage"
@@ -17,26 +24,90 @@ import (
 func TestService_Create(t *testing.T) {
 	t.Run("validation", func(t *testing.T) {
 		t.Run("no url", func(t *testing.T) {
-			srv := NewService(nil, nil)
+			srv := NewService(nil, nil, nil)
 			_, err := srv.Create(Context(), Entity{URL: ""})
 			exp := ValidationError{Cause: internal.ErrBadInput, Details: map[string]interface{}{"url": "blank or empty"}}
 			require.Equal(t, exp, err)
 		})
 
-		t.Run("hash is set", func(t *testing.T) {
-			srv := NewService(nil, nil)
-			_, err := srv.Create(Context(), Entity{URL: "http://example.com", Hash: "stub"})
-			exp := ValidationError{Cause: internal.ErrBadInput, Details: map[string]interface{}{"hash": "not empty"}}
+		t.Run("unsafe url", func(t *testing.T) {
+			srv := NewService(nil, nil, nil)
+			_, err := srv.Create(Context(), Entity{URL: "javascript:alert(1)"})
+			exp := ValidationError{
+				Cause:   internal.ErrBadInput,
+				Details: map[string]interface{}{"url": `scheme "javascript" is not allowed, one of http, https is expected`},
+			}
+			require.Equal(t, exp, err)
+		})
+
+		t.Run("blocked url", func(t *testing.T) {
+			srv := NewService(nil, nil, nil, WithDestinationPolicy(testDestinationPolicy{"evil.com"}, false))
+			_, err := srv.Create(Context(), Entity{URL: "https://EVIL.com/login"})
+			exp := ValidationError{Cause: internal.ErrBadInput, Details: map[string]interface{}{"url": "blocked: denied by test"}}
+			require.Equal(t, exp, err)
+		})
+
+		t.Run("bad alias", func(t *testing.T) {
+			srv := NewService(nil, nil, nil)
+			_, err := srv.Create(Context(), Entity{URL: "http://example.com", Hash: "api"})
+			exp := ValidationError{Cause: internal.ErrBadInput, Details: map[string]interface{}{"alias": "reserved"}}
+			require.Equal(t, exp, err)
+		})
+
+		t.Run("expired", func(t *testing.T) {
+			srv := NewService(nil, nil, nil)
+			_, err := srv.Create(Context(), Entity{URL: "http://example.com", ExpiresAt: time.Now().Add(-time.Minute)})
+			exp := ValidationError{Cause: internal.ErrBadInput, Details: map[string]interface{}{"expires_at": "not in the future"}}
+			require.Equal(t, exp, err)
+		})
+
+		t.Run("negative max clicks", func(t *testing.T) {
+			srv := NewService(nil, nil, nil)
+			_, err := srv.Create(Context(), Entity{URL: "http://example.com", MaxClicks: -1})
+			exp := ValidationError{Cause: internal.ErrBadInput, Details: map[string]interface{}{"max_clicks": "is negative"}}
 			require.Equal(t, exp, err)
 		})
 	})
 
+	t.Run("alias", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().
+			Persist(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, run storage.Runner, short shorten.Entity) (int64, error) {
+				require.Equal(t, "https://example.com", short.URL)
+				require.Equal(t, "summer-sale", short.Hash)
+				require.Equal(t, "ci", short.Owner)
+				return 1, nil
+			})
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		id, err := srv.Create(WithOwner(Context(), "ci"), Entity{URL: "https://example.com", Hash: "summer-sale"})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), id)
+	})
+
+	t.Run("alias is taken", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().Persist(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(0), internal.ErrNotUnique)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		_, err := srv.Create(Context(), Entity{URL: "https://example.com", Hash: "summer-sale"})
+		require.True(t, errors.Is(err, internal.ErrNotUnique))
+	})
+
 	t.Run("new", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		mockStorage := NewMockStorage(ctrl)
 		mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), gomock.Any()).Return(shorten.Entity{}, internal.ErrNotFound)
+		mockStorage.EXPECT().HashTaken(gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil)
 		mockStorage.EXPECT().
 			Persist(gomock.Any(), gomock.Any(), gomock.Any()).
 			DoAndReturn(func(ctx context.Context, run storage.Runner, short shorten.Entity) (int64, error) {
@@ -45,22 +116,44 @@ func TestService_Create(t *testing.T) {
 				return 1, nil
 			})
 
-		srv := NewService(testTransactioner{}, mockStorage)
+		srv := NewService(testTransactioner{}, mockStorage, nil)
 		id, err := srv.Create(Context(), Entity{URL: "https://example.com"})
 
 		require.NoError(t, err)
 		require.Equal(t, int64(1), id)
 	})
 
+	t.Run("normalized url", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		hash, err := defaultCodeGenerator.Generate("https://example.com", 0, 0)
+		require.NoError(t, err)
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), hash).Return(shorten.Entity{}, internal.ErrNotFound)
+		mockStorage.EXPECT().HashTaken(gomock.Any(), gomock.Any(), hash).Return(false, nil)
+		mockStorage.EXPECT().
+			Persist(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, run storage.Runner, short shorten.Entity) (int64, error) {
+				require.Equal(t, "https://example.com", short.URL)
+				return 1, nil
+			})
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		_, err = srv.Create(Context(), Entity{URL: "HTTPS://Example.COM:443/"})
+		require.NoError(t, err)
+	})
+
 	t.Run("reuse existing", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567"}
+		existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567", Workspace: DefaultWorkspace}
 		mockStorage := NewMockStorage(ctrl)
 		mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), gomock.Any()).Return(existing, nil)
 
-		srv := NewService(testTransactioner{}, mockStorage)
+		srv := NewService(testTransactioner{}, mockStorage, nil)
 		id, err := srv.Create(Context(), Entity{URL: "https://example.com"})
 
 		require.NoError(t, err)
@@ -68,6 +161,259 @@ func TestService_Create(t *testing.T) {
 	})
 }
 
+func TestService_Create_collisions(t *testing.T) {
+	// generator produces the same code for all URLs on the first attempt
+	generator := testCodeGenerator{kind: ContentCode, generate: func(url string, id int64, attempt int) (string, error) {
+		return "hash-" + strconv.Itoa(attempt), nil
+	}}
+
+	t.Run("taken by another url", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		gomock.InOrder(
+			mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), "hash-0").Return(shorten.Entity{ID: 1, URL: "https://stub.com", Hash: "hash-0", Workspace: DefaultWorkspace}, nil),
+			mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), "hash-1").Return(shorten.Entity{}, internal.ErrNotFound),
+			mockStorage.EXPECT().HashTaken(gomock.Any(), gomock.Any(), "hash-1").Return(false, nil),
+			mockStorage.EXPECT().
+				Persist(gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, run storage.Runner, short shorten.Entity) (int64, error) {
+					require.Equal(t, "https://example.com", short.URL)
+					require.Equal(t, "hash-1", short.Hash)
+					return 2, nil
+				}),
+		)
+
+		created := testutil.ToFloat64(createdTotal)
+		srv := NewService(testTransactioner{}, mockStorage, nil, WithCodeGenerator(generator))
+		id, err := srv.Create(Context(), Entity{URL: "https://example.com"})
+		require.NoError(t, err)
+		require.Equal(t, int64(2), id)
+		require.Equal(t, created+1, testutil.ToFloat64(createdTotal))
+	})
+
+	t.Run("reuse existing after collision", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		gomock.InOrder(
+			mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), "hash-0").Return(shorten.Entity{ID: 1, URL: "https://stub.com", Hash: "hash-0", Workspace: DefaultWorkspace}, nil),
+			mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), "hash-1").Return(shorten.Entity{ID: 2, URL: "https://example.com", Hash: "hash-1", Workspace: DefaultWorkspace}, nil),
+		)
+
+		created := testutil.ToFloat64(createdTotal)
+		srv := NewService(testTransactioner{}, mockStorage, nil, WithCodeGenerator(generator))
+		id, err := srv.Create(Context(), Entity{URL: "https://example.com"})
+		require.NoError(t, err)
+		require.Equal(t, int64(2), id)
+		// the existing shorten is returned, nothing is created
+		require.Equal(t, created, testutil.ToFloat64(createdTotal))
+	})
+
+	t.Run("same url in another workspace", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		gomock.InOrder(
+			mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), "hash-0").Return(shorten.Entity{ID: 1, URL: "https://example.com", Hash: "hash-0", Workspace: "marketing"}, nil),
+			mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), "hash-1").Return(shorten.Entity{}, internal.ErrNotFound),
+			mockStorage.EXPECT().HashTaken(gomock.Any(), gomock.Any(), "hash-1").Return(false, nil),
+			mockStorage.EXPECT().
+				Persist(gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, run storage.Runner, short shorten.Entity) (int64, error) {
+					require.Equal(t, "hash-1", short.Hash)
+					require.Equal(t, "sales", short.Workspace)
+					return 2, nil
+				}),
+		)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil, WithCodeGenerator(generator))
+		id, err := srv.Create(WithWorkspace(Context(), "sales"), Entity{URL: "https://example.com"})
+		require.NoError(t, err)
+		require.Equal(t, int64(2), id)
+	})
+
+	t.Run("concurrently created same url", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		gomock.InOrder(
+			mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), "hash-0").Return(shorten.Entity{}, internal.ErrNotFound),
+			mockStorage.EXPECT().HashTaken(gomock.Any(), gomock.Any(), "hash-0").Return(false, nil),
+			mockStorage.EXPECT().Persist(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(0), internal.ErrNotUnique),
+			mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), "hash-0").Return(shorten.Entity{ID: 1, URL: "https://example.com", Hash: "hash-0", Workspace: DefaultWorkspace}, nil),
+		)
+
+		created := testutil.ToFloat64(createdTotal)
+		srv := NewService(testTransactioner{}, mockStorage, nil, WithCodeGenerator(generator))
+		id, err := srv.Create(Context(), Entity{URL: "https://example.com"})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), id)
+		require.Equal(t, created, testutil.ToFloat64(createdTotal))
+	})
+
+	t.Run("concurrently created another url", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		gomock.InOrder(
+			mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), "hash-0").Return(shorten.Entity{}, internal.ErrNotFound),
+			mockStorage.EXPECT().HashTaken(gomock.Any(), gomock.Any(), "hash-0").Return(false, nil),
+			mockStorage.EXPECT().Persist(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(0), internal.ErrNotUnique),
+			mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), "hash-0").Return(shorten.Entity{ID: 1, URL: "https://stub.com", Hash: "hash-0", Workspace: DefaultWorkspace}, nil),
+			mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), "hash-1").Return(shorten.Entity{}, internal.ErrNotFound),
+			mockStorage.EXPECT().HashTaken(gomock.Any(), gomock.Any(), "hash-1").Return(false, nil),
+			mockStorage.EXPECT().Persist(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(2), nil),
+		)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil, WithCodeGenerator(generator))
+		id, err := srv.Create(Context(), Entity{URL: "https://example.com"})
+		require.NoError(t, err)
+		require.Equal(t, int64(2), id)
+	})
+
+	t.Run("taken by deleted shorten", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		gomock.InOrder(
+			mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), "hash-0").Return(shorten.Entity{}, internal.ErrNotFound),
+			mockStorage.EXPECT().HashTaken(gomock.Any(), gomock.Any(), "hash-0").Return(true, nil),
+			mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), "hash-1").Return(shorten.Entity{}, internal.ErrNotFound),
+			mockStorage.EXPECT().HashTaken(gomock.Any(), gomock.Any(), "hash-1").Return(false, nil),
+			mockStorage.EXPECT().
+				Persist(gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, run storage.Runner, short shorten.Entity) (int64, error) {
+					require.Equal(t, "hash-1", short.Hash)
+					return 2, nil
+				}),
+		)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil, WithCodeGenerator(generator))
+		id, err := srv.Create(Context(), Entity{URL: "https://example.com"})
+		require.NoError(t, err)
+		require.Equal(t, int64(2), id)
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().
+			ByHash(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(shorten.Entity{ID: 1, URL: "https://stub.com", Workspace: DefaultWorkspace}, nil).
+			Times(maxCodeAttempts)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil, WithCodeGenerator(generator))
+		_, err := srv.Create(Context(), Entity{URL: "https://example.com"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "hash collision")
+	})
+
+	t.Run("storage failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), "hash-0").Return(shorten.Entity{}, errors.New("stub"))
+
+		srv := NewService(testTransactioner{}, mockStorage, nil, WithCodeGenerator(generator))
+		_, err := srv.Create(Context(), Entity{URL: "https://example.com"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "stub")
+	})
+}
+
+func TestService_Create_idCode(t *testing.T) {
+	generator := testCodeGenerator{kind: IDCode, generate: func(url string, id int64, attempt int) (string, error) {
+		return strconv.FormatInt(id, 10) + "-" + strconv.Itoa(attempt), nil
+	}}
+
+	t.Run("ok", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		gomock.InOrder(
+			mockStorage.EXPECT().
+				Persist(gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, run storage.Runner, short shorten.Entity) (int64, error) {
+					require.Equal(t, "https://example.com", short.URL)
+					require.True(t, strings.HasPrefix(short.Hash, "~"), short.Hash)
+					return 5, nil
+				}),
+			mockStorage.EXPECT().HashTaken(gomock.Any(), gomock.Any(), "5-0").Return(true, nil),
+			mockStorage.EXPECT().HashTaken(gomock.Any(), gomock.Any(), "5-1").Return(false, nil),
+			mockStorage.EXPECT().UpdateHash(gomock.Any(), gomock.Any(), int64(5), "5-1").Return(nil),
+		)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil, WithCodeGenerator(generator))
+		id, err := srv.Create(Context(), Entity{URL: "https://example.com"})
+		require.NoError(t, err)
+		require.Equal(t, int64(5), id)
+	})
+
+	t.Run("persist failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().Persist(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(0), internal.ErrBadInput)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil, WithCodeGenerator(generator))
+		_, err := srv.Create(Context(), Entity{URL: "https://example.com"})
+		require.True(t, errors.Is(err, internal.ErrBadInput))
+	})
+}
+
+func TestService_Create_randomCode(t *testing.T) {
+	t.Run("retry on collision", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		generator := testCodeGenerator{kind: RandomCode, generate: func(url string, id int64, attempt int) (string, error) {
+			return "random-" + strconv.Itoa(attempt), nil
+		}}
+
+		var hashes []string
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().
+			Persist(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, run storage.Runner, short shorten.Entity) (int64, error) {
+				hashes = append(hashes, short.Hash)
+				if len(hashes) == 1 {
+					return 0, internal.ErrNotUnique
+				}
+				return 3, nil
+			}).
+			Times(2)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil, WithCodeGenerator(generator))
+		id, err := srv.Create(Context(), Entity{URL: "https://example.com"})
+		require.NoError(t, err)
+		require.Equal(t, int64(3), id)
+		require.Equal(t, []string{"random-0", "random-1"}, hashes)
+	})
+
+	t.Run("generation failure", func(t *testing.T) {
+		generator := testCodeGenerator{kind: RandomCode, generate: func(url string, id int64, attempt int) (string, error) {
+			return "", errors.New("stub")
+		}}
+
+		srv := NewService(testTransactioner{}, nil, nil, WithCodeGenerator(generator))
+		_, err := srv.Create(Context(), Entity{URL: "https://example.com"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "stub")
+	})
+}
+
 func TestService_Get(t *testing.T) {
 	t.Run("not existing", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -78,7 +424,7 @@ func TestService_Get(t *testing.T) {
 		mockStorage := NewMockStorage(ctrl)
 		mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), id).Return(shorten.Entity{}, internal.ErrNotFound)
 
-		srv := NewService(testTransactioner{}, mockStorage)
+		srv := NewService(testTransactioner{}, mockStorage, nil)
 		_, err := srv.Get(Context(), id)
 		require.True(t, errors.Is(err, internal.ErrNotFound))
 	})
@@ -87,32 +433,95 @@ func TestService_Get(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567"}
+		createdAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+		existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567", CreatedAt: createdAt, Workspace: DefaultWorkspace}
 		mockStorage := NewMockStorage(ctrl)
 		mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), existing.ID).Return(existing, nil)
+		mockStorage.EXPECT().Tags(gomock.Any(), gomock.Any(), existing.ID).Return(map[int64][]string{existing.ID: {"promo"}}, nil)
 
-		srv := NewService(testTransactioner{}, mockStorage)
+		srv := NewService(testTransactioner{}, mockStorage, nil)
 		actual, err := srv.Get(Context(), existing.ID)
 		require.NoError(t, err)
-		require.Equal(t, Entity{ID: existing.ID, URL: existing.URL, Hash: existing.Hash}, actual)
+		require.Equal(t, Entity{ID: existing.ID, URL: existing.URL, Hash: existing.Hash, CreatedAt: createdAt, Tags: []string{"promo"}, Workspace: DefaultWorkspace}, actual)
+	})
+
+	t.Run("another workspace", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567", Workspace: "marketing"}
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), existing.ID).Return(existing, nil)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		_, err := srv.Get(WithWorkspace(Context(), "sales"), existing.ID)
+		require.True(t, errors.Is(err, internal.ErrNotFound), err)
+
+		mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), existing.ID).Return(existing, nil)
+		mockStorage.EXPECT().Tags(gomock.Any(), gomock.Any(), existing.ID).Return(nil, nil)
+
+		actual, err := srv.Get(WithWorkspace(Context(), "marketing"), existing.ID)
+		require.NoError(t, err)
+		require.Equal(t, "marketing", actual.Workspace)
 	})
 }
 
 func TestService_List(t *testing.T) {
 	t.Run("validation", func(t *testing.T) {
 		t.Run("bad limit", func(t *testing.T) {
-			srv := NewService(nil, nil)
-			_, err := srv.List(Context(), Pager{Limit: -50, Offset: 10})
+			srv := NewService(nil, nil, nil)
+			_, err := srv.List(Context(), Filter{}, Pager{Limit: -50, Offset: 10})
 			exp := ValidationError{Cause: internal.ErrBadInput, Details: map[string]interface{}{"limit": "is lesser then 1"}}
 			require.Equal(t, exp, err)
 		})
 
 		t.Run("bad offset", func(t *testing.T) {
-			srv := NewService(nil, nil)
-			_, err := srv.List(Context(), Pager{Limit: 50, Offset: -10})
+			srv := NewService(nil, nil, nil)
+			_, err := srv.List(Context(), Filter{}, Pager{Limit: 50, Offset: -10})
 			exp := ValidationError{Cause: internal.ErrBadInput, Details: map[string]interface{}{"offset": "is negative"}}
 			require.Equal(t, exp, err)
 		})
+
+		t.Run("bad after id", func(t *testing.T) {
+			srv := NewService(nil, nil, nil)
+			_, err := srv.List(Context(), Filter{}, Pager{Limit: 50, AfterID: -1})
+			exp := ValidationError{Cause: internal.ErrBadInput, Details: map[string]interface{}{"after_id": "is negative"}}
+			require.Equal(t, exp, err)
+		})
+
+		now := time.Now()
+		for name, tc := range map[string]struct {
+			filter Filter
+			sort   string
+			exp    map[string]interface{}
+		}{
+			"unknown sort":    {sort: "hash", exp: map[string]interface{}{"sort": "is unknown"}},
+			"unknown status":  {filter: Filter{Status: "paused"}, exp: map[string]interface{}{"status": "is unknown"}},
+			"short url":       {filter: Filter{URL: " ab "}, exp: map[string]interface{}{"url": "is shorter then 3 characters"}},
+			"bad domain":      {filter: Filter{Domain: "exa mple.com"}, exp: map[string]interface{}{"domain": "not a valid domain"}},
+			"reversed period": {filter: Filter{CreatedFrom: now, CreatedTo: now}, exp: map[string]interface{}{"created_to": "not after created_from"}},
+		} {
+			tc := tc
+			t.Run(name, func(t *testing.T) {
+				srv := NewService(nil, nil, nil)
+				_, err := srv.List(Context(), tc.filter, Pager{Limit: 50, Sort: tc.sort})
+				require.Equal(t, ValidationError{Cause: internal.ErrBadInput, Details: tc.exp}, err)
+			})
+		}
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		filter := shorten.Filter{Domain: "xn--bcher-kva.de", URL: "summer", Tag: "promo", Owner: "ci", Status: shorten.StatusActive}
+		pager := shorten.Pager{Limit: 10, Sort: shorten.SortClicksDesc}
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().List(gomock.Any(), gomock.Any(), DefaultWorkspace, filter, pager).Return(nil, nil)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		_, err := srv.List(Context(), Filter{Domain: "Bücher.de.", URL: " summer ", Tag: " promo", Owner: "ci", Status: "active"}, pager)
+		require.NoError(t, err)
 	})
 
 	t.Run("nothing", func(t *testing.T) {
@@ -120,14 +529,26 @@ func TestService_List(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockStorage := NewMockStorage(ctrl)
-		mockStorage.EXPECT().List(gomock.Any(), gomock.Any(), shorten.Pager{Limit: 50, Offset: 10}).Return(nil, nil)
+		mockStorage.EXPECT().List(gomock.Any(), gomock.Any(), DefaultWorkspace, shorten.Filter{}, shorten.Pager{Limit: 50, Offset: 10}).Return(nil, nil)
 
-		srv := NewService(testTransactioner{}, mockStorage)
-		actual, err := srv.List(Context(), Pager{Limit: 50, Offset: 10})
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		actual, err := srv.List(Context(), Filter{}, Pager{Limit: 50, Offset: 10})
 		require.NoError(t, err)
 		require.Empty(t, actual)
 	})
 
+	t.Run("limit above max", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().List(gomock.Any(), gomock.Any(), DefaultWorkspace, shorten.Filter{}, shorten.Pager{Limit: MaxPageLimit}).Return(nil, nil)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		_, err := srv.List(Context(), Filter{}, Pager{Limit: 100000})
+		require.NoError(t, err)
+	})
+
 	t.Run("ok", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
@@ -138,18 +559,32 @@ func TestService_List(t *testing.T) {
 		}
 		exp := []Entity{
 			{ID: existing[0].ID, URL: existing[0].URL, Hash: existing[0].Hash},
-			{ID: existing[1].ID, URL: existing[1].URL, Hash: existing[1].Hash},
+			{ID: existing[1].ID, URL: existing[1].URL, Hash: existing[1].Hash, Tags: []string{"promo"}},
 		}
 		mockStorage := NewMockStorage(ctrl)
-		mockStorage.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return(existing, nil)
+		mockStorage.EXPECT().List(gomock.Any(), gomock.Any(), "marketing", shorten.Filter{}, gomock.Any()).Return(existing, nil)
+		mockStorage.EXPECT().Tags(gomock.Any(), gomock.Any(), int64(1), int64(2)).Return(map[int64][]string{2: {"promo"}}, nil)
 
-		srv := NewService(testTransactioner{}, mockStorage)
-		actual, err := srv.List(Context(), Pager{Limit: 10})
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		actual, err := srv.List(WithWorkspace(Context(), "marketing"), Filter{}, Pager{Limit: 10})
 		require.NoError(t, err)
 		require.Equal(t, exp, actual)
 	})
 }
 
+func TestNextPage(t *testing.T) {
+	last := Entity{ID: 7, CreatedAt: time.Unix(1700000000, 0), Clicks: 3}
+
+	for sort, exp := range map[string]Pager{
+		"":                        {Limit: 10, AfterID: 7},
+		shorten.SortCreatedAt:     {Limit: 10, Sort: shorten.SortCreatedAt, AfterID: 7, AfterKey: 1700000000},
+		shorten.SortCreatedAtDesc: {Limit: 10, Sort: shorten.SortCreatedAtDesc, AfterID: 7, AfterKey: 1700000000},
+		shorten.SortClicksDesc:    {Limit: 10, Sort: shorten.SortClicksDesc, AfterID: 7, AfterKey: 3},
+	} {
+		require.Equal(t, exp, NextPage(Pager{Limit: 10, Offset: 5, Sort: sort}, last), sort)
+	}
+}
+
 func TestService_Delete(t *testing.T) {
 	t.Run("not existing", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -158,9 +593,9 @@ func TestService_Delete(t *testing.T) {
 		const id = int64(1)
 
 		mockStorage := NewMockStorage(ctrl)
-		mockStorage.EXPECT().Delete(gomock.Any(), gomock.Any(), id).Return(internal.ErrNotFound)
+		mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), id).Return(shorten.Entity{}, internal.ErrNotFound)
 
-		srv := NewService(testTransactioner{}, mockStorage)
+		srv := NewService(testTransactioner{}, mockStorage, nil)
 		err := srv.Delete(Context(), id)
 		require.True(t, errors.Is(err, internal.ErrNotFound))
 	})
@@ -170,19 +605,43 @@ func TestService_Delete(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockStorage := NewMockStorage(ctrl)
-		mockStorage.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+		mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), int64(1)).Return(shorten.Entity{ID: 1, Workspace: DefaultWorkspace}, nil)
+		mockStorage.EXPECT().Delete(gomock.Any(), gomock.Any(), int64(1)).Return(nil)
 
-		srv := NewService(testTransactioner{}, mockStorage)
+		srv := NewService(testTransactioner{}, mockStorage, nil)
 		err := srv.Delete(Context(), 1)
 		require.NoError(t, err)
 	})
+
+	t.Run("another workspace", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), int64(1)).Return(shorten.Entity{ID: 1, Workspace: "marketing"}, nil)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		err := srv.Delete(WithWorkspace(Context(), "sales"), 1)
+		require.True(t, errors.Is(err, internal.ErrNotFound), err)
+	})
+	t.Run("already deleted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), int64(1)).Return(shorten.Entity{ID: 1, Workspace: DefaultWorkspace, DeletedAt: time.Now()}, nil)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		err := srv.Delete(Context(), 1)
+		require.True(t, errors.Is(err, internal.ErrNotFound), err)
+	})
 }
 
 func TestService_Resolve(t *testing.T) {
 	t.Run("validation", func(t *testing.T) {
 		t.Run("empty hash", func(t *testing.T) {
-			srv := NewService(nil, nil)
-			_, err := srv.Resolve(Context(), "   ")
+			srv := NewService(nil, nil, nil)
+			_, err := srv.Resolve(Context(), "   ", Click{})
 			exp := ValidationError{Cause: internal.ErrBadInput, Details: map[string]interface{}{"hash": "blank or empty"}}
 			require.Equal(t, exp, err)
 		})
@@ -197,8 +656,8 @@ func TestService_Resolve(t *testing.T) {
 		mockStorage := NewMockStorage(ctrl)
 		mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), hash).Return(shorten.Entity{}, internal.ErrNotFound)
 
-		srv := NewService(testTransactioner{}, mockStorage)
-		_, err := srv.Resolve(Context(), hash)
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		_, err := srv.Resolve(Context(), hash, Click{})
 		require.True(t, errors.Is(err, internal.ErrNotFound))
 	})
 
@@ -206,23 +665,283 @@ func TestService_Resolve(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567"}
+		existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567", Workspace: DefaultWorkspace}
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), existing.Hash).Return(existing, nil)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		actual, err := srv.Resolve(Context(), existing.Hash, Click{Referer: "https://referer.com", UserAgent: "curl", IP: "127.0.0.1"})
+		require.NoError(t, err)
+		require.Equal(t, existing.URL, actual)
+
+		select {
+		case click := <-srv.clickQueue:
+			require.Equal(t, existing.ID, click.ShortenID)
+			require.Equal(t, "https://referer.com", click.Referer)
+			require.Equal(t, "curl", click.UserAgent)
+			require.Equal(t, "127.0.0.1", click.IP)
+			require.False(t, click.ClickedAt.IsZero())
+		default:
+			require.Fail(t, "click was not registered")
+		}
+	})
+
+	t.Run("full click queue", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567", Workspace: DefaultWorkspace}
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), existing.Hash).Return(existing, nil).Times(clickQueueSize + 1)
+
+		dropped := testutil.ToFloat64(clicksDroppedTotal)
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		for i := 0; i <= clickQueueSize; i++ {
+			_, err := srv.Resolve(Context(), existing.Hash, Click{})
+			require.NoError(t, err)
+		}
+		require.Len(t, srv.clickQueue, clickQueueSize)
+		require.Equal(t, dropped+1, testutil.ToFloat64(clicksDroppedTotal))
+		require.Equal(t, int64(1), srv.droppedClicks.Load())
+
+		logger := logging.NewTestLogger()
+		srv.reportDroppedClicks(logger)
+		require.Equal(t, int64(0), srv.droppedClicks.Load())
+		require.Equal(t, "clicks are dropped", logger.Entries()[0]["msg"])
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567", ExpiresAt: time.Now().Add(-time.Second), Workspace: DefaultWorkspace}
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), existing.Hash).Return(existing, nil)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		_, err := srv.Resolve(Context(), existing.Hash, Click{})
+		require.True(t, errors.Is(err, internal.ErrExpired), err)
+		require.Len(t, srv.clickQueue, 0)
+	})
+
+	t.Run("limited", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567", MaxClicks: 2, Clicks: 1, Workspace: DefaultWorkspace}
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), existing.Hash).Return(existing, nil)
+		mockStorage.EXPECT().IncrementClicks(gomock.Any(), gomock.Any(), existing.ID).Return(nil)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		actual, err := srv.Resolve(Context(), existing.Hash, Click{})
+		require.NoError(t, err)
+		require.Equal(t, existing.URL, actual)
+		require.True(t, (<-srv.clickQueue).counted)
+	})
+
+	t.Run("no clicks left", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567", MaxClicks: 2, Clicks: 2, Workspace: DefaultWorkspace}
 		mockStorage := NewMockStorage(ctrl)
 		mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), existing.Hash).Return(existing, nil)
 
-		srv := NewService(testTransactioner{}, mockStorage)
-		actual, err := srv.Resolve(Context(), existing.Hash)
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		_, err := srv.Resolve(Context(), existing.Hash, Click{})
+		require.True(t, errors.Is(err, internal.ErrExpired), err)
+	})
+
+	t.Run("blocked", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		existing := shorten.Entity{ID: 1, URL: "https://evil.com/login", Hash: "1234567", Workspace: DefaultWorkspace}
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().ByHash(gomock.Any(), gomock.Any(), existing.Hash).Return(existing, nil).Times(2)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil, WithDestinationPolicy(testDestinationPolicy{"evil.com"}, true))
+		_, err := srv.Resolve(Context(), existing.Hash, Click{})
+		require.True(t, errors.Is(err, internal.ErrBlocked), err)
+		require.Len(t, srv.clickQueue, 0)
+
+		srv = NewService(testTransactioner{}, mockStorage, nil, WithDestinationPolicy(testDestinationPolicy{"evil.com"}, false))
+		actual, err := srv.Resolve(Context(), existing.Hash, Click{})
 		require.NoError(t, err)
 		require.Equal(t, existing.URL, actual)
 	})
 }
 
+func TestService_RecordClicks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	clickedAt := time.Now()
+	persisted := make(chan click.Entity)
+	mockClickStorage := NewMockClickStorage(ctrl)
+	mockClickStorage.EXPECT().
+		Persist(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, run storage.Runner, entity click.Entity) (int64, error) {
+			persisted <- entity
+			return 1, nil
+		})
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().IncrementClicks(gomock.Any(), gomock.Any(), int64(1)).Return(nil)
+
+	srv := NewService(testTransactioner{}, mockStorage, mockClickStorage)
+	srv.enqueueClick(Click{ShortenID: 1, ClickedAt: clickedAt, Referer: "ref", UserAgent: "ua", IP: "ip"})
+
+	ctx, cancel := context.WithCancel(logging.ToContext(Context(), logging.NewTestLogger()))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srv.RecordClicks(ctx)
+	}()
+
+	exp := click.Entity{ShortenID: 1, ClickedAt: clickedAt, Referer: "ref", UserAgent: "ua", IP: "ip"}
+	require.Equal(t, exp, <-persisted)
+
+	cancel()
+	<-done
+}
+
+func TestService_RecordClicks_drain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClickStorage := NewMockClickStorage(ctrl)
+	mockClickStorage.EXPECT().
+		Persist(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, run storage.Runner, entity click.Entity) (int64, error) {
+			require.NoError(t, ctx.Err())
+			return entity.ShortenID, nil
+		}).
+		Times(3)
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().IncrementClicks(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(3)
+
+	srv := NewService(testTransactioner{}, mockStorage, mockClickStorage)
+	for id := int64(1); id <= 3; id++ {
+		srv.enqueueClick(Click{ShortenID: id})
+	}
+
+	ctx, cancel := context.WithCancel(logging.ToContext(Context(), logging.NewTestLogger()))
+	cancel()
+	srv.RecordClicks(ctx)
+	require.Len(t, srv.clickQueue, 0)
+}
+
+func TestService_sweepExpired(t *testing.T) {
+	t.Run("delete", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().
+			DeleteExpired(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, run storage.Runner, deadline time.Time) (int64, error) {
+				require.WithinDuration(t, time.Now().Add(-time.Hour), deadline, time.Minute)
+				return 2, nil
+			})
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		swept, err := srv.sweepExpired(Context(), SweepOpts{Grace: time.Hour})
+		require.NoError(t, err)
+		require.Equal(t, int64(2), swept)
+	})
+
+	t.Run("archive", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().ArchiveExpired(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(1), nil)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		swept, err := srv.sweepExpired(Context(), SweepOpts{Archive: true})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), swept)
+	})
+}
+
+func TestService_Stats(t *testing.T) {
+	t.Run("validation", func(t *testing.T) {
+		srv := NewService(nil, nil, nil)
+		_, err := srv.Stats(Context(), 1, 0)
+		exp := ValidationError{Cause: internal.ErrBadInput, Details: map[string]interface{}{"days": "is out of range [1, 366]"}}
+		require.Equal(t, exp, err)
+	})
+
+	t.Run("not existing", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), int64(1)).Return(shorten.Entity{}, internal.ErrNotFound)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		_, err := srv.Stats(Context(), 1, 30)
+		require.True(t, errors.Is(err, internal.ErrNotFound))
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		const id = int64(1)
+		today := time.Now().UTC().Truncate(24 * time.Hour)
+		daily := []click.DailyCounter{{Day: today, Count: 2}}
+		referers := []click.Counter{{Value: "https://referer.com", Count: 1}}
+		userAgents := []click.Counter{{Value: "curl", Count: 2}}
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), id).Return(shorten.Entity{ID: id, Workspace: DefaultWorkspace}, nil)
+		mockClickStorage := NewMockClickStorage(ctrl)
+		mockClickStorage.EXPECT().Count(gomock.Any(), gomock.Any(), id).Return(int64(2), nil)
+		mockClickStorage.EXPECT().Daily(gomock.Any(), gomock.Any(), id, today.AddDate(0, 0, -6)).Return(daily, nil)
+		mockClickStorage.EXPECT().TopReferers(gomock.Any(), gomock.Any(), id, int64(topStatsLimit)).Return(referers, nil)
+		mockClickStorage.EXPECT().TopUserAgents(gomock.Any(), gomock.Any(), id, int64(topStatsLimit)).Return(userAgents, nil)
+
+		srv := NewService(testTransactioner{}, mockStorage, mockClickStorage)
+		actual, err := srv.Stats(Context(), id, 7)
+		require.NoError(t, err)
+		require.Equal(t, Stats{Total: 2, Daily: daily, TopReferers: referers, TopUserAgents: userAgents}, actual)
+	})
+}
+
 // TODO: verify flow when Transactioner fails to commit
 
 func Context() context.Context {
 	return context.Background()
 }
 
+type testCodeGenerator struct {
+	kind     CodeKind
+	generate func(url string, id int64, attempt int) (string, error)
+}
+
+func (g testCodeGenerator) Kind() CodeKind {
+	return g.kind
+}
+
+func (g testCodeGenerator) Generate(url string, id int64, attempt int) (string, error) {
+	return g.generate(url, id, attempt)
+}
+
+// testDestinationPolicy blocks the URLs containing any of the strings.
+type testDestinationPolicy []string
+
+func (p testDestinationPolicy) Check(url string) error {
+	for _, s := range p {
+		if strings.Contains(url, s) {
+			return fmt.Errorf("%w: denied by test", internal.ErrBlocked)
+		}
+	}
+
+	return nil
+}
+
 type testTransactioner struct{}
 
 func (testTransactioner) WithTx(_ context.Context, call func(runner storage.Runner) error) error {
@@ -0,0 +1,65 @@
+package shorten
+
+import (
+	"context"
+	"time"
+
+	"github.com/pavelmemory/jobtome/internal/logging"
+	"github.com/pavelmemory/jobtome/internal/storage"
+	"github.com/pavelmemory/jobtome/internal/tracing"
+)
+
+// SweepOpts configures removal of the expired shortens.
+type SweepOpts struct {
+	// Interval between two sweeps.
+	Interval time.Duration
+	// Grace is a period the expired shorten is kept, so it is reported as expired instead of not existing.
+	Grace time.Duration
+	// Archive moves the expired shortens into the archive instead of deleting them.
+	Archive bool
+}
+
+// SweepExpired removes expired shortens periodically until the `ctx` is cancelled.
+// It is blocking, so it should be run in a separate goroutine.
+// The `ctx` must have a logger injected into it.
+func (s *Service) SweepExpired(ctx context.Context, opts SweepOpts) {
+	logger := logging.FromContext(ctx).WithString("component", "Service").WithString("method", "SweepExpired")
+
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			swept, err := s.sweepExpired(ctx, opts)
+			if err != nil {
+				logger.WithError(err).Error("sweep expired shortens")
+				continue
+			}
+
+			if swept > 0 {
+				logger.WithInt64("swept", swept).Info("expired shortens swept")
+			}
+		}
+	}
+}
+
+func (s *Service) sweepExpired(ctx context.Context, opts SweepOpts) (swept int64, err error) {
+	ctx, span := startSpan(ctx, "SweepExpired")
+	defer func() { tracing.End(span, err) }()
+
+	deadline := time.Now().Add(-opts.Grace)
+
+	err = s.tr.WithTx(ctx, func(runner storage.Runner) error {
+		if opts.Archive {
+			swept, err = s.storage.ArchiveExpired(ctx, runner, deadline)
+		} else {
+			swept, err = s.storage.DeleteExpired(ctx, runner, deadline)
+		}
+		return err
+	})
+
+	return swept, err
+}
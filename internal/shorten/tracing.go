@@ -0,0 +1,15 @@
+package shorten
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/pavelmemory/jobtome/internal/shorten")
+
+// startSpan starts a span of the `method` of the service, it must be ended with `tracing.End`.
+func startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "shorten.Service/"+method)
+}
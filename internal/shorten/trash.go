@@ -0,0 +1,143 @@
+package shorten
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/logging"
+	"github.com/pavelmemory/jobtome/internal/storage"
+	"github.com/pavelmemory/jobtome/internal/tracing"
+)
+
+// Trash returns a page of the deleted shortens of the workspace, the most recently deleted go first.
+func (s *Service) Trash(ctx context.Context, pager Pager) (_ []Entity, err error) {
+	ctx, span := startSpan(ctx, "Trash")
+	defer func() { tracing.End(span, err) }()
+
+	if pager, err = validatePager(pager); err != nil {
+		return nil, err
+	}
+
+	if pager.AfterID != 0 {
+		return nil, ValidationError{
+			Cause:   internal.ErrBadInput,
+			Details: map[string]interface{}{"after_id": "not supported"},
+		}
+	}
+
+	if pager.Sort != "" {
+		return nil, ValidationError{
+			Cause:   internal.ErrBadInput,
+			Details: map[string]interface{}{"sort": "not supported"},
+		}
+	}
+
+	entities, err := s.list(ctx, pager, s.storage.Trash)
+	if err != nil {
+		return nil, fmt.Errorf("list deleted shortens: %w", err)
+	}
+
+	return entities, nil
+}
+
+// Restore returns the deleted shorten from the trash, so it redirects again with the same code.
+// It returns `internal.ErrNotFound` if the shorten is not deleted.
+func (s *Service) Restore(ctx context.Context, id int64) (_ Entity, err error) {
+	ctx, span := startSpan(ctx, "Restore")
+	defer func() { tracing.End(span, err) }()
+
+	var entity Entity
+	if err := s.tr.WithTx(ctx, func(runner storage.Runner) error {
+		if _, err := s.retrieveInWorkspace(ctx, runner, id); err != nil {
+			return err
+		}
+
+		if err := s.storage.Restore(ctx, runner, id); err != nil {
+			return err
+		}
+
+		entity, err = s.retrieve(ctx, runner, id)
+		return err
+	}); err != nil {
+		return Entity{}, fmt.Errorf("restore shorten %d: %w", id, err)
+	}
+
+	// the code could be cached as unknown while the shorten was deleted
+	s.invalidate(entity.Hash)
+
+	return entity, nil
+}
+
+// Purge permanently removes the shorten with its tags and clicks, deleted or not.
+// Its code becomes available for the new shortens.
+func (s *Service) Purge(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "Purge")
+	defer func() { tracing.End(span, err) }()
+
+	if err := s.tr.WithTx(ctx, func(runner storage.Runner) error {
+		if _, err := s.retrieveInWorkspace(ctx, runner, id); err != nil {
+			return err
+		}
+
+		return s.storage.Purge(ctx, runner, id)
+	}); err != nil {
+		return fmt.Errorf("purge shorten %d: %w", id, err)
+	}
+
+	if s.cache != nil {
+		s.cache.invalidateID(id)
+	}
+
+	return nil
+}
+
+// PurgeOpts configures removal of the deleted shortens from the trash.
+type PurgeOpts struct {
+	// Interval between two purges.
+	Interval time.Duration
+	// Retention is a period the deleted shortens are kept in the trash before they are purged.
+	Retention time.Duration
+}
+
+// PurgeTrash permanently removes the shortens kept in the trash longer than the retention period
+// periodically until the `ctx` is cancelled. It is independent of `SweepExpired`.
+// It is blocking, so it should be run in a separate goroutine.
+// The `ctx` must have a logger injected into it.
+func (s *Service) PurgeTrash(ctx context.Context, opts PurgeOpts) {
+	logger := logging.FromContext(ctx).WithString("component", "Service").WithString("method", "PurgeTrash")
+
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.purgeDeleted(ctx, opts.Retention)
+			if err != nil {
+				logger.WithError(err).Error("purge deleted shortens")
+				continue
+			}
+
+			if purged > 0 {
+				logger.WithInt64("purged", purged).Info("deleted shortens purged")
+			}
+		}
+	}
+}
+
+// purgeDeleted permanently removes the shortens deleted more than `retention` ago.
+func (s *Service) purgeDeleted(ctx context.Context, retention time.Duration) (purged int64, err error) {
+	ctx, span := startSpan(ctx, "PurgeDeleted")
+	defer func() { tracing.End(span, err) }()
+
+	err = s.tr.WithoutTx(ctx, func(runner storage.Runner) error {
+		purged, err = s.storage.PurgeDeleted(ctx, runner, time.Now().Add(-retention))
+		return err
+	})
+
+	return purged, err
+}
@@ -0,0 +1,177 @@
+package shorten
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/logging"
+	"github.com/pavelmemory/jobtome/internal/storage"
+	"github.com/pavelmemory/jobtome/internal/storage/shorten"
+)
+
+func TestService_Trash(t *testing.T) {
+	t.Run("bad limit", func(t *testing.T) {
+		srv := NewService(nil, nil, nil)
+		_, err := srv.Trash(Context(), Pager{Limit: 0})
+		exp := ValidationError{Cause: internal.ErrBadInput, Details: map[string]interface{}{"limit": "is lesser then 1"}}
+		require.Equal(t, exp, err)
+	})
+
+	t.Run("after id", func(t *testing.T) {
+		srv := NewService(nil, nil, nil)
+		_, err := srv.Trash(Context(), Pager{Limit: 10, AfterID: 1})
+		exp := ValidationError{Cause: internal.ErrBadInput, Details: map[string]interface{}{"after_id": "not supported"}}
+		require.Equal(t, exp, err)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		deletedAt := time.Now()
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().Trash(gomock.Any(), gomock.Any(), "marketing", Pager{Limit: 10}).
+			Return([]shorten.Entity{{ID: 1, URL: "https://example.com", Hash: "1", Workspace: "marketing", DeletedAt: deletedAt}}, nil)
+		mockStorage.EXPECT().Tags(gomock.Any(), gomock.Any(), int64(1)).Return(map[int64][]string{1: {"promo"}}, nil)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		entities, err := srv.Trash(WithWorkspace(Context(), "marketing"), Pager{Limit: 10})
+		require.NoError(t, err)
+		require.Equal(t, []Entity{{ID: 1, URL: "https://example.com", Hash: "1", Tags: []string{"promo"}, Workspace: "marketing", DeletedAt: deletedAt}}, entities)
+	})
+}
+
+func TestService_Restore(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		deleted := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1", Workspace: DefaultWorkspace, DeletedAt: time.Now()}
+		restored := deleted
+		restored.DeletedAt = time.Time{}
+
+		mockStorage := NewMockStorage(ctrl)
+		gomock.InOrder(
+			mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), int64(1)).Return(deleted, nil),
+			mockStorage.EXPECT().Restore(gomock.Any(), gomock.Any(), int64(1)).Return(nil),
+			mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), int64(1)).Return(restored, nil),
+		)
+		mockStorage.EXPECT().Tags(gomock.Any(), gomock.Any(), int64(1)).Return(map[int64][]string{}, nil)
+
+		cache := NewResolveCache(10, time.Minute, time.Minute)
+		cache.store(0, cacheEntry{hash: "1", expiresAt: time.Now().Add(time.Minute)})
+
+		srv := NewService(testTransactioner{}, mockStorage, nil, WithResolveCache(cache))
+		entity, err := srv.Restore(Context(), 1)
+		require.NoError(t, err)
+		require.Equal(t, serviceEntity(restored), entity)
+		require.Zero(t, cache.Stats().Entries, "unknown code is not cached anymore")
+	})
+
+	t.Run("not deleted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), int64(1)).Return(shorten.Entity{ID: 1, Workspace: DefaultWorkspace}, nil)
+		mockStorage.EXPECT().Restore(gomock.Any(), gomock.Any(), int64(1)).Return(internal.ErrNotFound)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		_, err := srv.Restore(Context(), 1)
+		require.True(t, errors.Is(err, internal.ErrNotFound), err)
+	})
+
+	t.Run("another workspace", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), int64(1)).Return(shorten.Entity{ID: 1, Workspace: "marketing", DeletedAt: time.Now()}, nil)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		_, err := srv.Restore(WithWorkspace(Context(), "sales"), 1)
+		require.True(t, errors.Is(err, internal.ErrNotFound), err)
+	})
+}
+
+func TestService_Purge(t *testing.T) {
+	t.Run("deleted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), int64(1)).Return(shorten.Entity{ID: 1, Workspace: DefaultWorkspace, DeletedAt: time.Now()}, nil)
+		mockStorage.EXPECT().Purge(gomock.Any(), gomock.Any(), int64(1)).Return(nil)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		require.NoError(t, srv.Purge(Context(), 1))
+	})
+
+	t.Run("another workspace", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), int64(1)).Return(shorten.Entity{ID: 1, Workspace: "marketing"}, nil)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		err := srv.Purge(WithWorkspace(Context(), "sales"), 1)
+		require.True(t, errors.Is(err, internal.ErrNotFound), err)
+	})
+}
+
+func TestService_purgeDeleted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().
+		PurgeDeleted(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, run storage.Runner, deadline time.Time) (int64, error) {
+			require.WithinDuration(t, time.Now().Add(-24*time.Hour), deadline, time.Minute)
+			return 3, nil
+		})
+
+	srv := NewService(testTransactioner{}, mockStorage, nil)
+	purged, err := srv.purgeDeleted(Context(), 24*time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), purged)
+}
+
+func TestService_PurgeTrash(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(logging.ToContext(Context(), logging.NewTestLogger()))
+	defer cancel()
+
+	mockStorage := NewMockStorage(ctrl)
+	gomock.InOrder(
+		mockStorage.EXPECT().PurgeDeleted(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(0), errors.New("database is locked")),
+		// the failed purge doesn't stop the next ones
+		mockStorage.EXPECT().PurgeDeleted(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(context.Context, storage.Runner, time.Time) (int64, error) {
+				cancel()
+				return 1, nil
+			}),
+	)
+
+	srv := NewService(testTransactioner{}, mockStorage, nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srv.PurgeTrash(ctx, PurgeOpts{Interval: time.Millisecond, Retention: time.Hour})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("trash is not purged")
+	}
+}
@@ -0,0 +1,160 @@
+package shorten
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/storage"
+	"github.com/pavelmemory/jobtome/internal/tracing"
+)
+
+const (
+	// maxTags is a number of tags that could be assigned to a single shorten.
+	maxTags = 20
+	// maxTagLength is the longest allowed tag.
+	maxTagLength = 64
+)
+
+// Patch is a set of changes for the existing shorten. Fields that are nil are left untouched.
+type Patch struct {
+	URL *string
+	// Alias replaces the code of the shorten, it must satisfy the alias policy.
+	Alias *string
+	// ExpiresAt replaces the expiration moment, a zero value removes the expiration.
+	ExpiresAt *time.Time
+	// MaxClicks replaces the number of redirects allowed, 0 removes the limit.
+	MaxClicks *int64
+	// Tags replaces all tags of the shorten, an empty slice removes them.
+	Tags *[]string
+}
+
+// Update applies the `patch` to the shorten and returns the updated shorten.
+// The code of the shorten is preserved unless a new alias is provided,
+// so already distributed short links keep working with the new URL.
+func (s *Service) Update(ctx context.Context, id int64, patch Patch) (_ Entity, err error) {
+	ctx, span := startSpan(ctx, "Update")
+	defer func() { tracing.End(span, err) }()
+
+	if err := s.validatePatch(&patch); err != nil {
+		return Entity{}, err
+	}
+
+	var updated Entity
+	var previousHash string
+	if err := s.tr.WithTx(ctx, func(runner storage.Runner) error {
+		short, err := s.retrieveOwned(ctx, runner, id)
+		if err != nil {
+			return err
+		}
+		previousHash = short.Hash
+
+		if patch.URL != nil {
+			short.URL = *patch.URL
+		}
+		if patch.Alias != nil {
+			short.Hash = *patch.Alias
+		}
+		if patch.ExpiresAt != nil {
+			short.ExpiresAt = *patch.ExpiresAt
+		}
+		if patch.MaxClicks != nil {
+			short.MaxClicks = *patch.MaxClicks
+		}
+
+		if err := s.storage.Update(ctx, runner, short); err != nil {
+			return err
+		}
+
+		if patch.Tags != nil {
+			if err := s.storage.SetTags(ctx, runner, id, *patch.Tags); err != nil {
+				return fmt.Errorf("set tags: %w", err)
+			}
+		}
+
+		updated, err = s.retrieve(ctx, runner, id)
+		return err
+	}); err != nil {
+		return Entity{}, fmt.Errorf("update shorten %d: %w", id, err)
+	}
+
+	s.invalidate(previousHash, updated.Hash)
+
+	return updated, nil
+}
+
+// validatePatch verifies the `patch` and normalizes its URL and tags.
+func (s *Service) validatePatch(patch *Patch) error {
+	if patch.URL != nil {
+		normalized, err := s.urlPolicy.Normalize(*patch.URL)
+		if err != nil {
+			return err
+		}
+		if err := s.checkDestination(normalized); err != nil {
+			return err
+		}
+		patch.URL = &normalized
+	}
+
+	if patch.Alias != nil {
+		if err := s.aliasPolicy.Validate(*patch.Alias); err != nil {
+			return err
+		}
+	}
+
+	limits := Entity{}
+	if patch.ExpiresAt != nil {
+		limits.ExpiresAt = *patch.ExpiresAt
+	}
+	if patch.MaxClicks != nil {
+		limits.MaxClicks = *patch.MaxClicks
+	}
+	if err := validateLimits(limits); err != nil {
+		return err
+	}
+
+	if patch.Tags != nil {
+		tags, err := normalizeTags(*patch.Tags)
+		if err != nil {
+			return err
+		}
+		patch.Tags = &tags
+	}
+
+	return nil
+}
+
+// normalizeTags trims, deduplicates and sorts the `tags`.
+func normalizeTags(tags []string) ([]string, error) {
+	unique := make(map[string]struct{}, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || len(tag) > maxTagLength {
+			return nil, ValidationError{
+				Cause:   internal.ErrBadInput,
+				Details: map[string]interface{}{"tags": fmt.Sprintf("tag length is out of range [1, %d]", maxTagLength)},
+			}
+		}
+
+		if _, ok := unique[tag]; ok {
+			continue
+		}
+		unique[tag] = struct{}{}
+		normalized = append(normalized, tag)
+	}
+
+	if len(normalized) > maxTags {
+		return nil, ValidationError{
+			Cause:   internal.ErrBadInput,
+			Details: map[string]interface{}{"tags": fmt.Sprintf("more then %d tags", maxTags)},
+		}
+	}
+
+	sort.Strings(normalized)
+
+	return normalized, nil
+}
@@ -0,0 +1,126 @@
+package shorten
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/storage/shorten"
+)
+
+func TestService_Update(t *testing.T) {
+	t.Run("validation", func(t *testing.T) {
+		blank := " "
+		unsafe := "ftp://example.com/file"
+		reserved := "api"
+		past := time.Now().Add(-time.Minute)
+		negative := int64(-1)
+		long := strings.Repeat("a", maxTagLength+1)
+		many := make([]string, maxTags+1)
+		for i := range many {
+			many[i] = fmt.Sprint(i)
+		}
+
+		for name, tc := range map[string]struct {
+			patch   Patch
+			details map[string]interface{}
+		}{
+			"blank url":           {patch: Patch{URL: &blank}, details: map[string]interface{}{"url": "blank or empty"}},
+			"unsafe url":          {patch: Patch{URL: &unsafe}, details: map[string]interface{}{"url": `scheme "ftp" is not allowed, one of http, https is expected`}},
+			"reserved alias":      {patch: Patch{Alias: &reserved}, details: map[string]interface{}{"alias": "reserved"}},
+			"expired":             {patch: Patch{ExpiresAt: &past}, details: map[string]interface{}{"expires_at": "not in the future"}},
+			"negative max clicks": {patch: Patch{MaxClicks: &negative}, details: map[string]interface{}{"max_clicks": "is negative"}},
+			"blank tag":           {patch: Patch{Tags: &[]string{"a", " "}}, details: map[string]interface{}{"tags": "tag length is out of range [1, 64]"}},
+			"long tag":            {patch: Patch{Tags: &[]string{long}}, details: map[string]interface{}{"tags": "tag length is out of range [1, 64]"}},
+			"too many tags":       {patch: Patch{Tags: &many}, details: map[string]interface{}{"tags": "more then 20 tags"}},
+		} {
+			t.Run(name, func(t *testing.T) {
+				srv := NewService(nil, nil, nil)
+				_, err := srv.Update(Context(), 1, tc.patch)
+				require.Equal(t, ValidationError{Cause: internal.ErrBadInput, Details: tc.details}, err)
+			})
+		}
+	})
+
+	t.Run("not existing", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), int64(1)).Return(shorten.Entity{}, internal.ErrNotFound)
+
+		url := "https://moved.com"
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		_, err := srv.Update(Context(), 1, Patch{URL: &url})
+		require.True(t, errors.Is(err, internal.ErrNotFound), err)
+	})
+
+	t.Run("url", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		expiresAt := time.Now().Add(time.Hour)
+		existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567", ExpiresAt: expiresAt, MaxClicks: 10, Workspace: DefaultWorkspace}
+		updated := existing
+		updated.URL = "https://moved.com"
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), existing.ID).Return(existing, nil)
+		mockStorage.EXPECT().Update(gomock.Any(), gomock.Any(), updated).Return(nil)
+		mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), existing.ID).Return(updated, nil)
+		mockStorage.EXPECT().Tags(gomock.Any(), gomock.Any(), existing.ID).Return(nil, nil)
+
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		actual, err := srv.Update(Context(), existing.ID, Patch{URL: &updated.URL})
+		require.NoError(t, err)
+		require.Equal(t, Entity{ID: 1, URL: "https://moved.com", Hash: "1234567", ExpiresAt: expiresAt, MaxClicks: 10, Workspace: DefaultWorkspace}, actual)
+	})
+
+	t.Run("alias and tags", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567", ExpiresAt: time.Now().Add(time.Hour), Workspace: DefaultWorkspace}
+		updated := existing
+		updated.Hash = "summer-sale"
+		updated.ExpiresAt = time.Time{}
+
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), existing.ID).Return(existing, nil)
+		mockStorage.EXPECT().Update(gomock.Any(), gomock.Any(), updated).Return(nil)
+		mockStorage.EXPECT().SetTags(gomock.Any(), gomock.Any(), existing.ID, []string{"promo", "summer"}).Return(nil)
+		mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), existing.ID).Return(updated, nil)
+		mockStorage.EXPECT().Tags(gomock.Any(), gomock.Any(), existing.ID).Return(map[int64][]string{1: {"promo", "summer"}}, nil)
+
+		never := time.Time{}
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		actual, err := srv.Update(Context(), existing.ID, Patch{
+			Alias:     &updated.Hash,
+			ExpiresAt: &never,
+			Tags:      &[]string{"summer", " promo ", "summer"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, Entity{ID: 1, URL: "https://example.com", Hash: "summer-sale", Tags: []string{"promo", "summer"}, Workspace: DefaultWorkspace}, actual)
+	})
+
+	t.Run("alias is taken", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		existing := shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567", Workspace: DefaultWorkspace}
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().Retrieve(gomock.Any(), gomock.Any(), existing.ID).Return(existing, nil)
+		mockStorage.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(internal.ErrNotUnique)
+
+		alias := "summer-sale"
+		srv := NewService(testTransactioner{}, mockStorage, nil)
+		_, err := srv.Update(Context(), existing.ID, Patch{Alias: &alias})
+		require.True(t, errors.Is(err, internal.ErrNotUnique), err)
+	})
+}
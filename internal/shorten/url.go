@@ -0,0 +1,159 @@
+package shorten
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/idna"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/policy"
+)
+
+// DefaultSchemes are the schemes of the URLs allowed by default.
+var DefaultSchemes = []string{"http", "https"}
+
+// DefaultURLPolicy is used if no other policy is configured.
+var DefaultURLPolicy = NewURLPolicy(DefaultSchemes, false, false)
+
+// maxURLLength is a length of the longest URL accepted, longer URLs are not supported by the most browsers.
+const maxURLLength = 2048
+
+// defaultPorts are the ports omitted from the normalized URLs of the schemes.
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// NewURLPolicy returns a policy that allows the URLs with the `schemes`, all other schemes are rejected.
+// `sortQuery` orders the query parameters by name and `stripFragment` removes the fragment,
+// so the URLs that differ only by them are deduplicated.
+func NewURLPolicy(schemes []string, sortQuery, stripFragment bool) URLPolicy {
+	policy := URLPolicy{
+		Schemes:       make(map[string]struct{}, len(schemes)),
+		SortQuery:     sortQuery,
+		StripFragment: stripFragment,
+	}
+
+	for _, scheme := range schemes {
+		if scheme = strings.TrimSpace(scheme); scheme != "" {
+			policy.Schemes[strings.ToLower(scheme)] = struct{}{}
+		}
+	}
+
+	return policy
+}
+
+// URLPolicy defines what URLs could be shortened and how they are normalized.
+type URLPolicy struct {
+	// Schemes are lower-cased schemes of the allowed URLs.
+	Schemes map[string]struct{}
+	// SortQuery orders the query parameters by name, the order of the values of the same parameter is kept.
+	SortQuery bool
+	// StripFragment removes the fragment, otherwise it is kept as is.
+	StripFragment bool
+}
+
+// Normalize returns the URL in the canonical form, so the equivalent URLs are shortened into the same shorten:
+// the scheme and the host are lower-cased, the internationalized host is converted to punycode,
+// the default port and the root path `/` are removed.
+// It returns `ValidationError` if the URL can't be parsed or it isn't allowed by the policy.
+func (up URLPolicy) Normalize(raw string) (string, error) {
+	reason := func(format string, args ...interface{}) error {
+		return ValidationError{
+			Cause:   internal.ErrBadInput,
+			Details: map[string]interface{}{"url": fmt.Sprintf(format, args...)},
+		}
+	}
+
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", reason("blank or empty")
+	}
+
+	if len(raw) > maxURLLength {
+		return "", reason("longer than %d characters", maxURLLength)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", reason("not a valid URL")
+	}
+
+	if u.Scheme == "" {
+		return "", reason("scheme is missing, one of %s is expected", up.schemes())
+	}
+
+	if _, ok := up.Schemes[u.Scheme]; !ok {
+		return "", reason("scheme %q is not allowed, one of %s is expected", u.Scheme, up.schemes())
+	}
+
+	if u.Opaque != "" || u.Hostname() == "" {
+		return "", reason("host is missing")
+	}
+
+	if u.User != nil {
+		// `https://bank.com@evil.com` is used to disguise the real destination
+		return "", reason("user info is not allowed")
+	}
+
+	host, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return "", reason("invalid host: %v", err)
+	}
+
+	port := u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+
+	u.Host = host
+	if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	}
+	if port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	}
+
+	if u.Path == "/" && u.RawPath == "" {
+		// the root path is dropped rather than added, so the codes of the already stored URLs are kept
+		u.Path = ""
+	}
+
+	if up.SortQuery && u.RawQuery != "" {
+		// encoding of the query sorts the parameters by name
+		u.RawQuery = u.Query().Encode()
+	}
+	u.ForceQuery = false
+
+	if up.StripFragment {
+		u.Fragment, u.RawFragment = "", ""
+	}
+
+	return u.String(), nil
+}
+
+// schemes returns the allowed schemes in a stable order.
+func (up URLPolicy) schemes() string {
+	schemes := make([]string, 0, len(up.Schemes))
+	for scheme := range up.Schemes {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+
+	return strings.Join(schemes, ", ")
+}
+
+// normalizeHost lower-cases the domain name and converts its internationalized labels to punycode.
+// IP addresses are returned in their canonical form, including the short and numeric forms of IPv4 like `127.1`.
+func normalizeHost(host string) (string, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+
+	if ip := policy.ParseIPv4(host); ip != nil {
+		return ip.String(), nil
+	}
+
+	return idna.Lookup.ToASCII(host)
+}
@@ -0,0 +1,77 @@
+package shorten
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pavelmemory/jobtome/internal"
+)
+
+func TestURLPolicy_Normalize(t *testing.T) {
+	t.Run("normalized", func(t *testing.T) {
+		policy := DefaultURLPolicy
+
+		for raw, exp := range map[string]string{
+			"https://example.com":                  "https://example.com",
+			"  https://example.com/  ":             "https://example.com",
+			"HTTPS://Example.COM/Path":             "https://example.com/Path",
+			"http://example.com:80/a":              "http://example.com/a",
+			"https://example.com:443/a":            "https://example.com/a",
+			"http://example.com:443/a":             "http://example.com:443/a",
+			"https://example.com:8443":             "https://example.com:8443",
+			"https://bücher.example/straße?q=ä":    "https://xn--bcher-kva.example/stra%C3%9Fe?q=ä",
+			"http://[::1]:80/a":                    "http://[::1]/a",
+			"http://[0:0::1]:8080/a":               "http://[::1]:8080/a",
+			"http://127.0.0.1/a":                   "http://127.0.0.1/a",
+			"http://2130706433/a":                  "http://127.0.0.1/a",
+			"http://127.1/a":                       "http://127.0.0.1/a",
+			"http://0x7f.1:8080/a":                 "http://127.0.0.1:8080/a",
+			"https://example.com/a?b=2&a=1":        "https://example.com/a?b=2&a=1",
+			"https://example.com/a?":               "https://example.com/a",
+			"https://example.com/a#section":        "https://example.com/a#section",
+			"https://example.com/a%2Fb":            "https://example.com/a%2Fb",
+			"https://example.com/?utm_source=mail": "https://example.com?utm_source=mail",
+		} {
+			act, err := policy.Normalize(raw)
+			require.NoError(t, err, raw)
+			require.Equal(t, exp, act, raw)
+		}
+	})
+
+	t.Run("sorted query and stripped fragment", func(t *testing.T) {
+		policy := NewURLPolicy(DefaultSchemes, true, true)
+
+		act, err := policy.Normalize("https://example.com/a?b=2&a=1&b=1#section")
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com/a?a=1&b=2&b=1", act)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		policy := NewURLPolicy([]string{"HTTPS", " ", "http"}, false, false)
+
+		for raw, reason := range map[string]string{
+			"":                           "blank or empty",
+			"   ":                        "blank or empty",
+			"not a url":                  "scheme is missing, one of http, https is expected",
+			"example.com/a":              "scheme is missing, one of http, https is expected",
+			"javascript:alert(1)":        `scheme "javascript" is not allowed, one of http, https is expected`,
+			"ftp://example.com/file":     `scheme "ftp" is not allowed, one of http, https is expected`,
+			"http://":                    "host is missing",
+			"http://:8080/a":             "host is missing",
+			"http:example.com":           "host is missing",
+			"https://bank.com@evil.com/": "user info is not allowed",
+			"http://exa mple.com/":       "not a valid URL",
+			"https://example.com/" + strings.Repeat("a", maxURLLength): "longer than 2048 characters",
+		} {
+			_, err := policy.Normalize(raw)
+			exp := ValidationError{Cause: internal.ErrBadInput, Details: map[string]interface{}{"url": reason}}
+			require.Equal(t, exp, err, raw)
+		}
+
+		_, err := policy.Normalize("https://exa_mple.com/")
+		require.ErrorIs(t, err, internal.ErrBadInput)
+		require.Contains(t, err.(ValidationError).Details["url"], "invalid host")
+	})
+}
@@ -0,0 +1,73 @@
+package shorten
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/storage"
+	"github.com/pavelmemory/jobtome/internal/storage/shorten"
+)
+
+// DefaultWorkspace is a workspace of the shortens managed without authorization
+// and of the shortens created before the workspaces were introduced.
+const DefaultWorkspace = "default"
+
+type workspaceKey struct{}
+
+// WithWorkspace returns a copy of the `ctx` that scopes the operations of the service to the `workspace`.
+// Shortens of the other workspaces are not visible for such operations, but their codes are still taken.
+func WithWorkspace(ctx context.Context, workspace string) context.Context {
+	return context.WithValue(ctx, workspaceKey{}, workspace)
+}
+
+// WorkspaceFrom returns the workspace the operations of the `ctx` are scoped to, `DefaultWorkspace` if it is not set.
+func WorkspaceFrom(ctx context.Context) string {
+	if workspace, ok := ctx.Value(workspaceKey{}).(string); ok && workspace != "" {
+		return workspace
+	}
+
+	return DefaultWorkspace
+}
+
+type ownerKey struct{}
+
+// WithOwner returns a copy of the `ctx` that makes the `owner` an owner of the shortens created with it.
+func WithOwner(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, ownerKey{}, owner)
+}
+
+// OwnerFrom returns the owner of the shortens created with the `ctx`, it is empty if it is not set.
+func OwnerFrom(ctx context.Context) string {
+	owner, _ := ctx.Value(ownerKey{}).(string)
+	return owner
+}
+
+// retrieveOwned returns the shorten with the `id` if it belongs to the workspace of the `ctx` and it is not deleted.
+// Shortens of the other workspaces and the deleted ones are reported as not existing.
+func (s *Service) retrieveOwned(ctx context.Context, runner storage.Runner, id int64) (shorten.Entity, error) {
+	short, err := s.retrieveInWorkspace(ctx, runner, id)
+	if err != nil {
+		return shorten.Entity{}, err
+	}
+
+	if !short.DeletedAt.IsZero() {
+		return shorten.Entity{}, fmt.Errorf("deleted shorten: %w", internal.ErrNotFound)
+	}
+
+	return short, nil
+}
+
+// retrieveInWorkspace returns the shorten with the `id`, deleted or not, if it belongs to the workspace of the `ctx`.
+func (s *Service) retrieveInWorkspace(ctx context.Context, runner storage.Runner, id int64) (shorten.Entity, error) {
+	short, err := s.storage.Retrieve(ctx, runner, id)
+	if err != nil {
+		return shorten.Entity{}, err
+	}
+
+	if short.Workspace != WorkspaceFrom(ctx) {
+		return shorten.Entity{}, fmt.Errorf("shorten of another workspace: %w", internal.ErrNotFound)
+	}
+
+	return short, nil
+}
@@ -0,0 +1,131 @@
+package apikey
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/storage"
+)
+
+type Entity struct {
+	ID   int64
+	Name string
+	// Hash is a digest of the key, the key itself is never stored.
+	Hash   string
+	Scopes []string
+	// Workspace is a tenant the key gives access to.
+	Workspace string
+	CreatedAt time.Time
+	// RevokedAt is a moment the key was revoked, zero value means the key is active.
+	RevokedAt time.Time
+}
+
+// columns is a list of columns scanned by `scan`.
+const columns = `id, name, key_hash, scopes, workspace, created_at, revoked_at`
+
+type Repo struct{}
+
+func (Repo) Persist(ctx context.Context, run storage.Runner, entity Entity) (int64, error) {
+	const query = `
+		INSERT INTO api_key(name, key_hash, scopes, workspace, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`
+
+	var id int64
+	res := run.QuerySingle(ctx, query, entity.Name, entity.Hash, strings.Join(entity.Scopes, " "), entity.Workspace, time.Now().Unix())
+	if err := storage.ConvertError(res.Scan(&id)); err != nil {
+		return 0, fmt.Errorf("exec: %w", err)
+	}
+
+	return id, nil
+}
+
+// ByHash returns the active key with the `hash`.
+func (Repo) ByHash(ctx context.Context, run storage.Runner, hash string) (Entity, error) {
+	const query = `
+		SELECT ` + columns + `
+		FROM api_key
+		WHERE key_hash = $1 AND revoked_at = 0`
+
+	entity, err := scan(run.QuerySingle(ctx, query, hash))
+	if err != nil {
+		return Entity{}, fmt.Errorf("retrieve single: %w", err)
+	}
+
+	return entity, nil
+}
+
+// List returns the keys of the `workspace` including the revoked ones, the empty `workspace` matches all keys.
+func (Repo) List(ctx context.Context, run storage.Runner, workspace string) ([]Entity, error) {
+	query := `
+		SELECT ` + columns + `
+		FROM api_key`
+	var args []interface{}
+	if workspace != "" {
+		query += `
+		WHERE workspace = $1`
+		args = append(args, workspace)
+	}
+	query += `
+		ORDER BY id`
+
+	res, err := run.Query(ctx, query, args...)
+	if err := storage.ConvertError(err); err != nil {
+		return nil, fmt.Errorf("retrieve multiple: %w", err)
+	}
+	defer res.Close()
+
+	var entities []Entity
+	for res.Next() {
+		entity, err := scan(res)
+		if err != nil {
+			return nil, fmt.Errorf("scan retrieved: %w", err)
+		}
+		entities = append(entities, entity)
+	}
+
+	return entities, nil
+}
+
+// Revoke marks the active key with the `id` of the `workspace` as revoked, so it can't be used anymore.
+// The empty `workspace` matches the key of any workspace.
+func (Repo) Revoke(ctx context.Context, run storage.Runner, workspace string, id int64) error {
+	query := `UPDATE api_key SET revoked_at = $1 WHERE id = $2 AND revoked_at = 0`
+	args := []interface{}{time.Now().Unix(), id}
+	if workspace != "" {
+		query += ` AND workspace = $3`
+		args = append(args, workspace)
+	}
+
+	res := run.Exec(ctx, query, args...)
+	if err := storage.ConvertError(res.Err()); err != nil {
+		return fmt.Errorf("exec update: %w", err)
+	}
+
+	if res.Affected() == 1 {
+		return nil
+	}
+
+	return internal.ErrNotFound
+}
+
+func scan(res storage.SingleResult) (Entity, error) {
+	var entity Entity
+	var scopes string
+	var createdAt, revokedAt int64
+	err := res.Scan(&entity.ID, &entity.Name, &entity.Hash, &scopes, &entity.Workspace, &createdAt, &revokedAt)
+	if err := storage.ConvertError(err); err != nil {
+		return Entity{}, err
+	}
+
+	entity.Scopes = strings.Fields(scopes)
+	entity.CreatedAt = time.Unix(createdAt, 0)
+	if revokedAt != 0 {
+		entity.RevokedAt = time.Unix(revokedAt, 0)
+	}
+
+	return entity, nil
+}
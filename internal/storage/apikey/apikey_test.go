@@ -0,0 +1,84 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/storage"
+)
+
+func TestRepo(t *testing.T) {
+	db, cleanup := initDB(t, t.Name())
+	defer cleanup()
+
+	repo := Repo{}
+
+	var id int64
+	t.Run("persist", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) (err error) {
+			id, err = repo.Persist(context.Background(), runner, Entity{Name: "ci", Hash: "digest", Scopes: []string{"shorten:read", "shorten:write"}, Workspace: "marketing"})
+			return err
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("not unique", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			_, err := repo.Persist(context.Background(), runner, Entity{Name: "other", Hash: "digest", Scopes: []string{"admin"}, Workspace: "default"})
+			return err
+		})
+		require.True(t, errors.Is(err, internal.ErrNotUnique), err)
+	})
+
+	t.Run("by hash", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			entity, err := repo.ByHash(context.Background(), runner, "digest")
+			require.NoError(t, err)
+			require.Equal(t, id, entity.ID)
+			require.Equal(t, "ci", entity.Name)
+			require.Equal(t, []string{"shorten:read", "shorten:write"}, entity.Scopes)
+			require.Equal(t, "marketing", entity.Workspace)
+			require.False(t, entity.CreatedAt.IsZero())
+			require.True(t, entity.RevokedAt.IsZero())
+
+			_, err = repo.ByHash(context.Background(), runner, "unknown")
+			require.True(t, errors.Is(err, internal.ErrNotFound), err)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			err := repo.Revoke(context.Background(), runner, "default", id)
+			require.True(t, errors.Is(err, internal.ErrNotFound), err)
+
+			require.NoError(t, repo.Revoke(context.Background(), runner, "marketing", id))
+
+			err = repo.Revoke(context.Background(), runner, "", id)
+			require.True(t, errors.Is(err, internal.ErrNotFound), err)
+
+			_, err = repo.ByHash(context.Background(), runner, "digest")
+			require.True(t, errors.Is(err, internal.ErrNotFound), err)
+
+			entities, err := repo.List(context.Background(), runner, "")
+			require.NoError(t, err)
+			require.Len(t, entities, 1)
+			require.False(t, entities[0].RevokedAt.IsZero())
+
+			entities, err = repo.List(context.Background(), runner, "marketing")
+			require.NoError(t, err)
+			require.Len(t, entities, 1)
+
+			entities, err = repo.List(context.Background(), runner, "default")
+			require.NoError(t, err)
+			require.Empty(t, entities)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,137 @@
+package click
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pavelmemory/jobtome/internal/storage"
+)
+
+const day = int64(24 * time.Hour / time.Second)
+
+type Entity struct {
+	ID        int64
+	ShortenID int64
+	ClickedAt time.Time
+	Referer   string
+	UserAgent string
+	IP        string
+}
+
+// Counter is a number of clicks that share the same value of some attribute.
+type Counter struct {
+	Value string
+	Count int64
+}
+
+// DailyCounter is a number of clicks made during a single day (UTC).
+type DailyCounter struct {
+	Day   time.Time
+	Count int64
+}
+
+type Repo struct{}
+
+func (Repo) Persist(ctx context.Context, run storage.Runner, entity Entity) (int64, error) {
+	const query = `
+		INSERT INTO click(shorten_id, clicked_at, referer, user_agent, ip)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`
+
+	var id int64
+	res := run.QuerySingle(ctx, query, entity.ShortenID, entity.ClickedAt.Unix(), entity.Referer, entity.UserAgent, entity.IP)
+	if err := storage.ConvertError(res.Scan(&id)); err != nil {
+		return 0, fmt.Errorf("exec: %w", err)
+	}
+
+	return id, nil
+}
+
+// Count returns total amount of clicks made for the shorten.
+func (Repo) Count(ctx context.Context, run storage.Runner, shortenID int64) (int64, error) {
+	const query = `SELECT COUNT(*) FROM click WHERE shorten_id = $1`
+
+	var count int64
+	res := run.QuerySingle(ctx, query, shortenID)
+	if err := storage.ConvertError(res.Scan(&count)); err != nil {
+		return 0, fmt.Errorf("retrieve single: %w", err)
+	}
+
+	return count, nil
+}
+
+// Daily returns amount of clicks made for the shorten grouped by days starting from `since`.
+// Days without clicks are not included into the result.
+func (Repo) Daily(ctx context.Context, run storage.Runner, shortenID int64, since time.Time) ([]DailyCounter, error) {
+	const query = `
+		SELECT (clicked_at / $1) * $1 AS day, COUNT(*)
+		FROM click
+		WHERE shorten_id = $2 AND clicked_at >= $3
+		GROUP BY day
+		ORDER BY day`
+
+	res, err := run.Query(ctx, query, day, shortenID, since.Unix())
+	if err := storage.ConvertError(err); err != nil {
+		return nil, fmt.Errorf("retrieve multiple: %w", err)
+	}
+	defer res.Close()
+
+	var counters []DailyCounter
+	for res.Next() {
+		var counter DailyCounter
+		var dayStart int64
+		if err := storage.ConvertError(res.Scan(&dayStart, &counter.Count)); err != nil {
+			return nil, fmt.Errorf("scan retrieved: %w", err)
+		}
+		counter.Day = time.Unix(dayStart, 0).UTC()
+		counters = append(counters, counter)
+	}
+
+	return counters, nil
+}
+
+// TopReferers returns up to `limit` the most frequent referers of the shorten's clicks.
+func (p Repo) TopReferers(ctx context.Context, run storage.Runner, shortenID, limit int64) ([]Counter, error) {
+	const query = `
+		SELECT referer, COUNT(*) AS cnt
+		FROM click
+		WHERE shorten_id = $1 AND referer <> ''
+		GROUP BY referer
+		ORDER BY cnt DESC, referer
+		LIMIT $2`
+
+	return p.top(ctx, run, query, shortenID, limit)
+}
+
+// TopUserAgents returns up to `limit` the most frequent user agents of the shorten's clicks.
+func (p Repo) TopUserAgents(ctx context.Context, run storage.Runner, shortenID, limit int64) ([]Counter, error) {
+	const query = `
+		SELECT user_agent, COUNT(*) AS cnt
+		FROM click
+		WHERE shorten_id = $1 AND user_agent <> ''
+		GROUP BY user_agent
+		ORDER BY cnt DESC, user_agent
+		LIMIT $2`
+
+	return p.top(ctx, run, query, shortenID, limit)
+}
+
+func (Repo) top(ctx context.Context, run storage.Runner, query string, shortenID, limit int64) ([]Counter, error) {
+	res, err := run.Query(ctx, query, shortenID, limit)
+	if err := storage.ConvertError(err); err != nil {
+		return nil, fmt.Errorf("retrieve multiple: %w", err)
+	}
+	defer res.Close()
+
+	var counters []Counter
+	for res.Next() {
+		var counter Counter
+		if err := storage.ConvertError(res.Scan(&counter.Value, &counter.Count)); err != nil {
+			return nil, fmt.Errorf("scan retrieved: %w", err)
+		}
+		counters = append(counters, counter)
+	}
+
+	return counters, nil
+}
@@ -0,0 +1,162 @@
+package click
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/storage"
+)
+
+func TestSQLLite_Persist(t *testing.T) {
+	db, cleanup := initDB(t, t.Name())
+	defer cleanup()
+
+	repo := Repo{}
+
+	t.Run("unknown shorten", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			_, err := repo.Persist(context.Background(), runner, Entity{ShortenID: 100, ClickedAt: time.Now()})
+			require.Error(t, err)
+			require.True(t, errors.Is(err, internal.ErrBadInput), err.Error())
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		now := time.Now()
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			shortenID := insertShorten(t, runner, "1")
+			id, err := repo.Persist(context.Background(), runner, Entity{
+				ShortenID: shortenID,
+				ClickedAt: now,
+				Referer:   "https://referer.com",
+				UserAgent: "curl/7.68.0",
+				IP:        "127.0.0.1",
+			})
+			require.NoError(t, err)
+
+			var actual Entity
+			var clickedAt int64
+			res := runner.QuerySingle(context.Background(), "SELECT shorten_id, clicked_at, referer, user_agent, ip FROM click WHERE id = $1", id)
+			require.NoError(t, res.Scan(&actual.ShortenID, &clickedAt, &actual.Referer, &actual.UserAgent, &actual.IP))
+			require.Equal(t, shortenID, actual.ShortenID)
+			require.Equal(t, now.Unix(), clickedAt)
+			require.Equal(t, "https://referer.com", actual.Referer)
+			require.Equal(t, "curl/7.68.0", actual.UserAgent)
+			require.Equal(t, "127.0.0.1", actual.IP)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
+
+func TestSQLLite_Stats(t *testing.T) {
+	db, cleanup := initDB(t, t.Name())
+	defer cleanup()
+
+	repo := Repo{}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	var shortenID, otherID int64
+	err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+		shortenID = insertShorten(t, runner, "1")
+		otherID = insertShorten(t, runner, "2")
+		for _, click := range []Entity{
+			{ShortenID: shortenID, ClickedAt: today.Add(-48 * time.Hour), Referer: "a", UserAgent: "x"},
+			{ShortenID: shortenID, ClickedAt: today.Add(time.Hour), Referer: "b", UserAgent: "x"},
+			{ShortenID: shortenID, ClickedAt: today.Add(2 * time.Hour), Referer: "b", UserAgent: "y"},
+			{ShortenID: shortenID, ClickedAt: today.Add(3 * time.Hour), UserAgent: "y"},
+			{ShortenID: otherID, ClickedAt: today.Add(time.Hour), Referer: "c", UserAgent: "z"},
+		} {
+			_, err := repo.Persist(context.Background(), runner, click)
+			require.NoError(t, err)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	t.Run("count", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			count, err := repo.Count(context.Background(), runner, shortenID)
+			require.NoError(t, err)
+			require.Equal(t, int64(4), count)
+
+			count, err = repo.Count(context.Background(), runner, 100)
+			require.NoError(t, err)
+			require.Equal(t, int64(0), count)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("daily", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			counters, err := repo.Daily(context.Background(), runner, shortenID, today.Add(-72*time.Hour))
+			require.NoError(t, err)
+			require.Equal(t, []DailyCounter{
+				{Day: today.Add(-48 * time.Hour), Count: 1},
+				{Day: today, Count: 3},
+			}, counters)
+
+			counters, err = repo.Daily(context.Background(), runner, shortenID, today)
+			require.NoError(t, err)
+			require.Equal(t, []DailyCounter{{Day: today, Count: 3}}, counters)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("top referers", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			counters, err := repo.TopReferers(context.Background(), runner, shortenID, 10)
+			require.NoError(t, err)
+			require.Equal(t, []Counter{{Value: "b", Count: 2}, {Value: "a", Count: 1}}, counters)
+
+			counters, err = repo.TopReferers(context.Background(), runner, shortenID, 1)
+			require.NoError(t, err)
+			require.Equal(t, []Counter{{Value: "b", Count: 2}}, counters)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("top user agents", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			counters, err := repo.TopUserAgents(context.Background(), runner, shortenID, 10)
+			require.NoError(t, err)
+			require.Equal(t, []Counter{{Value: "x", Count: 2}, {Value: "y", Count: 2}}, counters)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("removed with shorten", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			res := runner.Exec(context.Background(), "DELETE FROM shorten WHERE id = $1", otherID)
+			require.NoError(t, res.Err())
+
+			count, err := repo.Count(context.Background(), runner, otherID)
+			require.NoError(t, err)
+			require.Equal(t, int64(0), count)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
+
+func insertShorten(t *testing.T, runner storage.Runner, hash string) int64 {
+	var id int64
+	res := runner.QuerySingle(
+		context.Background(),
+		`INSERT INTO shorten(url, hash, created_at) VALUES ($1, $2, $3) RETURNING id`,
+		"https://example.com/"+hash, hash, time.Now().Unix(),
+	)
+	require.NoError(t, res.Scan(&id))
+	return id
+}
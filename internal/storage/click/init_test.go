@@ -0,0 +1,14 @@
+package click
+
+import (
+	"testing"
+
+	"github.com/pavelmemory/jobtome/internal/storage"
+	"github.com/pavelmemory/jobtome/internal/storage/storagetest"
+)
+
+func initDB(t *testing.T, name string) (storage.Pool, func()) {
+	t.Helper()
+
+	return storagetest.Open(t, name)
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func observedQueries(t *testing.T, operation string) uint64 {
+	var metric dto.Metric
+	require.NoError(t, queryDuration.WithLabelValues(operation).(prometheus.Histogram).Write(&metric))
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestPoolCollector(t *testing.T) {
+	db, err := NewSQLLite(filepath.Join(t.TempDir(), "pool.dat"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	before := observedQueries(t, operationExec)
+	require.NoError(t, db.WithoutTx(context.Background(), func(runner Runner) error {
+		return runner.Exec(context.Background(), `CREATE TABLE t(id INTEGER)`).Err()
+	}))
+	require.Equal(t, before+1, observedQueries(t, operationExec))
+
+	const exp = `
+		# HELP jobtome_storage_pool_max_open_connections Maximum number of open connections to the database.
+		# TYPE jobtome_storage_pool_max_open_connections gauge
+		jobtome_storage_pool_max_open_connections{driver="sqlite3"} 16
+		# HELP jobtome_storage_pool_in_use_connections Number of connections currently in use.
+		# TYPE jobtome_storage_pool_in_use_connections gauge
+		jobtome_storage_pool_in_use_connections{driver="sqlite3"} 0
+	`
+	err = testutil.CollectAndCompare(
+		NewPoolCollector(DriverSQLite, db),
+		strings.NewReader(exp),
+		"jobtome_storage_pool_max_open_connections", "jobtome_storage_pool_in_use_connections",
+	)
+	require.NoError(t, err)
+}
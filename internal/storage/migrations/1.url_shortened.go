@@ -1,25 +1,33 @@
 package migrations
 
-import (
-	"database/sql"
-)
-
-func UrlShortened(db *sql.DB) error {
-	tx, err := db.Begin()
-	if err != nil {
-		return err
-	}
-
-	_, err = tx.Exec(`
-		CREATE TABLE IF NOT EXISTS shorten (
-			id INTEGER PRIMARY KEY,
-			url TEXT NOT NULL CHECK(LENGTH(url) > 0),
-			hash TEXT NOT NULL CHECK(LENGTH(hash) > 0) UNIQUE,
-			created_at INTEGER NOT NULL
-		)`)
-	if err != nil {
-		return err
-	}
-
-	return tx.Commit()
+var urlShortened = Migration{
+	Version: 1,
+	Name:    "url_shortened",
+	Marker:  "shorten",
+	SQLite: Script{
+		Up: []string{
+			`CREATE TABLE shorten (
+				id INTEGER PRIMARY KEY,
+				url TEXT NOT NULL CHECK(LENGTH(url) > 0),
+				hash TEXT NOT NULL CHECK(LENGTH(hash) > 0) UNIQUE,
+				created_at INTEGER NOT NULL
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE shorten`,
+		},
+	},
+	Postgres: Script{
+		Up: []string{
+			`CREATE TABLE shorten (
+				id BIGSERIAL PRIMARY KEY,
+				url TEXT NOT NULL CHECK(LENGTH(url) > 0),
+				hash TEXT NOT NULL CHECK(LENGTH(hash) > 0) UNIQUE,
+				created_at BIGINT NOT NULL
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE shorten`,
+		},
+	},
 }
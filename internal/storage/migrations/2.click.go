@@ -0,0 +1,39 @@
+package migrations
+
+var click = Migration{
+	Version: 2,
+	Name:    "click",
+	Marker:  "click",
+	SQLite: Script{
+		Up: []string{
+			`CREATE TABLE click (
+				id INTEGER PRIMARY KEY,
+				shorten_id INTEGER NOT NULL REFERENCES shorten(id) ON DELETE CASCADE,
+				clicked_at INTEGER NOT NULL,
+				referer TEXT NOT NULL,
+				user_agent TEXT NOT NULL,
+				ip TEXT NOT NULL
+			)`,
+			`CREATE INDEX click_shorten_id_clicked_at ON click(shorten_id, clicked_at)`,
+		},
+		Down: []string{
+			`DROP TABLE click`,
+		},
+	},
+	Postgres: Script{
+		Up: []string{
+			`CREATE TABLE click (
+				id BIGSERIAL PRIMARY KEY,
+				shorten_id BIGINT NOT NULL REFERENCES shorten(id) ON DELETE CASCADE,
+				clicked_at BIGINT NOT NULL,
+				referer TEXT NOT NULL,
+				user_agent TEXT NOT NULL,
+				ip TEXT NOT NULL
+			)`,
+			`CREATE INDEX click_shorten_id_clicked_at ON click(shorten_id, clicked_at)`,
+		},
+		Down: []string{
+			`DROP TABLE click`,
+		},
+	},
+}
@@ -0,0 +1,57 @@
+package migrations
+
+var shortenExpiration = Migration{
+	Version: 3,
+	Name:    "shorten_expiration",
+	Marker:  "shorten_archive",
+	SQLite: Script{
+		Up: []string{
+			`ALTER TABLE shorten ADD COLUMN expires_at INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE shorten ADD COLUMN max_clicks INTEGER NOT NULL DEFAULT 0 CHECK(max_clicks >= 0)`,
+			`ALTER TABLE shorten ADD COLUMN clicks INTEGER NOT NULL DEFAULT 0`,
+			`CREATE INDEX shorten_expires_at ON shorten(expires_at) WHERE expires_at > 0`,
+			`CREATE TABLE shorten_archive (
+				id INTEGER PRIMARY KEY,
+				url TEXT NOT NULL,
+				hash TEXT NOT NULL,
+				created_at INTEGER NOT NULL,
+				expires_at INTEGER NOT NULL,
+				max_clicks INTEGER NOT NULL,
+				clicks INTEGER NOT NULL,
+				archived_at INTEGER NOT NULL
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE shorten_archive`,
+			`DROP INDEX shorten_expires_at`,
+			`ALTER TABLE shorten DROP COLUMN clicks`,
+			`ALTER TABLE shorten DROP COLUMN max_clicks`,
+			`ALTER TABLE shorten DROP COLUMN expires_at`,
+		},
+	},
+	Postgres: Script{
+		Up: []string{
+			`ALTER TABLE shorten ADD COLUMN expires_at BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE shorten ADD COLUMN max_clicks BIGINT NOT NULL DEFAULT 0 CHECK(max_clicks >= 0)`,
+			`ALTER TABLE shorten ADD COLUMN clicks BIGINT NOT NULL DEFAULT 0`,
+			`CREATE INDEX shorten_expires_at ON shorten(expires_at) WHERE expires_at > 0`,
+			`CREATE TABLE shorten_archive (
+				id BIGINT PRIMARY KEY,
+				url TEXT NOT NULL,
+				hash TEXT NOT NULL,
+				created_at BIGINT NOT NULL,
+				expires_at BIGINT NOT NULL,
+				max_clicks BIGINT NOT NULL,
+				clicks BIGINT NOT NULL,
+				archived_at BIGINT NOT NULL
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE shorten_archive`,
+			`DROP INDEX shorten_expires_at`,
+			`ALTER TABLE shorten DROP COLUMN clicks`,
+			`ALTER TABLE shorten DROP COLUMN max_clicks`,
+			`ALTER TABLE shorten DROP COLUMN expires_at`,
+		},
+	},
+}
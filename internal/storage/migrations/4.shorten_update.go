@@ -0,0 +1,41 @@
+package migrations
+
+var shortenUpdate = Migration{
+	Version: 4,
+	Name:    "shorten_update",
+	Marker:  "shorten_tag",
+	SQLite: Script{
+		Up: []string{
+			`ALTER TABLE shorten ADD COLUMN updated_at INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE shorten_archive ADD COLUMN updated_at INTEGER NOT NULL DEFAULT 0`,
+			`CREATE TABLE shorten_tag (
+				shorten_id INTEGER NOT NULL REFERENCES shorten(id) ON DELETE CASCADE,
+				tag TEXT NOT NULL,
+				PRIMARY KEY (shorten_id, tag)
+			)`,
+			`CREATE INDEX shorten_tag_tag ON shorten_tag(tag)`,
+		},
+		Down: []string{
+			`DROP TABLE shorten_tag`,
+			`ALTER TABLE shorten_archive DROP COLUMN updated_at`,
+			`ALTER TABLE shorten DROP COLUMN updated_at`,
+		},
+	},
+	Postgres: Script{
+		Up: []string{
+			`ALTER TABLE shorten ADD COLUMN updated_at BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE shorten_archive ADD COLUMN updated_at BIGINT NOT NULL DEFAULT 0`,
+			`CREATE TABLE shorten_tag (
+				shorten_id BIGINT NOT NULL REFERENCES shorten(id) ON DELETE CASCADE,
+				tag TEXT NOT NULL,
+				PRIMARY KEY (shorten_id, tag)
+			)`,
+			`CREATE INDEX shorten_tag_tag ON shorten_tag(tag)`,
+		},
+		Down: []string{
+			`DROP TABLE shorten_tag`,
+			`ALTER TABLE shorten_archive DROP COLUMN updated_at`,
+			`ALTER TABLE shorten DROP COLUMN updated_at`,
+		},
+	},
+}
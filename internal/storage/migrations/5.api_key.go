@@ -0,0 +1,36 @@
+package migrations
+
+var apiKey = Migration{
+	Version: 5,
+	Name:    "api_key",
+	SQLite: Script{
+		Up: []string{
+			`CREATE TABLE api_key (
+				id INTEGER PRIMARY KEY,
+				name TEXT NOT NULL,
+				key_hash TEXT NOT NULL UNIQUE,
+				scopes TEXT NOT NULL,
+				created_at INTEGER NOT NULL,
+				revoked_at INTEGER NOT NULL DEFAULT 0
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE api_key`,
+		},
+	},
+	Postgres: Script{
+		Up: []string{
+			`CREATE TABLE api_key (
+				id BIGSERIAL PRIMARY KEY,
+				name TEXT NOT NULL,
+				key_hash TEXT NOT NULL UNIQUE,
+				scopes TEXT NOT NULL,
+				created_at BIGINT NOT NULL,
+				revoked_at BIGINT NOT NULL DEFAULT 0
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE api_key`,
+		},
+	},
+}
@@ -0,0 +1,34 @@
+package migrations
+
+var workspace = Migration{
+	Version: 6,
+	Name:    "workspace",
+	SQLite: Script{
+		Up: []string{
+			`ALTER TABLE shorten ADD COLUMN workspace TEXT NOT NULL DEFAULT 'default'`,
+			`ALTER TABLE shorten_archive ADD COLUMN workspace TEXT NOT NULL DEFAULT 'default'`,
+			`CREATE INDEX shorten_workspace ON shorten(workspace, id)`,
+			`ALTER TABLE api_key ADD COLUMN workspace TEXT NOT NULL DEFAULT 'default'`,
+		},
+		Down: []string{
+			`ALTER TABLE api_key DROP COLUMN workspace`,
+			`DROP INDEX shorten_workspace`,
+			`ALTER TABLE shorten_archive DROP COLUMN workspace`,
+			`ALTER TABLE shorten DROP COLUMN workspace`,
+		},
+	},
+	Postgres: Script{
+		Up: []string{
+			`ALTER TABLE shorten ADD COLUMN workspace TEXT NOT NULL DEFAULT 'default'`,
+			`ALTER TABLE shorten_archive ADD COLUMN workspace TEXT NOT NULL DEFAULT 'default'`,
+			`CREATE INDEX shorten_workspace ON shorten(workspace, id)`,
+			`ALTER TABLE api_key ADD COLUMN workspace TEXT NOT NULL DEFAULT 'default'`,
+		},
+		Down: []string{
+			`ALTER TABLE api_key DROP COLUMN workspace`,
+			`DROP INDEX shorten_workspace`,
+			`ALTER TABLE shorten_archive DROP COLUMN workspace`,
+			`ALTER TABLE shorten DROP COLUMN workspace`,
+		},
+	},
+}
@@ -0,0 +1,30 @@
+package migrations
+
+var shortenSoftDelete = Migration{
+	Version: 7,
+	Name:    "shorten_soft_delete",
+	SQLite: Script{
+		Up: []string{
+			`ALTER TABLE shorten ADD COLUMN deleted_at INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE shorten_archive ADD COLUMN deleted_at INTEGER NOT NULL DEFAULT 0`,
+			`CREATE INDEX shorten_deleted_at ON shorten(deleted_at) WHERE deleted_at > 0`,
+		},
+		Down: []string{
+			`DROP INDEX shorten_deleted_at`,
+			`ALTER TABLE shorten_archive DROP COLUMN deleted_at`,
+			`ALTER TABLE shorten DROP COLUMN deleted_at`,
+		},
+	},
+	Postgres: Script{
+		Up: []string{
+			`ALTER TABLE shorten ADD COLUMN deleted_at BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE shorten_archive ADD COLUMN deleted_at BIGINT NOT NULL DEFAULT 0`,
+			`CREATE INDEX shorten_deleted_at ON shorten(deleted_at) WHERE deleted_at > 0`,
+		},
+		Down: []string{
+			`DROP INDEX shorten_deleted_at`,
+			`ALTER TABLE shorten_archive DROP COLUMN deleted_at`,
+			`ALTER TABLE shorten DROP COLUMN deleted_at`,
+		},
+	},
+}
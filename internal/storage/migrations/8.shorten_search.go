@@ -0,0 +1,58 @@
+package migrations
+
+// shortenSearch adds the columns the shortens are filtered by and the `shorten_search` relation
+// that finds the shortens by a part of the URL with `SELECT rowid FROM shorten_search WHERE url LIKE '%part%'`.
+// The pattern must be lower-cased. In SQLite the relation scans the URLs, it is switched to the FTS5 index
+// after the migrations if SQLite supports it, see `syncSearch`.
+var shortenSearch = Migration{
+	Version: 8,
+	Name:    "shorten_search",
+	SQLite: Script{
+		Up: []string{
+			`ALTER TABLE shorten ADD COLUMN domain TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE shorten ADD COLUMN owner TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE shorten_archive ADD COLUMN owner TEXT NOT NULL DEFAULT ''`,
+			// the host is cut at the first of the delimiters following it
+			`UPDATE shorten SET domain = h.domain
+			FROM (
+				SELECT id, lower(substr(h3, 1, instr(h3 || ':', ':') - 1)) AS domain
+				FROM (SELECT id, substr(h2, 1, instr(h2 || '#', '#') - 1) AS h3
+				FROM (SELECT id, substr(h1, 1, instr(h1 || '?', '?') - 1) AS h2
+				FROM (SELECT id, substr(rest, 1, instr(rest || '/', '/') - 1) AS h1
+				FROM (SELECT id, substr(url, instr(url, '://') + 3) AS rest FROM shorten))))
+			) AS h
+			WHERE shorten.id = h.id`,
+			`CREATE INDEX shorten_domain ON shorten(workspace, domain)`,
+			`CREATE INDEX shorten_owner ON shorten(workspace, owner)`,
+			sqliteSearchScan,
+		},
+		Down: []string{
+			`DROP VIEW shorten_search`,
+			`DROP INDEX shorten_owner`,
+			`DROP INDEX shorten_domain`,
+			`ALTER TABLE shorten_archive DROP COLUMN owner`,
+			`ALTER TABLE shorten DROP COLUMN owner`,
+			`ALTER TABLE shorten DROP COLUMN domain`,
+		},
+	},
+	Postgres: Script{
+		Up: []string{
+			`ALTER TABLE shorten ADD COLUMN domain TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE shorten ADD COLUMN owner TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE shorten_archive ADD COLUMN owner TEXT NOT NULL DEFAULT ''`,
+			`UPDATE shorten SET domain = COALESCE(lower(substring(url from '^[^:/]+://([^/?#:]+)')), '')`,
+			`CREATE INDEX shorten_domain ON shorten(workspace, domain)`,
+			`CREATE INDEX shorten_owner ON shorten(workspace, owner)`,
+			// the URLs are scanned, there is no index that could be created without an extension
+			`CREATE VIEW shorten_search AS SELECT id AS rowid, lower(url) AS url FROM shorten`,
+		},
+		Down: []string{
+			`DROP VIEW shorten_search`,
+			`DROP INDEX shorten_owner`,
+			`DROP INDEX shorten_domain`,
+			`ALTER TABLE shorten_archive DROP COLUMN owner`,
+			`ALTER TABLE shorten DROP COLUMN owner`,
+			`ALTER TABLE shorten DROP COLUMN domain`,
+		},
+	},
+}
@@ -0,0 +1,50 @@
+package migrations
+
+var archiveDetails = Migration{
+	Version: 9,
+	Name:    "archive_details",
+	SQLite: Script{
+		Up: []string{
+			`CREATE TABLE shorten_tag_archive (
+				shorten_id INTEGER NOT NULL REFERENCES shorten_archive(id) ON DELETE CASCADE,
+				tag TEXT NOT NULL,
+				PRIMARY KEY (shorten_id, tag)
+			)`,
+			`CREATE TABLE click_archive (
+				id INTEGER PRIMARY KEY,
+				shorten_id INTEGER NOT NULL REFERENCES shorten_archive(id) ON DELETE CASCADE,
+				clicked_at INTEGER NOT NULL,
+				referer TEXT NOT NULL,
+				user_agent TEXT NOT NULL,
+				ip TEXT NOT NULL
+			)`,
+			`CREATE INDEX click_archive_shorten_id ON click_archive(shorten_id)`,
+		},
+		Down: []string{
+			`DROP TABLE click_archive`,
+			`DROP TABLE shorten_tag_archive`,
+		},
+	},
+	Postgres: Script{
+		Up: []string{
+			`CREATE TABLE shorten_tag_archive (
+				shorten_id BIGINT NOT NULL REFERENCES shorten_archive(id) ON DELETE CASCADE,
+				tag TEXT NOT NULL,
+				PRIMARY KEY (shorten_id, tag)
+			)`,
+			`CREATE TABLE click_archive (
+				id BIGINT PRIMARY KEY,
+				shorten_id BIGINT NOT NULL REFERENCES shorten_archive(id) ON DELETE CASCADE,
+				clicked_at BIGINT NOT NULL,
+				referer TEXT NOT NULL,
+				user_agent TEXT NOT NULL,
+				ip TEXT NOT NULL
+			)`,
+			`CREATE INDEX click_archive_shorten_id ON click_archive(shorten_id)`,
+		},
+		Down: []string{
+			`DROP TABLE click_archive`,
+			`DROP TABLE shorten_tag_archive`,
+		},
+	},
+}
@@ -0,0 +1,372 @@
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/pavelmemory/jobtome/internal/storage"
+)
+
+var (
+	// ErrDatabaseNewer shows that the database has migrations applied that are unknown to this binary.
+	ErrDatabaseNewer = errors.New("database is newer than supported")
+	// ErrChecksumMismatch shows that an applied migration was changed after it had been applied.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+)
+
+// Migration is a single versioned change of the database schema.
+type Migration struct {
+	Version int
+	Name    string
+	// Marker is a table created by the migration. It is used to adopt the databases
+	// migrated before the versioning was introduced, new migrations don't need it.
+	Marker   string
+	SQLite   Script
+	Postgres Script
+}
+
+// Script is a set of statements for a single driver.
+type Script struct {
+	Up   []string
+	Down []string
+}
+
+// checksum returns a digest of the `Up` statements, so the changes of the applied migrations could be detected.
+func (s Script) checksum() string {
+	digest := sha256.Sum256([]byte(strings.Join(s.Up, "\n;\n")))
+	return hex.EncodeToString(digest[:])
+}
+
+// Status describes the state of a single migration.
+type Status struct {
+	Version int
+	Name    string
+	// AppliedAt is a moment the migration was applied, zero value means it is pending.
+	AppliedAt time.Time
+}
+
+// dialect holds driver specific statements used by the Migrator.
+type dialect struct {
+	tableExists string
+	lock        string
+	unlock      string
+}
+
+var dialects = map[string]dialect{
+	storage.DriverSQLite: {
+		tableExists: `SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = $1`,
+	},
+	storage.DriverPostgres: {
+		tableExists: `SELECT COUNT(*) > 0 FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = $1`,
+		// prevents concurrent migration by several replicas starting at the same time
+		lock:   `SELECT pg_advisory_lock(4242)`,
+		unlock: `SELECT pg_advisory_unlock(4242)`,
+	},
+}
+
+// NewMigrator returns a Migrator of the database located by the `dsn`.
+// The Migrator owns the connection and must be closed once it is not needed anymore.
+func NewMigrator(driver, dsn string) (*Migrator, error) {
+	d, ok := dialects[driver]
+	if !ok {
+		return nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open connection: %w", err)
+	}
+
+	return &Migrator{db: db, driver: driver, dialect: d, migrations: all}, nil
+}
+
+// Migrator applies and reverts versioned migrations, each of them inside of its own transaction.
+// Applied versions are recorded in `schema_migrations` table.
+type Migrator struct {
+	db         *sql.DB
+	driver     string
+	dialect    dialect
+	migrations []Migration
+}
+
+// Close closes the connection to the database.
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// Up applies all pending migrations and returns their amount.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	var applied int
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		records, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := records[migration.Version]; ok {
+				continue
+			}
+
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return fmt.Errorf("apply migration %d %s: %w", migration.Version, migration.Name, err)
+			}
+			applied++
+		}
+
+		return m.syncSearch(ctx, conn)
+	})
+
+	return applied, err
+}
+
+// Down reverts up to `steps` the most recently applied migrations and returns their amount.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	var reverted int
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		records, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		if err := m.dropSearch(ctx, conn); err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && reverted < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := records[migration.Version]; !ok {
+				continue
+			}
+
+			if err := m.revert(ctx, conn, migration); err != nil {
+				return fmt.Errorf("revert migration %d %s: %w", migration.Version, migration.Name, err)
+			}
+			reverted++
+		}
+
+		return m.syncSearch(ctx, conn)
+	})
+
+	return reverted, err
+}
+
+// Status returns states of all known migrations in order of their versions.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		records, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			statuses = append(statuses, Status{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: records[migration.Version].appliedAt,
+			})
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+// locked executes the `action` on a single connection holding the migration lock.
+func (m *Migrator) locked(ctx context.Context, action func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	if m.dialect.lock != "" {
+		if _, err := conn.ExecContext(ctx, m.dialect.lock); err != nil {
+			return fmt.Errorf("acquire lock: %w", err)
+		}
+		defer func() {
+			_, _ = conn.ExecContext(context.Background(), m.dialect.unlock)
+		}()
+	}
+
+	return action(conn)
+}
+
+type record struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+// applied returns records of the applied migrations by their versions.
+// It fails if there are unknown or changed migrations among them.
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int]record, error) {
+	if err := m.init(ctx, conn); err != nil {
+		return nil, fmt.Errorf("init schema_migrations: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("retrieve applied: %w", err)
+	}
+	defer rows.Close()
+
+	records := make(map[int]record)
+	for rows.Next() {
+		var version int
+		var rec record
+		var appliedAt int64
+		if err := rows.Scan(&version, &rec.checksum, &appliedAt); err != nil {
+			return nil, fmt.Errorf("scan applied: %w", err)
+		}
+		rec.appliedAt = time.Unix(appliedAt, 0)
+		records[version] = rec
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("retrieve applied: %w", err)
+	}
+
+	known := make(map[int]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	versions := make([]int, 0, len(records))
+	for version := range records {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+
+	for _, version := range versions {
+		migration, ok := known[version]
+		if !ok {
+			return nil, fmt.Errorf("migration %d: %w", version, ErrDatabaseNewer)
+		}
+
+		if records[version].checksum != m.script(migration).checksum() {
+			return nil, fmt.Errorf("migration %d %s: %w", version, migration.Name, ErrChecksumMismatch)
+		}
+	}
+
+	return records, nil
+}
+
+// init creates `schema_migrations` table if it doesn't exist yet.
+// The migrations applied before the versioning was introduced are recorded as applied.
+func (m *Migrator) init(ctx context.Context, conn *sql.Conn) error {
+	exists, err := m.tableExists(ctx, conn, "schema_migrations")
+	if err != nil || exists {
+		return err
+	}
+
+	adopted := 0
+	for i, migration := range m.migrations {
+		if migration.Marker == "" {
+			break
+		}
+
+		exists, err := m.tableExists(ctx, conn, migration.Marker)
+		if err != nil {
+			return err
+		}
+		if exists {
+			adopted = i + 1
+		}
+	}
+
+	return m.inTx(ctx, conn, func(tx *sql.Tx) error {
+		const query = `
+			CREATE TABLE schema_migrations (
+				version INTEGER PRIMARY KEY,
+				name TEXT NOT NULL,
+				checksum TEXT NOT NULL,
+				applied_at BIGINT NOT NULL
+			)`
+
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations[:adopted] {
+			if err := m.record(ctx, tx, migration); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	return m.inTx(ctx, conn, func(tx *sql.Tx) error {
+		for _, stmt := range m.script(migration).Up {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+
+		return m.record(ctx, tx, migration)
+	})
+}
+
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	return m.inTx(ctx, conn, func(tx *sql.Tx) error {
+		for _, stmt := range m.script(migration).Down {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+		return err
+	})
+}
+
+func (m *Migrator) record(ctx context.Context, tx *sql.Tx, migration Migration) error {
+	const query = `INSERT INTO schema_migrations(version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)`
+
+	_, err := tx.ExecContext(ctx, query, migration.Version, migration.Name, m.script(migration).checksum(), time.Now().Unix())
+	return err
+}
+
+func (m *Migrator) tableExists(ctx context.Context, conn *sql.Conn, table string) (bool, error) {
+	var exists bool
+	if err := conn.QueryRowContext(ctx, m.dialect.tableExists, table).Scan(&exists); err != nil {
+		return false, fmt.Errorf("check table %q exists: %w", table, err)
+	}
+
+	return exists, nil
+}
+
+func (m *Migrator) inTx(ctx context.Context, conn *sql.Conn, action func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := action(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *Migrator) script(migration Migration) Script {
+	if m.driver == storage.DriverPostgres {
+		return migration.Postgres
+	}
+
+	return migration.SQLite
+}
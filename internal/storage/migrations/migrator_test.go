@@ -0,0 +1,191 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pavelmemory/jobtome/internal/storage"
+)
+
+func TestMigrator(t *testing.T) {
+	migrator, cleanup := initMigrator(t, t.Name())
+	defer cleanup()
+
+	ctx := context.Background()
+
+	t.Run("up", func(t *testing.T) {
+		applied, err := migrator.Up(ctx)
+		require.NoError(t, err)
+		require.Equal(t, len(all), applied)
+
+		applied, err = migrator.Up(ctx)
+		require.NoError(t, err)
+		require.Zero(t, applied)
+
+		statuses, err := migrator.Status(ctx)
+		require.NoError(t, err)
+		require.Len(t, statuses, len(all))
+		for i, status := range statuses {
+			require.Equal(t, all[i].Version, status.Version)
+			require.Equal(t, all[i].Name, status.Name)
+			require.False(t, status.AppliedAt.IsZero())
+		}
+	})
+
+	t.Run("down", func(t *testing.T) {
+		reverted, err := migrator.Down(ctx, 2)
+		require.NoError(t, err)
+		require.Equal(t, 2, reverted)
+
+		statuses, err := migrator.Status(ctx)
+		require.NoError(t, err)
+		require.False(t, statuses[len(all)-3].AppliedAt.IsZero())
+		require.True(t, statuses[len(all)-2].AppliedAt.IsZero())
+		require.True(t, statuses[len(all)-1].AppliedAt.IsZero())
+
+		applied, err := migrator.Up(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 2, applied)
+	})
+
+	t.Run("down all", func(t *testing.T) {
+		reverted, err := migrator.Down(ctx, len(all)+1)
+		require.NoError(t, err)
+		require.Equal(t, len(all), reverted)
+
+		_, err = migrator.db.Exec(`SELECT 1 FROM shorten`)
+		require.Error(t, err)
+
+		applied, err := migrator.Up(ctx)
+		require.NoError(t, err)
+		require.Equal(t, len(all), applied)
+	})
+
+	t.Run("database is newer", func(t *testing.T) {
+		_, err := migrator.db.Exec(`INSERT INTO schema_migrations(version, name, checksum, applied_at) VALUES (1000, 'future', '', 0)`)
+		require.NoError(t, err)
+		defer func() {
+			_, err := migrator.db.Exec(`DELETE FROM schema_migrations WHERE version = 1000`)
+			require.NoError(t, err)
+		}()
+
+		_, err = migrator.Up(ctx)
+		require.True(t, errors.Is(err, ErrDatabaseNewer), err)
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		_, err := migrator.db.Exec(`UPDATE schema_migrations SET checksum = 'changed' WHERE version = 1`)
+		require.NoError(t, err)
+
+		_, err = migrator.Up(ctx)
+		require.True(t, errors.Is(err, ErrChecksumMismatch), err)
+	})
+}
+
+func TestMigrator_adoption(t *testing.T) {
+	migrator, cleanup := initMigrator(t, t.Name())
+	defer cleanup()
+
+	// the database migrated before the versioning was introduced
+	for _, migration := range all[:2] {
+		for _, stmt := range migration.SQLite.Up {
+			_, err := migrator.db.Exec(stmt)
+			require.NoError(t, err)
+		}
+	}
+
+	applied, err := migrator.Up(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, len(all)-2, applied)
+}
+
+func TestMigrator_search(t *testing.T) {
+	migrator, cleanup := initMigrator(t, t.Name())
+	defer cleanup()
+
+	ctx := context.Background()
+	_, err := migrator.Up(ctx)
+	require.NoError(t, err)
+
+	var indexed, fts5 bool
+	const query = `
+		SELECT
+			EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'trigger' AND name = 'shorten_fts_insert'),
+			sqlite_compileoption_used('ENABLE_FTS5')`
+	require.NoError(t, migrator.db.QueryRow(query).Scan(&indexed, &fts5))
+	require.Equal(t, fts5, indexed)
+
+	insert := func(url string) {
+		_, err := migrator.db.Exec(`INSERT INTO shorten(url, hash, created_at) VALUES ($1, $1, 0)`, url)
+		require.NoError(t, err)
+	}
+
+	urls := []string{"https://example.com/summer"}
+	insert(urls[0])
+	if fts5 {
+		// the binary built without FTS5 switches to the scan, so its shortens aren't indexed until the index is rebuilt
+		for _, stmt := range sqliteSearchUnindex {
+			_, err := migrator.db.Exec(stmt)
+			require.NoError(t, err)
+		}
+		urls = append(urls, "https://example.com/summer-sale")
+		insert(urls[1])
+	}
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	require.Zero(t, applied)
+
+	var found int
+	require.NoError(t, migrator.db.QueryRow(`SELECT COUNT(*) FROM shorten_search WHERE url LIKE '%summer%'`).Scan(&found))
+	require.Equal(t, len(urls), found)
+}
+
+func TestUp(t *testing.T) {
+	const filepath = "TestUp"
+	require.NoError(t, os.RemoveAll(filepath))
+	defer os.RemoveAll(filepath)
+
+	require.NoError(t, Up(storage.DriverSQLite, filepath))
+	require.NoError(t, Up(storage.DriverSQLite, filepath))
+
+	db, err := sql.Open(storage.DriverSQLite, filepath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&count))
+	require.Equal(t, len(all), count)
+
+	pool, err := storage.Open(storage.DriverSQLite, filepath)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	require.NoError(t, pool.WithoutTx(context.Background(), func(runner storage.Runner) error {
+		current, err := Current(context.Background(), runner)
+		require.NoError(t, err)
+		require.Equal(t, Latest(), current)
+		return nil
+	}))
+}
+
+func initMigrator(t *testing.T, filepath string) (*Migrator, func()) {
+	t.Helper()
+
+	require.NoError(t, os.RemoveAll(filepath))
+
+	migrator, err := NewMigrator(storage.DriverSQLite, filepath)
+	require.NoError(t, err)
+
+	cleanup := func() {
+		require.NoError(t, migrator.Close())
+		require.NoError(t, os.RemoveAll(filepath))
+	}
+
+	return migrator, cleanup
+}
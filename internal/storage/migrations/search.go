@@ -0,0 +1,117 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pavelmemory/jobtome/internal/storage"
+)
+
+// sqliteSearchScan is the `shorten_search` relation that scans the URLs.
+const sqliteSearchScan = `CREATE VIEW shorten_search(rowid, url) AS SELECT id, url FROM shorten`
+
+// sqliteSearchIndex replaces the `shorten_search` relation with the FTS5 index of the URLs kept in sync
+// with the `shorten` table by the triggers. The trigram tokenizer lets `LIKE` patterns of 3 and more characters
+// be served by the index.
+var sqliteSearchIndex = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS shorten_fts USING fts5(url, content='shorten', content_rowid='id', tokenize='trigram')`,
+	`INSERT INTO shorten_fts(shorten_fts) VALUES ('rebuild')`,
+	`CREATE TRIGGER shorten_fts_insert AFTER INSERT ON shorten BEGIN
+		INSERT INTO shorten_fts(rowid, url) VALUES (new.id, new.url);
+	END`,
+	`CREATE TRIGGER shorten_fts_delete AFTER DELETE ON shorten BEGIN
+		INSERT INTO shorten_fts(shorten_fts, rowid, url) VALUES ('delete', old.id, old.url);
+	END`,
+	`CREATE TRIGGER shorten_fts_update AFTER UPDATE OF url ON shorten BEGIN
+		INSERT INTO shorten_fts(shorten_fts, rowid, url) VALUES ('delete', old.id, old.url);
+		INSERT INTO shorten_fts(rowid, url) VALUES (new.id, new.url);
+	END`,
+	`DROP VIEW shorten_search`,
+	`CREATE VIEW shorten_search(rowid, url) AS SELECT rowid, url FROM shorten_fts`,
+}
+
+// sqliteSearchUnindex returns the `shorten_search` relation to the scan of the URLs.
+// The index itself is left in place if it can't be dropped without FTS5, it is rebuilt once FTS5 is back.
+var sqliteSearchUnindex = []string{
+	`DROP TRIGGER shorten_fts_update`,
+	`DROP TRIGGER shorten_fts_delete`,
+	`DROP TRIGGER shorten_fts_insert`,
+	`DROP VIEW shorten_search`,
+	sqliteSearchScan,
+}
+
+// syncSearch switches the SQLite `shorten_search` relation to the FTS5 index if FTS5 is compiled into SQLite
+// (the `sqlite_fts5` build tag) and back to the scan otherwise. It is not a part of the migrations,
+// so the same database could be used by the binaries built with and without the tag, though not at the same time:
+// the index is rebuilt each time it is enabled, as the changes made without it aren't indexed.
+func (m *Migrator) syncSearch(ctx context.Context, conn *sql.Conn) error {
+	if m.driver != storage.DriverSQLite {
+		return nil
+	}
+
+	var searchable, indexed, fts5 bool
+	const query = `
+		SELECT
+			EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'view' AND name = 'shorten_search'),
+			EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'trigger' AND name = 'shorten_fts_insert'),
+			sqlite_compileoption_used('ENABLE_FTS5')`
+	if err := conn.QueryRowContext(ctx, query).Scan(&searchable, &indexed, &fts5); err != nil {
+		return fmt.Errorf("check search index: %w", err)
+	}
+
+	var stmts []string
+	switch {
+	case searchable && fts5 && !indexed:
+		stmts = sqliteSearchIndex
+	case searchable && !fts5 && indexed:
+		stmts = sqliteSearchUnindex
+	default:
+		return nil
+	}
+
+	return m.inTx(ctx, conn, func(tx *sql.Tx) error {
+		for _, stmt := range stmts {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("sync search index: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// dropSearch returns the SQLite `shorten_search` relation to the scan and drops the FTS5 index,
+// so the migrations could be reverted.
+func (m *Migrator) dropSearch(ctx context.Context, conn *sql.Conn) error {
+	if m.driver != storage.DriverSQLite {
+		return nil
+	}
+
+	var indexed, fts5 bool
+	const query = `
+		SELECT
+			EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'trigger' AND name = 'shorten_fts_insert'),
+			sqlite_compileoption_used('ENABLE_FTS5')`
+	if err := conn.QueryRowContext(ctx, query).Scan(&indexed, &fts5); err != nil {
+		return fmt.Errorf("check search index: %w", err)
+	}
+
+	return m.inTx(ctx, conn, func(tx *sql.Tx) error {
+		var stmts []string
+		if indexed {
+			stmts = append(stmts, sqliteSearchUnindex...)
+		}
+		if fts5 {
+			stmts = append(stmts, `DROP TABLE IF EXISTS shorten_fts`)
+		}
+
+		for _, stmt := range stmts {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("drop search index: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
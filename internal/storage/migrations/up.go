@@ -1,24 +1,51 @@
 package migrations
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/pavelmemory/jobtome/internal/storage"
 )
 
-func Up(filepath string) error {
-	db, err := sql.Open("sqlite3", filepath)
+// all is an ordered list of all known migrations, a new migration must be appended to the end.
+var all = []Migration{
+	urlShortened,
+	click,
+	shortenExpiration,
+	shortenUpdate,
+	apiKey,
+	workspace,
+	shortenSoftDelete,
+	shortenSearch,
+	archiveDetails,
+}
+
+// Latest returns the version of the most recent known migration,
+// the database is up to date if it is the most recent applied one.
+func Latest() int {
+	return all[len(all)-1].Version
+}
+
+// Current returns the version of the most recent migration applied to the database.
+func Current(ctx context.Context, run storage.Runner) (int, error) {
+	var version int
+	if err := run.QuerySingle(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("retrieve current version: %w", storage.ConvertError(err))
+	}
+
+	return version, nil
+}
+
+// Up applies all pending migrations of the `driver` to the database located by the `dsn`.
+func Up(driver, dsn string) error {
+	migrator, err := NewMigrator(driver, dsn)
 	if err != nil {
-		return fmt.Errorf("open connection: %w", err)
+		return err
 	}
+	defer migrator.Close()
 
-	for i, migration := range []func(*sql.DB) error{
-		UrlShortened,
-	} {
-		if err := migration(db); err != nil {
-			return fmt.Errorf("apply migration %d: %w", i+1, err)
-		}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		return fmt.Errorf("migrate up: %w", err)
 	}
 
 	return nil
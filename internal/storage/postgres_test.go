@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pavelmemory/jobtome/internal"
+)
+
+func TestConvertError_postgres(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err error
+		exp error
+	}{
+		"no rows":          {err: sql.ErrNoRows, exp: internal.ErrNotFound},
+		"unique violation": {err: &pq.Error{Code: "23505"}, exp: internal.ErrNotUnique},
+		"foreign key":      {err: &pq.Error{Code: "23503"}, exp: internal.ErrBadInput},
+		"check violation":  {err: &pq.Error{Code: "23514"}, exp: internal.ErrBadInput},
+		"out of range":     {err: &pq.Error{Code: "22003"}, exp: internal.ErrBadInput},
+		"wrapped":          {err: fmt.Errorf("exec: %w", &pq.Error{Code: "23505"}), exp: internal.ErrNotUnique},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := ConvertError(tc.err)
+			require.True(t, errors.Is(err, tc.exp), err)
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		original := &pq.Error{Code: "57014"} // query canceled
+		err := ConvertError(original)
+		require.Equal(t, original, err)
+	})
+}
@@ -0,0 +1,165 @@
+package shorten
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Statuses of the shortens, see `Filter`.
+const (
+	// StatusActive matches the shortens that redirect.
+	StatusActive = "active"
+	// StatusExpired matches the shortens that passed their expiration moment.
+	StatusExpired = "expired"
+	// StatusDisabled matches the not expired shortens that have no redirects left.
+	StatusDisabled = "disabled"
+)
+
+// Orders of the shortens, see `Pager`. The shortens with equal sort keys are ordered by ID in the same direction.
+const (
+	SortCreatedAt     = "created_at"
+	SortCreatedAtDesc = "-created_at"
+	SortClicks        = "clicks"
+	SortClicksDesc    = "-clicks"
+)
+
+// Filter narrows the listed shortens, the zero value of each field matches all shortens.
+type Filter struct {
+	// Domain matches the shortens redirecting to the host equal to it or to its subdomain, it is matched literally.
+	Domain string
+	// URL matches the shortens with the URL containing it literally, the case is ignored.
+	URL string
+	// CreatedFrom matches the shortens created at or after it.
+	CreatedFrom time.Time
+	// CreatedTo matches the shortens created before it.
+	CreatedTo time.Time
+	// Tag matches the shortens labeled with it.
+	Tag string
+	// Owner matches the shortens created by the owner, see `Entity`.
+	Owner string
+	// Status is one of `StatusActive`, `StatusExpired` or `StatusDisabled`.
+	Status string
+}
+
+// likeEscaper escapes the wildcards of the `LIKE` pattern, so they are matched literally with `ESCAPE '\'`.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// sortKey returns the column the shortens are ordered by and the direction of the order.
+func sortKey(sort string) (string, string) {
+	switch sort {
+	case SortCreatedAt, SortClicks:
+		return sort, "ASC"
+	case SortCreatedAtDesc, SortClicksDesc:
+		return strings.TrimPrefix(sort, "-"), "DESC"
+	default:
+		return "", "ASC"
+	}
+}
+
+// listQuery returns the query of the page of not deleted shortens of the `workspace` matched by the `filter`.
+func listQuery(workspace string, filter Filter, pager Pager, now time.Time) (string, []interface{}) {
+	var q conditions
+	q.add("workspace = %s", workspace)
+	q.add("deleted_at = 0")
+
+	if filter.Domain != "" {
+		domain := strings.ToLower(filter.Domain)
+		q.add(`(domain = %s OR domain LIKE %s ESCAPE '\')`, domain, "%."+likeEscaper.Replace(domain))
+	}
+	if filter.URL != "" {
+		literal := strings.ToLower(filter.URL)
+		pattern := likeEscaper.Replace(literal)
+		if pattern == literal {
+			// the trigram index isn't used for the patterns with `ESCAPE`, so it is added only when needed
+			q.add("id IN (SELECT rowid FROM shorten_search WHERE url LIKE %s)", "%"+pattern+"%")
+		} else {
+			q.add(`id IN (SELECT rowid FROM shorten_search WHERE url LIKE %s ESCAPE '\')`, "%"+pattern+"%")
+		}
+	}
+	if !filter.CreatedFrom.IsZero() {
+		q.add("created_at >= %s", filter.CreatedFrom.Unix())
+	}
+	if !filter.CreatedTo.IsZero() {
+		q.add("created_at < %s", filter.CreatedTo.Unix())
+	}
+	if filter.Tag != "" {
+		q.add("id IN (SELECT shorten_id FROM shorten_tag WHERE tag = %s)", filter.Tag)
+	}
+	if filter.Owner != "" {
+		q.add("owner = %s", filter.Owner)
+	}
+
+	const exhausted = "max_clicks > 0 AND clicks >= max_clicks"
+	switch filter.Status {
+	case StatusActive:
+		q.add("(expires_at = 0 OR expires_at > %s) AND NOT ("+exhausted+")", now.Unix())
+	case StatusExpired:
+		q.add("expires_at > 0 AND expires_at <= %s", now.Unix())
+	case StatusDisabled:
+		q.add("(expires_at = 0 OR expires_at > %s) AND "+exhausted, now.Unix())
+	}
+
+	column, direction := sortKey(pager.Sort)
+	op := ">"
+	if direction == "DESC" {
+		op = "<"
+	}
+
+	order := "id " + direction
+	if column != "" {
+		order = column + " " + direction + ", " + order
+	}
+
+	if pager.AfterID > 0 {
+		if column == "" {
+			q.add("id "+op+" %s", pager.AfterID)
+		} else {
+			q.add("("+column+" "+op+" %s OR ("+column+" = %s AND id "+op+" %s))", pager.AfterKey, pager.AfterKey, pager.AfterID)
+		}
+	}
+
+	query := `
+		SELECT ` + columns + `
+		FROM shorten
+		WHERE ` + strings.Join(q.clauses, " AND ") + `
+		ORDER BY ` + order + `
+		LIMIT ` + q.arg(pager.Limit) + `
+		OFFSET ` + q.arg(pager.Offset)
+
+	return query, q.args
+}
+
+// conditions collects the clauses of the `WHERE` and their positional arguments.
+type conditions struct {
+	clauses []string
+	args    []interface{}
+}
+
+// add appends the `clause` with each `%s` replaced by a placeholder of the corresponding `args`.
+func (c *conditions) add(clause string, args ...interface{}) {
+	placeholders := make([]interface{}, len(args))
+	for i, arg := range args {
+		placeholders[i] = c.arg(arg)
+	}
+
+	c.clauses = append(c.clauses, fmt.Sprintf(clause, placeholders...))
+}
+
+// arg appends the argument and returns its placeholder.
+func (c *conditions) arg(arg interface{}) string {
+	c.args = append(c.args, arg)
+	return "$" + strconv.Itoa(len(c.args))
+}
+
+// domainOf returns the lower-cased host of the `rawURL`, it is empty if the URL can't be parsed.
+func domainOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(u.Hostname())
+}
@@ -3,6 +3,8 @@ package shorten
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pavelmemory/jobtome/internal"
@@ -14,78 +16,150 @@ type Entity struct {
 	URL       string
 	Hash      string
 	CreatedAt time.Time
+	// Workspace is a tenant the shorten belongs to.
+	Workspace string
+	// ExpiresAt is a moment the shorten stops working, zero value means it never expires.
+	ExpiresAt time.Time
+	// MaxClicks is a number of redirects allowed for the shorten, 0 means it is unlimited.
+	MaxClicks int64
+	// Clicks is a number of redirects already made with the shorten.
+	Clicks int64
+	// UpdatedAt is a moment of the last update, zero value means the shorten was never updated.
+	UpdatedAt time.Time
+	// DeletedAt is a moment the shorten was moved to the trash, zero value means it is not deleted.
+	DeletedAt time.Time
+	// Owner is a name of the API key the shorten was created with, it is empty if the key was not used.
+	Owner string
 }
 
+// columns is a list of columns scanned by `scan`.
+// The `domain` column is not scanned, it is derived from the URL on each change.
+const columns = `id, url, hash, created_at, expires_at, max_clicks, clicks, updated_at, workspace, deleted_at, owner`
+
 type Repo struct{}
 
 func (p Repo) Persist(ctx context.Context, run storage.Runner, entry Entity) (int64, error) {
 	const query = `
-		INSERT INTO shorten(id, url, hash, created_at) 
-		VALUES (NULL, $1, $2, $3)`
+		INSERT INTO shorten(url, hash, created_at, expires_at, max_clicks, workspace, owner, domain)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id`
 
-	res := run.Exec(ctx, query, entry.URL, entry.Hash, time.Now().Unix())
-	if err := storage.ConvertError(res.Err()); err != nil {
+	var id int64
+	res := run.QuerySingle(ctx, query, entry.URL, entry.Hash, time.Now().Unix(), toUnix(entry.ExpiresAt), entry.MaxClicks,
+		entry.Workspace, entry.Owner, domainOf(entry.URL))
+	if err := storage.ConvertError(res.Scan(&id)); err != nil {
 		return 0, fmt.Errorf("exec: %w", err)
 	}
 
-	return res.ID(), nil
+	return id, nil
 }
 
 func (p Repo) Retrieve(ctx context.Context, run storage.Runner, id int64) (Entity, error) {
 	const query = `
-		SELECT url, hash, created_at
+		SELECT ` + columns + `
 		FROM shorten
 		WHERE id = $1`
 
-	entity := Entity{ID: id}
-	var createdAt int64
-
-	res := run.QuerySingle(ctx, query, id)
-	if err := storage.ConvertError(res.Scan(&entity.URL, &entity.Hash, &createdAt)); err != nil {
+	entity, err := scan(run.QuerySingle(ctx, query, id))
+	if err != nil {
 		return Entity{}, fmt.Errorf("retrieve single: %w", err)
 	}
-	entity.CreatedAt = time.Unix(createdAt, 0)
 
 	return entity, nil
 }
 
-// Pager is a lightweight paging abstraction that we could afford to use only with small databases like SQLLite.
+// Pager defines a page of the shortens ordered by ID or by the `Sort` key.
+// Skipping with the `Offset` gets slower with each page, so large tables should be walked with the `AfterID`.
 type Pager struct {
 	Limit  int64
 	Offset int64
+	// AfterID limits the page to the shortens following the shorten with the ID in the order,
+	// so the next page is located by the last shorten of the previous one (keyset pagination).
+	// Zero value means the first page.
+	AfterID int64
+	// Sort is one of `SortCreatedAt`, `SortCreatedAtDesc`, `SortClicks` or `SortClicksDesc`, empty means by ID.
+	Sort string
+	// AfterKey is a value of the `Sort` key of the shorten with the `AfterID`, it is ignored without the `Sort`.
+	AfterKey int64
 }
 
-func (Repo) List(ctx context.Context, run storage.Runner, pager Pager) ([]Entity, error) {
+// List returns a page of the shortens of the `workspace` matched by the `filter`, the deleted shortens are not included.
+func (Repo) List(ctx context.Context, run storage.Runner, workspace string, filter Filter, pager Pager) ([]Entity, error) {
+	query, args := listQuery(workspace, filter, pager, time.Now())
+	return list(ctx, run, query, args...)
+}
+
+// Trash returns a page of the deleted shortens of the `workspace`, the most recently deleted go first.
+// The `AfterID` and the `Sort` of the `pager` are not supported.
+func (Repo) Trash(ctx context.Context, run storage.Runner, workspace string, pager Pager) ([]Entity, error) {
 	const query = `
-		SELECT id, url, hash, created_at
+		SELECT ` + columns + `
 		FROM shorten
-		ORDER BY id
-		LIMIT $1 
-		OFFSET $2`
+		WHERE workspace = $1 AND deleted_at > 0
+		ORDER BY deleted_at DESC, id DESC
+		LIMIT $2
+		OFFSET $3`
+
+	return list(ctx, run, query, workspace, pager.Limit, pager.Offset)
+}
 
+// list returns the shortens selected by the `query` with `columns`.
+func list(ctx context.Context, run storage.Runner, query string, args ...interface{}) ([]Entity, error) {
 	var entities []Entity
 
-	res, err := run.Query(ctx, query, pager.Limit, pager.Offset)
+	res, err := run.Query(ctx, query, args...)
 	if err := storage.ConvertError(err); err != nil {
 		return nil, fmt.Errorf("retrieve multiple: %w", err)
 	}
 	defer res.Close() // TODO: proper handling of closing error
 
 	for res.Next() {
-		var entity Entity
-		var createdAt int64
-		err := res.Scan(&entity.ID, &entity.URL, &entity.Hash, &createdAt)
-		if err := storage.ConvertError(err); err != nil {
+		entity, err := scan(res)
+		if err != nil {
 			return nil, fmt.Errorf("scan retrieved: %w", err)
 		}
-		entity.CreatedAt = time.Unix(createdAt, 0)
 		entities = append(entities, entity)
 	}
 
 	return entities, nil
 }
 
+// Delete moves the shorten to the trash, its code stays taken until it is purged.
+// It returns `internal.ErrNotFound` if the shorten doesn't exist or it is already deleted.
 func (Repo) Delete(ctx context.Context, run storage.Runner, id int64) error {
+	const query = `UPDATE shorten SET deleted_at = $1 WHERE id = $2 AND deleted_at = 0`
+
+	res := run.Exec(ctx, query, time.Now().Unix(), id)
+	if err := storage.ConvertError(res.Err()); err != nil {
+		return fmt.Errorf("exec update: %w", err)
+	}
+
+	if res.Affected() == 1 {
+		return nil
+	}
+
+	return internal.ErrNotFound
+}
+
+// Restore returns the deleted shorten from the trash.
+// It returns `internal.ErrNotFound` if the shorten doesn't exist or it is not deleted.
+func (Repo) Restore(ctx context.Context, run storage.Runner, id int64) error {
+	const query = `UPDATE shorten SET deleted_at = 0 WHERE id = $1 AND deleted_at > 0`
+
+	res := run.Exec(ctx, query, id)
+	if err := storage.ConvertError(res.Err()); err != nil {
+		return fmt.Errorf("exec update: %w", err)
+	}
+
+	if res.Affected() == 1 {
+		return nil
+	}
+
+	return internal.ErrNotFound
+}
+
+// Purge permanently removes the shorten with its tags and clicks, deleted or not.
+func (Repo) Purge(ctx context.Context, run storage.Runner, id int64) error {
 	const query = `DELETE FROM shorten WHERE id = $1`
 
 	res := run.Exec(ctx, query, id)
@@ -100,20 +174,248 @@ func (Repo) Delete(ctx context.Context, run storage.Runner, id int64) error {
 	return internal.ErrNotFound
 }
 
+// PurgeDeleted permanently removes shortens deleted before `deadline` and returns their amount.
+func (Repo) PurgeDeleted(ctx context.Context, run storage.Runner, deadline time.Time) (int64, error) {
+	const query = `DELETE FROM shorten WHERE deleted_at > 0 AND deleted_at <= $1`
+
+	res := run.Exec(ctx, query, deadline.Unix())
+	if err := storage.ConvertError(res.Err()); err != nil {
+		return 0, fmt.Errorf("exec delete: %w", err)
+	}
+
+	return res.Affected(), nil
+}
+
+func (Repo) UpdateHash(ctx context.Context, run storage.Runner, id int64, hash string) error {
+	const query = `UPDATE shorten SET hash = $1 WHERE id = $2`
+
+	res := run.Exec(ctx, query, hash, id)
+	if err := storage.ConvertError(res.Err()); err != nil {
+		return fmt.Errorf("exec update: %w", err)
+	}
+
+	if res.Affected() == 1 {
+		return nil
+	}
+
+	return internal.ErrNotFound
+}
+
+// Update replaces the URL, hash and limits of the shorten and sets its `UpdatedAt` to the current time.
+func (Repo) Update(ctx context.Context, run storage.Runner, entity Entity) error {
+	const query = `
+		UPDATE shorten
+		SET url = $1, domain = $2, hash = $3, expires_at = $4, max_clicks = $5, updated_at = $6
+		WHERE id = $7`
+
+	res := run.Exec(ctx, query, entity.URL, domainOf(entity.URL), entity.Hash, toUnix(entity.ExpiresAt), entity.MaxClicks, time.Now().Unix(), entity.ID)
+	if err := storage.ConvertError(res.Err()); err != nil {
+		return fmt.Errorf("exec update: %w", err)
+	}
+
+	if res.Affected() == 1 {
+		return nil
+	}
+
+	return internal.ErrNotFound
+}
+
+// Tags returns tags of the shortens with the `ids` grouped by the shorten ID.
+// Shortens without tags are not included into the result.
+func (Repo) Tags(ctx context.Context, run storage.Runner, ids ...int64) (map[int64][]string, error) {
+	tags := make(map[int64][]string)
+	if len(ids) == 0 {
+		return tags, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+		args[i] = id
+	}
+
+	query := `
+		SELECT shorten_id, tag
+		FROM shorten_tag
+		WHERE shorten_id IN (` + strings.Join(placeholders, ", ") + `)
+		ORDER BY shorten_id, tag`
+
+	res, err := run.Query(ctx, query, args...)
+	if err := storage.ConvertError(err); err != nil {
+		return nil, fmt.Errorf("retrieve multiple: %w", err)
+	}
+	defer res.Close()
+
+	for res.Next() {
+		var id int64
+		var tag string
+		if err := storage.ConvertError(res.Scan(&id, &tag)); err != nil {
+			return nil, fmt.Errorf("scan retrieved: %w", err)
+		}
+		tags[id] = append(tags[id], tag)
+	}
+
+	return tags, nil
+}
+
+// SetTags replaces all tags of the shorten with the `tags`.
+// It must be called inside of the transaction.
+func (Repo) SetTags(ctx context.Context, run storage.Runner, id int64, tags []string) error {
+	res := run.Exec(ctx, `DELETE FROM shorten_tag WHERE shorten_id = $1`, id)
+	if err := storage.ConvertError(res.Err()); err != nil {
+		return fmt.Errorf("exec delete: %w", err)
+	}
+
+	for _, tag := range tags {
+		res := run.Exec(ctx, `INSERT INTO shorten_tag(shorten_id, tag) VALUES ($1, $2)`, id, tag)
+		if err := storage.ConvertError(res.Err()); err != nil {
+			return fmt.Errorf("exec insert: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ByHash returns the shorten with the `hash`, the deleted shortens are reported as not existing.
 func (Repo) ByHash(ctx context.Context, run storage.Runner, hash string) (Entity, error) {
 	const query = `
-		SELECT id, url, created_at
+		SELECT ` + columns + `
 		FROM shorten
-		WHERE hash = $1`
+		WHERE hash = $1 AND deleted_at = 0`
 
-	entity := Entity{Hash: hash}
-	var createdAt int64
-
-	res := run.QuerySingle(ctx, query, hash)
-	if err := storage.ConvertError(res.Scan(&entity.ID, &entity.URL, &createdAt)); err != nil {
+	entity, err := scan(run.QuerySingle(ctx, query, hash))
+	if err != nil {
 		return Entity{}, fmt.Errorf("retrieve single: %w", err)
 	}
+
+	return entity, nil
+}
+
+// HashTaken reports if the `hash` is used by any shorten including the deleted ones.
+func (Repo) HashTaken(ctx context.Context, run storage.Runner, hash string) (bool, error) {
+	const query = `SELECT COUNT(*) FROM shorten WHERE hash = $1`
+
+	var count int64
+	if err := storage.ConvertError(run.QuerySingle(ctx, query, hash).Scan(&count)); err != nil {
+		return false, fmt.Errorf("retrieve single: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// IncrementClicks increases a number of clicks made with the shorten if it has clicks left.
+// It returns `internal.ErrExpired` if the shorten has no clicks left or doesn't exist anymore.
+func (Repo) IncrementClicks(ctx context.Context, run storage.Runner, id int64) error {
+	const query = `
+		UPDATE shorten
+		SET clicks = clicks + 1
+		WHERE id = $1 AND (max_clicks = 0 OR clicks < max_clicks)`
+
+	res := run.Exec(ctx, query, id)
+	if err := storage.ConvertError(res.Err()); err != nil {
+		return fmt.Errorf("exec update: %w", err)
+	}
+
+	if res.Affected() == 1 {
+		return nil
+	}
+
+	return internal.ErrExpired
+}
+
+// DeleteExpired removes shortens expired before `deadline` and returns their amount.
+func (Repo) DeleteExpired(ctx context.Context, run storage.Runner, deadline time.Time) (int64, error) {
+	const query = `DELETE FROM shorten WHERE expires_at > 0 AND expires_at <= $1`
+
+	res := run.Exec(ctx, query, deadline.Unix())
+	if err := storage.ConvertError(res.Err()); err != nil {
+		return 0, fmt.Errorf("exec delete: %w", err)
+	}
+
+	return res.Affected(), nil
+}
+
+// ArchiveExpired moves shortens expired before `deadline` together with their tags and clicks into the archive
+// and returns their amount.
+// It must be called inside of the transaction.
+func (p Repo) ArchiveExpired(ctx context.Context, run storage.Runner, deadline time.Time) (int64, error) {
+	const expired = `SELECT id FROM shorten WHERE expires_at > 0 AND expires_at <= $1`
+
+	queries := []struct {
+		name  string
+		query string
+		args  []interface{}
+	}{
+		{
+			name: "shortens",
+			query: `
+				INSERT INTO shorten_archive(id, url, hash, created_at, expires_at, max_clicks, clicks, updated_at, workspace, deleted_at, owner, archived_at)
+				SELECT ` + columns + `, CAST($1 AS BIGINT)
+				FROM shorten
+				WHERE expires_at > 0 AND expires_at <= $2`,
+			args: []interface{}{time.Now().Unix(), deadline.Unix()},
+		},
+		{
+			name: "tags",
+			query: `
+				INSERT INTO shorten_tag_archive(shorten_id, tag)
+				SELECT shorten_id, tag
+				FROM shorten_tag
+				WHERE shorten_id IN (` + expired + `)`,
+			args: []interface{}{deadline.Unix()},
+		},
+		{
+			name: "clicks",
+			query: `
+				INSERT INTO click_archive(id, shorten_id, clicked_at, referer, user_agent, ip)
+				SELECT id, shorten_id, clicked_at, referer, user_agent, ip
+				FROM click
+				WHERE shorten_id IN (` + expired + `)`,
+			args: []interface{}{deadline.Unix()},
+		},
+	}
+
+	for _, q := range queries {
+		res := run.Exec(ctx, q.query, q.args...)
+		if err := storage.ConvertError(res.Err()); err != nil {
+			return 0, fmt.Errorf("exec archive %s: %w", q.name, err)
+		}
+	}
+
+	return p.DeleteExpired(ctx, run, deadline)
+}
+
+func scan(res storage.SingleResult) (Entity, error) {
+	var entity Entity
+	var createdAt, expiresAt, updatedAt, deletedAt int64
+	err := res.Scan(&entity.ID, &entity.URL, &entity.Hash, &createdAt, &expiresAt, &entity.MaxClicks, &entity.Clicks, &updatedAt, &entity.Workspace, &deletedAt, &entity.Owner)
+	if err := storage.ConvertError(err); err != nil {
+		return Entity{}, err
+	}
+
 	entity.CreatedAt = time.Unix(createdAt, 0)
+	entity.ExpiresAt = fromUnix(expiresAt)
+	entity.UpdatedAt = fromUnix(updatedAt)
+	entity.DeletedAt = fromUnix(deletedAt)
 
 	return entity, nil
 }
+
+// toUnix returns unix time of `t` or 0 if `t` is a zero value.
+func toUnix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
+
+// fromUnix is a reverse operation for `toUnix`.
+func fromUnix(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(sec, 0)
+}
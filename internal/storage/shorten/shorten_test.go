@@ -68,6 +68,37 @@ func TestSQLLite_Persist(t *testing.T) {
 		})
 		require.NoError(t, err)
 	})
+
+	t.Run("limited", func(t *testing.T) {
+		var id int64
+		expiresAt := time.Now().Add(time.Hour)
+
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) (err error) {
+			id, err = repo.Persist(context.Background(), runner, Entity{URL: "https://example.com", Hash: "limited", ExpiresAt: expiresAt, MaxClicks: 10})
+			return err
+		})
+		require.NoError(t, err)
+
+		err = db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			entity, err := repo.Retrieve(context.Background(), runner, id)
+			require.NoError(t, err)
+			require.Equal(t, expiresAt.Unix(), entity.ExpiresAt.Unix())
+			require.Equal(t, int64(10), entity.MaxClicks)
+			require.Zero(t, entity.Clicks)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("negative max clicks", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			_, err := repo.Persist(context.Background(), runner, Entity{URL: "https://example.com", Hash: "negative", MaxClicks: -1})
+			require.Error(t, err)
+			require.True(t, errors.Is(err, internal.ErrBadInput), err.Error())
+			return nil
+		})
+		require.NoError(t, err)
+	})
 }
 
 func TestSQLLite_Retrieve(t *testing.T) {
@@ -115,7 +146,7 @@ func TestSQLLite_List(t *testing.T) {
 
 	t.Run("nothing", func(t *testing.T) {
 		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
-			entities, err := repo.List(context.Background(), runner, Pager{Limit: 100})
+			entities, err := repo.List(context.Background(), runner, "default", Filter{}, Pager{Limit: 100})
 			require.NoError(t, err)
 			require.Nil(t, entities)
 			return nil
@@ -129,13 +160,14 @@ func TestSQLLite_List(t *testing.T) {
 		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
 			insert(t, runner, Entity{Hash: "1", URL: "https://example.com", CreatedAt: now})
 			insert(t, runner, Entity{Hash: "2", URL: "https://stub.com", CreatedAt: now})
+			insert(t, runner, Entity{Hash: "3", URL: "https://example.com", CreatedAt: now, Workspace: "marketing"})
 			return nil
 		})
 		require.NoError(t, err)
 
 		t.Run("limited", func(t *testing.T) {
 			err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
-				entities, err := repo.List(context.Background(), runner, Pager{Limit: 1, Offset: 1})
+				entities, err := repo.List(context.Background(), runner, "default", Filter{}, Pager{Limit: 1, Offset: 1})
 				require.NoError(t, err)
 				require.Len(t, entities, 1)
 				require.Equal(t, "2", entities[0].Hash)
@@ -148,7 +180,7 @@ func TestSQLLite_List(t *testing.T) {
 
 		t.Run("all", func(t *testing.T) {
 			err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
-				entities, err := repo.List(context.Background(), runner, Pager{Limit: 100})
+				entities, err := repo.List(context.Background(), runner, "default", Filter{}, Pager{Limit: 100})
 				require.NoError(t, err)
 				require.Len(t, entities, 2)
 				require.Equal(t, "1", entities[0].Hash)
@@ -157,9 +189,150 @@ func TestSQLLite_List(t *testing.T) {
 			})
 			require.NoError(t, err)
 		})
+
+		t.Run("after id", func(t *testing.T) {
+			err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+				first, err := repo.List(context.Background(), runner, "default", Filter{}, Pager{Limit: 1})
+				require.NoError(t, err)
+				require.Len(t, first, 1)
+
+				next, err := repo.List(context.Background(), runner, "default", Filter{}, Pager{Limit: 1, AfterID: first[0].ID})
+				require.NoError(t, err)
+				require.Len(t, next, 1)
+				require.Equal(t, "2", next[0].Hash)
+
+				last, err := repo.List(context.Background(), runner, "default", Filter{}, Pager{Limit: 1, AfterID: next[0].ID})
+				require.NoError(t, err)
+				require.Empty(t, last)
+				return nil
+			})
+			require.NoError(t, err)
+		})
+
+		t.Run("workspace", func(t *testing.T) {
+			err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+				entities, err := repo.List(context.Background(), runner, "marketing", Filter{}, Pager{Limit: 100})
+				require.NoError(t, err)
+				require.Len(t, entities, 1)
+				require.Equal(t, "3", entities[0].Hash)
+				require.Equal(t, "marketing", entities[0].Workspace)
+				return nil
+			})
+			require.NoError(t, err)
+		})
 	})
 }
 
+func TestSQLLite_ListFilter(t *testing.T) {
+	db, cleanup := initDB(t, t.Name())
+	defer cleanup()
+
+	repo := Repo{}
+	now := time.Now()
+
+	err := db.WithTx(context.Background(), func(runner storage.Runner) error {
+		insert(t, runner, Entity{Hash: "1", URL: "https://example.com/Summer-Sale", CreatedAt: now.Add(-3 * time.Hour), Clicks: 5, Owner: "ci"})
+		insert(t, runner, Entity{Hash: "2", URL: "https://docs.example.com/guide", CreatedAt: now.Add(-2 * time.Hour), Clicks: 9, MaxClicks: 9})
+		insert(t, runner, Entity{Hash: "3", URL: "https://notexample.com/summer", CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(-time.Minute), Owner: "ci"})
+		insert(t, runner, Entity{Hash: "4", URL: "https://stub.com/winter", CreatedAt: now, Clicks: 1, ExpiresAt: now.Add(time.Hour)})
+		insert(t, runner, Entity{Hash: "5", URL: "https://example.com/summer", CreatedAt: now, DeletedAt: now})
+		insert(t, runner, Entity{Hash: "6", URL: "https://example.com/summer", CreatedAt: now, Workspace: "marketing"})
+		return repo.SetTags(context.Background(), runner, 2, []string{"docs", "promo"})
+	})
+	require.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		filter Filter
+		sort   string
+		exp    []string
+	}{
+		"nothing":             {exp: []string{"1", "2", "3", "4"}},
+		"domain":              {filter: Filter{Domain: "Example.com"}, exp: []string{"1", "2"}},
+		"subdomain":           {filter: Filter{Domain: "docs.example.com"}, exp: []string{"2"}},
+		"domain with percent": {filter: Filter{Domain: "%"}},
+		"domain underscore":   {filter: Filter{Domain: "example_com"}},
+		"url":                 {filter: Filter{URL: "SUMMER"}, exp: []string{"1", "3"}},
+		"url of path":         {filter: Filter{URL: "com/guide"}, exp: []string{"2"}},
+		"url not found":       {filter: Filter{URL: "autumn"}},
+		"url with underscore": {filter: Filter{URL: "summer_sale"}},
+		"url with percent":    {filter: Filter{URL: "summer%sale"}},
+		"url with backslash":  {filter: Filter{URL: `summer\-sale`}},
+		"url with dash":       {filter: Filter{URL: "summer-sale"}, exp: []string{"1"}},
+		"created from":        {filter: Filter{CreatedFrom: now.Add(-time.Hour)}, exp: []string{"3", "4"}},
+		"created to":          {filter: Filter{CreatedTo: now.Add(-time.Hour)}, exp: []string{"1", "2"}},
+		"tag":                 {filter: Filter{Tag: "promo"}, exp: []string{"2"}},
+		"owner":               {filter: Filter{Owner: "ci"}, exp: []string{"1", "3"}},
+		"active":              {filter: Filter{Status: StatusActive}, exp: []string{"1", "4"}},
+		"expired":             {filter: Filter{Status: StatusExpired}, exp: []string{"3"}},
+		"disabled":            {filter: Filter{Status: StatusDisabled}, exp: []string{"2"}},
+		"combined":            {filter: Filter{Owner: "ci", Status: StatusActive, URL: "summer"}, exp: []string{"1"}},
+		"sort created at":     {sort: SortCreatedAt, exp: []string{"1", "2", "3", "4"}},
+		"sort -created at":    {sort: SortCreatedAtDesc, exp: []string{"4", "3", "2", "1"}},
+		"sort clicks":         {sort: SortClicks, exp: []string{"3", "4", "1", "2"}},
+		"sort -clicks":        {sort: SortClicksDesc, exp: []string{"2", "1", "4", "3"}},
+		"filtered and sorted": {filter: Filter{Domain: "example.com"}, sort: SortClicksDesc, exp: []string{"2", "1"}},
+	} {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+				entities, err := repo.List(context.Background(), runner, "default", tc.filter, Pager{Limit: 100, Sort: tc.sort})
+				require.NoError(t, err)
+				require.Equal(t, tc.exp, hashes(entities))
+				return nil
+			})
+			require.NoError(t, err)
+		})
+	}
+
+	t.Run("after key", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			var walked []string
+			pager := Pager{Limit: 1, Sort: SortClicksDesc}
+			for {
+				entities, err := repo.List(context.Background(), runner, "default", Filter{}, pager)
+				require.NoError(t, err)
+				if len(entities) == 0 {
+					break
+				}
+				walked = append(walked, entities[0].Hash)
+				pager.AfterID, pager.AfterKey = entities[0].ID, entities[0].Clicks
+			}
+			require.Equal(t, []string{"2", "1", "4", "3"}, walked)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("changed url", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			require.NoError(t, repo.Update(context.Background(), runner, Entity{ID: 4, URL: "https://stub.org/autumn", Hash: "4"}))
+
+			entities, err := repo.List(context.Background(), runner, "default", Filter{URL: "autumn", Domain: "stub.org"}, Pager{Limit: 100})
+			require.NoError(t, err)
+			require.Equal(t, []string{"4"}, hashes(entities))
+
+			entities, err = repo.List(context.Background(), runner, "default", Filter{URL: "winter"}, Pager{Limit: 100})
+			require.NoError(t, err)
+			require.Empty(t, entities)
+
+			require.NoError(t, repo.Purge(context.Background(), runner, 4))
+			entities, err = repo.List(context.Background(), runner, "default", Filter{URL: "autumn"}, Pager{Limit: 100})
+			require.NoError(t, err)
+			require.Empty(t, entities)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
+
+func hashes(entities []Entity) []string {
+	var hashes []string
+	for _, entity := range entities {
+		hashes = append(hashes, entity.Hash)
+	}
+	return hashes
+}
+
 func TestSQLLite_Delete(t *testing.T) {
 	db, cleanup := initDB(t, t.Name())
 	defer cleanup()
@@ -189,15 +362,309 @@ func TestSQLLite_Delete(t *testing.T) {
 			err := repo.Delete(context.Background(), runner, id)
 			require.NoError(t, err)
 
+			entity, err := repo.Retrieve(context.Background(), runner, id)
+			require.NoError(t, err)
+			require.WithinDuration(t, time.Now(), entity.DeletedAt, time.Minute)
+
+			_, err = repo.ByHash(context.Background(), runner, "1")
+			require.True(t, errors.Is(err, internal.ErrNotFound), err)
+
+			entities, err := repo.List(context.Background(), runner, "default", Filter{}, Pager{Limit: 100})
+			require.NoError(t, err)
+			require.Empty(t, entities)
+
+			err = repo.Delete(context.Background(), runner, id)
+			require.True(t, errors.Is(err, internal.ErrNotFound), err, "already deleted")
+
+			_, err = repo.Persist(context.Background(), runner, Entity{URL: "https://stub.com", Hash: "1"})
+			require.True(t, errors.Is(err, internal.ErrNotUnique), err, "code of the deleted shorten is taken")
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
+
+func TestSQLLite_Restore(t *testing.T) {
+	db, cleanup := initDB(t, t.Name())
+	defer cleanup()
+
+	repo := Repo{}
+
+	err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+		id := insert(t, runner, Entity{Hash: "1", URL: "https://example.com", CreatedAt: time.Now()})
+
+		err := repo.Restore(context.Background(), runner, id)
+		require.True(t, errors.Is(err, internal.ErrNotFound), err, "not deleted")
+
+		require.NoError(t, repo.Delete(context.Background(), runner, id))
+		require.NoError(t, repo.Restore(context.Background(), runner, id))
+
+		entity, err := repo.ByHash(context.Background(), runner, "1")
+		require.NoError(t, err)
+		require.Equal(t, id, entity.ID)
+		require.True(t, entity.DeletedAt.IsZero())
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestSQLLite_Trash(t *testing.T) {
+	db, cleanup := initDB(t, t.Name())
+	defer cleanup()
+
+	repo := Repo{}
+
+	err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+		now := time.Now()
+		insert(t, runner, Entity{Hash: "1", URL: "https://example.com", CreatedAt: now})
+		first := insert(t, runner, Entity{Hash: "2", URL: "https://example.com", CreatedAt: now, DeletedAt: now.Add(-time.Hour)})
+		second := insert(t, runner, Entity{Hash: "3", URL: "https://example.com", CreatedAt: now, DeletedAt: now})
+		insert(t, runner, Entity{Hash: "4", URL: "https://example.com", CreatedAt: now, DeletedAt: now, Workspace: "marketing"})
+
+		entities, err := repo.Trash(context.Background(), runner, "default", Pager{Limit: 100})
+		require.NoError(t, err)
+		require.Len(t, entities, 2)
+		require.Equal(t, second, entities[0].ID)
+		require.Equal(t, now.Unix(), entities[0].DeletedAt.Unix())
+		require.Equal(t, first, entities[1].ID)
+
+		entities, err = repo.Trash(context.Background(), runner, "default", Pager{Limit: 1, Offset: 1})
+		require.NoError(t, err)
+		require.Len(t, entities, 1)
+		require.Equal(t, first, entities[0].ID)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestSQLLite_Purge(t *testing.T) {
+	db, cleanup := initDB(t, t.Name())
+	defer cleanup()
+
+	repo := Repo{}
+
+	t.Run("not existing", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			err := repo.Purge(context.Background(), runner, 0)
+			require.True(t, errors.Is(err, internal.ErrNotFound), err)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			id := insert(t, runner, Entity{Hash: "1", URL: "https://example.com", CreatedAt: time.Now()})
+			require.NoError(t, repo.Purge(context.Background(), runner, id))
+
 			var dst interface{}
 			res := runner.QuerySingle(context.Background(), "SELECT 1 FROM shorten WHERE id = $1", id)
-			err = res.Scan(&dst)
+			err := res.Scan(&dst)
 			require.Error(t, err)
 			require.True(t, errors.Is(err, sql.ErrNoRows), err.Error())
 			return nil
 		})
 		require.NoError(t, err)
 	})
+
+	t.Run("deleted", func(t *testing.T) {
+		now := time.Now()
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			old := insert(t, runner, Entity{Hash: "2", URL: "https://example.com", CreatedAt: now, DeletedAt: now.Add(-48 * time.Hour)})
+			recent := insert(t, runner, Entity{Hash: "3", URL: "https://example.com", CreatedAt: now, DeletedAt: now.Add(-time.Hour)})
+			active := insert(t, runner, Entity{Hash: "4", URL: "https://example.com", CreatedAt: now})
+
+			purged, err := repo.PurgeDeleted(context.Background(), runner, now.Add(-24*time.Hour))
+			require.NoError(t, err)
+			require.Equal(t, int64(1), purged)
+
+			_, err = repo.Retrieve(context.Background(), runner, old)
+			require.True(t, errors.Is(err, internal.ErrNotFound), err)
+			_, err = repo.Retrieve(context.Background(), runner, recent)
+			require.NoError(t, err)
+			_, err = repo.Retrieve(context.Background(), runner, active)
+			require.NoError(t, err)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
+
+func TestSQLLite_HashTaken(t *testing.T) {
+	db, cleanup := initDB(t, t.Name())
+	defer cleanup()
+
+	repo := Repo{}
+
+	err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+		now := time.Now()
+		insert(t, runner, Entity{Hash: "active", URL: "https://example.com", CreatedAt: now})
+		insert(t, runner, Entity{Hash: "deleted", URL: "https://example.com", CreatedAt: now, DeletedAt: now})
+
+		for hash, exp := range map[string]bool{"active": true, "deleted": true, "free": false} {
+			taken, err := repo.HashTaken(context.Background(), runner, hash)
+			require.NoError(t, err)
+			require.Equal(t, exp, taken, hash)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestSQLLite_UpdateHash(t *testing.T) {
+	db, cleanup := initDB(t, t.Name())
+	defer cleanup()
+
+	repo := Repo{}
+
+	t.Run("not existing", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			err := repo.UpdateHash(context.Background(), runner, 100, "1234567")
+			require.Error(t, err)
+			require.True(t, errors.Is(err, internal.ErrNotFound), err.Error())
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		err := db.WithTx(context.Background(), func(runner storage.Runner) error {
+			id := insert(t, runner, Entity{URL: "https://example.com", Hash: "~tmp", CreatedAt: time.Now()})
+			require.NotZero(t, id)
+			require.NoError(t, repo.UpdateHash(context.Background(), runner, id, "1234567"))
+
+			entity, err := repo.ByHash(context.Background(), runner, "1234567")
+			require.NoError(t, err)
+			require.Equal(t, id, entity.ID)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("not unique", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			id := insert(t, runner, Entity{URL: "https://stub.com", Hash: "~tmp", CreatedAt: time.Now()})
+			err := repo.UpdateHash(context.Background(), runner, id, "1234567")
+			require.Error(t, err)
+			require.True(t, errors.Is(err, internal.ErrNotUnique), err.Error())
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
+
+func TestSQLLite_Update(t *testing.T) {
+	db, cleanup := initDB(t, t.Name())
+	defer cleanup()
+
+	repo := Repo{}
+
+	t.Run("not existing", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			err := repo.Update(context.Background(), runner, Entity{ID: 100, URL: "https://example.com", Hash: "1"})
+			require.Error(t, err)
+			require.True(t, errors.Is(err, internal.ErrNotFound), err.Error())
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			id := insert(t, runner, Entity{URL: "https://example.com", Hash: "1", CreatedAt: time.Now(), Clicks: 5})
+
+			expiresAt := time.Now().Add(time.Hour)
+			err := repo.Update(context.Background(), runner, Entity{ID: id, URL: "https://moved.com", Hash: "moved", ExpiresAt: expiresAt, MaxClicks: 10})
+			require.NoError(t, err)
+
+			entity, err := repo.Retrieve(context.Background(), runner, id)
+			require.NoError(t, err)
+			require.Equal(t, "https://moved.com", entity.URL)
+			require.Equal(t, "moved", entity.Hash)
+			require.Equal(t, expiresAt.Unix(), entity.ExpiresAt.Unix())
+			require.Equal(t, int64(10), entity.MaxClicks)
+			require.Equal(t, int64(5), entity.Clicks)
+			require.WithinDuration(t, time.Now(), entity.UpdatedAt, time.Minute)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("not unique", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			id := insert(t, runner, Entity{URL: "https://stub.com", Hash: "2", CreatedAt: time.Now()})
+			err := repo.Update(context.Background(), runner, Entity{ID: id, URL: "https://stub.com", Hash: "moved"})
+			require.Error(t, err)
+			require.True(t, errors.Is(err, internal.ErrNotUnique), err.Error())
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
+
+func TestSQLLite_Tags(t *testing.T) {
+	db, cleanup := initDB(t, t.Name())
+	defer cleanup()
+
+	repo := Repo{}
+
+	var first, second int64
+	err := db.WithTx(context.Background(), func(runner storage.Runner) error {
+		first = insert(t, runner, Entity{URL: "https://example.com/1", Hash: "1", CreatedAt: time.Now()})
+		second = insert(t, runner, Entity{URL: "https://example.com/2", Hash: "2", CreatedAt: time.Now()})
+		require.NoError(t, repo.SetTags(context.Background(), runner, first, []string{"summer", "promo"}))
+		require.NoError(t, repo.SetTags(context.Background(), runner, second, []string{"promo"}))
+		return nil
+	})
+	require.NoError(t, err)
+
+	t.Run("none", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			tags, err := repo.Tags(context.Background(), runner)
+			require.NoError(t, err)
+			require.Empty(t, tags)
+
+			tags, err = repo.Tags(context.Background(), runner, 100)
+			require.NoError(t, err)
+			require.Empty(t, tags)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("multiple", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			tags, err := repo.Tags(context.Background(), runner, first, second)
+			require.NoError(t, err)
+			require.Equal(t, map[int64][]string{first: {"promo", "summer"}, second: {"promo"}}, tags)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		err := db.WithTx(context.Background(), func(runner storage.Runner) error {
+			require.NoError(t, repo.SetTags(context.Background(), runner, first, []string{"winter"}))
+
+			tags, err := repo.Tags(context.Background(), runner, first)
+			require.NoError(t, err)
+			require.Equal(t, map[int64][]string{first: {"winter"}}, tags)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("removed with shorten", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			require.NoError(t, repo.Purge(context.Background(), runner, second))
+
+			tags, err := repo.Tags(context.Background(), runner, second)
+			require.NoError(t, err)
+			require.Empty(t, tags)
+			return nil
+		})
+		require.NoError(t, err)
+	})
 }
 
 func TestSQLLite_ByHash(t *testing.T) {
@@ -237,13 +704,148 @@ func TestSQLLite_ByHash(t *testing.T) {
 	})
 }
 
+func TestSQLLite_IncrementClicks(t *testing.T) {
+	db, cleanup := initDB(t, t.Name())
+	defer cleanup()
+
+	repo := Repo{}
+
+	t.Run("not existing", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			err := repo.IncrementClicks(context.Background(), runner, 100)
+			require.True(t, errors.Is(err, internal.ErrExpired), err)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			id := insert(t, runner, Entity{URL: "https://example.com", Hash: "1", CreatedAt: time.Now()})
+			for i := 0; i < 3; i++ {
+				require.NoError(t, repo.IncrementClicks(context.Background(), runner, id))
+			}
+
+			entity, err := repo.Retrieve(context.Background(), runner, id)
+			require.NoError(t, err)
+			require.Equal(t, int64(3), entity.Clicks)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("limited", func(t *testing.T) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			id := insert(t, runner, Entity{URL: "https://example.com", Hash: "2", CreatedAt: time.Now(), MaxClicks: 2})
+			require.NoError(t, repo.IncrementClicks(context.Background(), runner, id))
+			require.NoError(t, repo.IncrementClicks(context.Background(), runner, id))
+
+			err := repo.IncrementClicks(context.Background(), runner, id)
+			require.True(t, errors.Is(err, internal.ErrExpired), err)
+
+			entity, err := repo.Retrieve(context.Background(), runner, id)
+			require.NoError(t, err)
+			require.Equal(t, int64(2), entity.Clicks)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
+
+func TestSQLLite_SweepExpired(t *testing.T) {
+	now := time.Now()
+
+	prepare := func(t *testing.T, db storage.Pool) (expired, active, unlimited int64) {
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			expired = insert(t, runner, Entity{URL: "https://expired.com", Hash: "1", CreatedAt: now, ExpiresAt: now.Add(-time.Hour), Clicks: 5})
+			active = insert(t, runner, Entity{URL: "https://active.com", Hash: "2", CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+			unlimited = insert(t, runner, Entity{URL: "https://unlimited.com", Hash: "3", CreatedAt: now})
+			require.NoError(t, Repo{}.SetTags(context.Background(), runner, expired, []string{"sale"}))
+			res := runner.Exec(context.Background(), `INSERT INTO click(shorten_id, clicked_at, referer, user_agent, ip) VALUES ($1, $2, $3, $4, $5)`, expired, now.Unix(), "https://referer.com", "curl", "127.0.0.1")
+			require.NoError(t, res.Err())
+			return nil
+		})
+		require.NoError(t, err)
+		return expired, active, unlimited
+	}
+
+	t.Run("delete", func(t *testing.T) {
+		db, cleanup := initDB(t, "TestSQLLite_SweepExpired_delete")
+		defer cleanup()
+
+		repo := Repo{}
+		expired, active, unlimited := prepare(t, db)
+
+		err := db.WithoutTx(context.Background(), func(runner storage.Runner) error {
+			swept, err := repo.DeleteExpired(context.Background(), runner, now)
+			require.NoError(t, err)
+			require.Equal(t, int64(1), swept)
+
+			_, err = repo.Retrieve(context.Background(), runner, expired)
+			require.True(t, errors.Is(err, internal.ErrNotFound), err)
+			_, err = repo.Retrieve(context.Background(), runner, active)
+			require.NoError(t, err)
+			_, err = repo.Retrieve(context.Background(), runner, unlimited)
+			require.NoError(t, err)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("archive", func(t *testing.T) {
+		db, cleanup := initDB(t, "TestSQLLite_SweepExpired_archive")
+		defer cleanup()
+
+		repo := Repo{}
+		expired, active, _ := prepare(t, db)
+
+		err := db.WithTx(context.Background(), func(runner storage.Runner) error {
+			swept, err := repo.ArchiveExpired(context.Background(), runner, now.Add(-2*time.Hour))
+			require.NoError(t, err)
+			require.Zero(t, swept, "grace period is not over yet")
+
+			swept, err = repo.ArchiveExpired(context.Background(), runner, now)
+			require.NoError(t, err)
+			require.Equal(t, int64(1), swept)
+
+			_, err = repo.Retrieve(context.Background(), runner, expired)
+			require.True(t, errors.Is(err, internal.ErrNotFound), err)
+			_, err = repo.Retrieve(context.Background(), runner, active)
+			require.NoError(t, err)
+
+			var url string
+			var clicks int64
+			res := runner.QuerySingle(context.Background(), "SELECT url, clicks FROM shorten_archive WHERE id = $1", expired)
+			require.NoError(t, res.Scan(&url, &clicks))
+			require.Equal(t, "https://expired.com", url)
+			require.Equal(t, int64(5), clicks)
+
+			var tag string
+			res = runner.QuerySingle(context.Background(), "SELECT tag FROM shorten_tag_archive WHERE shorten_id = $1", expired)
+			require.NoError(t, res.Scan(&tag))
+			require.Equal(t, "sale", tag)
+
+			var referer string
+			res = runner.QuerySingle(context.Background(), "SELECT referer FROM click_archive WHERE shorten_id = $1", expired)
+			require.NoError(t, res.Scan(&referer))
+			require.Equal(t, "https://referer.com", referer)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
+
 func insert(t *testing.T, runner storage.Runner, shorten Entity) int64 {
-	res := runner.Exec(
+	if shorten.Workspace == "" {
+		shorten.Workspace = "default"
+	}
+
+	var id int64
+	res := runner.QuerySingle(
 		context.Background(),
-		`INSERT INTO shorten VALUES (NULL, $1, $2, $3)`,
-		shorten.URL, shorten.Hash, shorten.CreatedAt.Unix(),
+		`INSERT INTO shorten(url, hash, created_at, expires_at, max_clicks, clicks, workspace, deleted_at, owner, domain) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`,
+		shorten.URL, shorten.Hash, shorten.CreatedAt.Unix(), toUnix(shorten.ExpiresAt), shorten.MaxClicks, shorten.Clicks, shorten.Workspace, toUnix(shorten.DeletedAt), shorten.Owner, domainOf(shorten.URL),
 	)
-	require.NoError(t, res.Err())
-	require.EqualValues(t, 1, res.Affected())
-	return res.ID()
+	require.NoError(t, res.Scan(&id))
+	return id
 }
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSQLLite_foreignKeys(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, dsn := range map[string]string{
+		"path":                 filepath.Join(dir, "path.dat"),
+		"uri":                  "file:" + filepath.Join(dir, "uri.dat"),
+		"uri with parameters":  "file:" + filepath.Join(dir, "parameters.dat") + "?cache=shared&mode=rwc",
+		"path with parameters": filepath.Join(dir, "path_parameters.dat") + "?_busy_timeout=5000",
+	} {
+		t.Run(name, func(t *testing.T) {
+			db, err := NewSQLLite(dsn)
+			require.NoError(t, err)
+			defer db.Close()
+
+			require.NoError(t, db.WithoutTx(context.Background(), func(runner Runner) error {
+				var enabled bool
+				require.NoError(t, runner.QuerySingle(context.Background(), `PRAGMA foreign_keys`).Scan(&enabled))
+				require.True(t, enabled)
+				return nil
+			}))
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/pavelmemory/jobtome/internal"
+)
+
+// Supported database drivers.
+const (
+	DriverSQLite   = "sqlite3"
+	DriverPostgres = "postgres"
+)
+
+type ExecResult interface {
+	Err() error
+	Affected() int64
+}
+
+type MultiResult interface {
+	Next() bool
+	Scan(dst ...interface{}) error
+	Close() error
+}
+
+type SingleResult interface {
+	Scan(dst ...interface{}) error
+}
+
+type Runner interface {
+	Exec(ctx context.Context, query string, params ...interface{}) ExecResult
+	Query(ctx context.Context, query string, params ...interface{}) (MultiResult, error)
+	QuerySingle(ctx context.Context, query string, params ...interface{}) SingleResult
+}
+
+// Pool is a connection pool to the database.
+type Pool interface {
+	// WithTx executes provided callback inside of the transaction.
+	// If callback returns an error the transaction will be rolled back, otherwise it will be committed.
+	WithTx(ctx context.Context, action func(runner Runner) error) error
+	// WithoutTx executes provided callback without explicitly open transaction.
+	WithoutTx(ctx context.Context, action func(runner Runner) error) error
+	// Close closes all connections of the pool.
+	Close()
+	// Stats returns statistics of the pool connections.
+	Stats() sql.DBStats
+	// Ping verifies the database is reachable.
+	Ping(ctx context.Context) error
+}
+
+// Open returns a connection pool of the `driver` to the database located by the `dsn`.
+// For SQLite the `dsn` is a path to the database file.
+func Open(driver, dsn string) (Pool, error) {
+	switch driver {
+	case DriverSQLite:
+		return NewSQLLite(dsn)
+	case DriverPostgres:
+		return NewPostgres(dsn)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
+}
+
+// pool implements transaction handling common for all drivers.
+type pool struct {
+	db     *sql.DB
+	driver string
+}
+
+func (p pool) WithTx(ctx context.Context, action func(runner Runner) error) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := action(txRunner{tx: tx, driver: p.driver}); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (p pool) WithoutTx(_ context.Context, action func(runner Runner) error) error {
+	return action(qRunner{db: p.db, driver: p.driver})
+}
+
+func (p pool) Close() {
+	p.db.Close()
+}
+
+func (p pool) Stats() sql.DBStats {
+	return p.db.Stats()
+}
+
+func (p pool) Ping(ctx context.Context) error {
+	return p.db.PingContext(ctx)
+}
+
+type qRunner struct {
+	db     *sql.DB
+	driver string
+}
+
+func (q qRunner) Exec(ctx context.Context, query string, params ...interface{}) ExecResult {
+	ctx, finish := startQuery(ctx, q.driver, operationExec, query)
+	res := newExecResult(q.db.ExecContext(ctx, query, params...))
+	finish(res.Err())
+	return res
+}
+
+func (q qRunner) Query(ctx context.Context, query string, params ...interface{}) (MultiResult, error) {
+	ctx, finish := startQuery(ctx, q.driver, operationQuery, query)
+	res, err := q.db.QueryContext(ctx, query, params...)
+	finish(err)
+	return res, err
+}
+
+func (q qRunner) QuerySingle(ctx context.Context, query string, params ...interface{}) SingleResult {
+	ctx, finish := startQuery(ctx, q.driver, operationQuerySingle, query)
+	defer finish(nil) // errors are reported only on scan
+	return q.db.QueryRowContext(ctx, query, params...)
+}
+
+type txRunner struct {
+	tx     *sql.Tx
+	driver string
+}
+
+func (r txRunner) Exec(ctx context.Context, query string, params ...interface{}) ExecResult {
+	ctx, finish := startQuery(ctx, r.driver, operationExec, query)
+	res := newExecResult(r.tx.ExecContext(ctx, query, params...))
+	finish(res.Err())
+	return res
+}
+
+func (r txRunner) Query(ctx context.Context, query string, params ...interface{}) (MultiResult, error) {
+	ctx, finish := startQuery(ctx, r.driver, operationQuery, query)
+	res, err := r.tx.QueryContext(ctx, query, params...)
+	finish(err)
+	return res, err
+}
+
+func (r txRunner) QuerySingle(ctx context.Context, query string, params ...interface{}) SingleResult {
+	ctx, finish := startQuery(ctx, r.driver, operationQuerySingle, query)
+	defer finish(nil) // errors are reported only on scan
+	return r.tx.QueryRowContext(ctx, query, params...)
+}
+
+func newExecResult(res sql.Result, err error) execResult {
+	if err != nil {
+		return execResult{err: err}
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return execResult{err: err}
+	}
+
+	// IDs of inserted rows are not supported by all drivers, use `INSERT ... RETURNING` instead
+	return execResult{result: n}
+}
+
+type execResult struct {
+	result int64
+	err    error
+}
+
+func (r execResult) Err() error {
+	return r.err
+}
+
+func (r execResult) Affected() int64 {
+	return r.result
+}
+
+// ConvertError converts driver specific errors into the standard ones defined in `internal` package.
+func ConvertError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return internal.ErrNotFound
+	}
+
+	if converted, ok := convertSQLiteError(err); ok {
+		return converted
+	}
+
+	if converted, ok := convertPostgresError(err); ok {
+		return converted
+	}
+
+	return err
+}
@@ -0,0 +1,91 @@
+// Package storagetest provides databases for the tests of the storage packages.
+package storagetest
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pavelmemory/jobtome/internal/storage"
+	"github.com/pavelmemory/jobtome/internal/storage/migrations"
+)
+
+// PostgresDSNEnv is a name of the environment variable with a DSN of PostgreSQL database used by the tests.
+// If it is not set the tests are run against SQLite.
+const PostgresDSNEnv = "STORAGE_TEST_POSTGRES_DSN"
+
+// Open returns a new migrated database with the `name` and a function to remove it.
+// With PostgreSQL each database is a separate schema, so tests of different packages don't interfere.
+func Open(t *testing.T, name string) (storage.Pool, func()) {
+	t.Helper()
+
+	if dsn := os.Getenv(PostgresDSNEnv); dsn != "" {
+		return openPostgres(t, dsn, name)
+	}
+
+	return openSQLite(t, name)
+}
+
+func openSQLite(t *testing.T, filepath string) (storage.Pool, func()) {
+	require.NoError(t, os.RemoveAll(filepath))
+
+	require.NoError(t, migrations.Up(storage.DriverSQLite, filepath))
+
+	instance, err := storage.NewSQLLite(filepath)
+	require.NoError(t, err)
+
+	cleanup := func() {
+		instance.Close()
+		require.NoError(t, os.RemoveAll(filepath))
+	}
+
+	return instance, cleanup
+}
+
+var nonIdentifier = regexp.MustCompile(`[^a-z0-9_]+`)
+
+func openPostgres(t *testing.T, dsn, name string) (storage.Pool, func()) {
+	// tests of different packages are run concurrently by different processes and may have the same names
+	schema := fmt.Sprintf("test_%d_%s", os.Getpid(), nonIdentifier.ReplaceAllString(strings.ToLower(name), "_"))
+
+	admin, err := sql.Open(storage.DriverPostgres, dsn)
+	require.NoError(t, err)
+
+	_, err = admin.Exec(`DROP SCHEMA IF EXISTS ` + schema + ` CASCADE`)
+	require.NoError(t, err)
+	_, err = admin.Exec(`CREATE SCHEMA ` + schema)
+	require.NoError(t, err)
+
+	schemaDSN := withSearchPath(dsn, schema)
+	require.NoError(t, migrations.Up(storage.DriverPostgres, schemaDSN))
+
+	instance, err := storage.NewPostgres(schemaDSN)
+	require.NoError(t, err)
+
+	cleanup := func() {
+		instance.Close()
+		_, err := admin.Exec(`DROP SCHEMA IF EXISTS ` + schema + ` CASCADE`)
+		require.NoError(t, err)
+		require.NoError(t, admin.Close())
+	}
+
+	return instance, cleanup
+}
+
+// withSearchPath adds `search_path` run-time parameter to the URL or key/value `dsn`.
+func withSearchPath(dsn, schema string) string {
+	if !strings.HasPrefix(dsn, "postgres://") && !strings.HasPrefix(dsn, "postgresql://") {
+		return dsn + " search_path=" + schema
+	}
+
+	if strings.Contains(dsn, "?") {
+		return dsn + "&search_path=" + schema
+	}
+
+	return dsn + "?search_path=" + schema
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pavelmemory/jobtome/internal/tracing"
+)
+
+var tracer = otel.Tracer("github.com/pavelmemory/jobtome/internal/storage")
+
+// startQuery starts a span of the statement and returns a function
+// that must be called with the result of the statement once it is executed.
+// The function ends the span and records the duration of the statement.
+func startQuery(ctx context.Context, driver, operation, query string) (context.Context, func(error)) {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "storage."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", driver),
+			attribute.String("db.statement", statement(query)),
+		),
+	)
+
+	return ctx, func(err error) {
+		queryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+		tracing.End(span, err)
+	}
+}
+
+// statement returns the `query` with the whitespaces collapsed, so it looks well as a span attribute.
+func statement(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRunner_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(prevProvider)
+
+	db, err := NewSQLLite(filepath.Join(t.TempDir(), "tracing.dat"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.WithTx(context.Background(), func(runner Runner) error {
+		if err := runner.Exec(context.Background(), "CREATE TABLE t(\n\tid INTEGER\n)").Err(); err != nil {
+			return err
+		}
+
+		var count int
+		return runner.QuerySingle(context.Background(), "SELECT COUNT(*) FROM t").Scan(&count)
+	}))
+	require.Error(t, db.WithoutTx(context.Background(), func(runner Runner) error {
+		return runner.Exec(context.Background(), "SELECT * FROM unknown").Err()
+	}))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	for i, exp := range []struct {
+		name      string
+		statement string
+		failed    bool
+	}{
+		{name: "storage.exec", statement: "CREATE TABLE t( id INTEGER )"},
+		{name: "storage.query_single", statement: "SELECT COUNT(*) FROM t"},
+		{name: "storage.exec", statement: "SELECT * FROM unknown", failed: true},
+	} {
+		require.Equal(t, exp.name, spans[i].Name())
+		require.Equal(t, trace.SpanKindClient, spans[i].SpanKind())
+		require.Contains(t, spans[i].Attributes(), attribute.String("db.system", DriverSQLite))
+		require.Contains(t, spans[i].Attributes(), attribute.String("db.statement", exp.statement))
+		require.Equal(t, exp.failed, len(spans[i].Events()) > 0)
+	}
+}
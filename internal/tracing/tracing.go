@@ -0,0 +1,126 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pavelmemory/jobtome/internal"
+)
+
+// Exporters supported by `Setup`.
+const (
+	// ExporterNone disables the tracing.
+	ExporterNone = "none"
+	// ExporterStdout writes spans to the standard output.
+	ExporterStdout = "stdout"
+	// ExporterFile writes spans to the file.
+	ExporterFile = "file"
+	// ExporterOTLP sends spans to the OpenTelemetry collector with OTLP over HTTP.
+	ExporterOTLP = "otlp"
+)
+
+// Config defines where and how many spans are exported.
+type Config struct {
+	// Exporter is one of the supported exporters, `ExporterNone` by default.
+	Exporter string
+	// Endpoint is a path to the file for `ExporterFile` or a URL of the collector for `ExporterOTLP`.
+	// An empty URL means the collector is located by the standard `OTEL_EXPORTER_OTLP_*` environment variables.
+	Endpoint string
+	// SampleRatio is a fraction of the traces started by the service that are exported.
+	// Traces started by the callers are exported if the caller sampled them.
+	SampleRatio float64
+}
+
+// Setup installs a global tracer provider that exports spans as configured
+// and W3C Trace Context propagation of the traces across the services.
+// The returned function flushes the spans that are not yet exported and must be called before exit.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil || exporter == nil {
+		return func(context.Context) error { return nil }, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", "jobtome"),
+		attribute.String("service.version", internal.Version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case ExporterNone, "":
+		return nil, nil
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterFile:
+		file, err := os.OpenFile(cfg.Endpoint, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("open traces file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		return closingExporter{SpanExporter: exporter, closer: file}, nil
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		return otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+}
+
+// closingExporter closes the file spans are written to once the exporter is shut down.
+type closingExporter struct {
+	sdktrace.SpanExporter
+	closer io.Closer
+}
+
+func (e closingExporter) Shutdown(ctx context.Context) error {
+	if err := e.SpanExporter.Shutdown(ctx); err != nil {
+		_ = e.closer.Close()
+		return err
+	}
+
+	return e.closer.Close()
+}
+
+// End records the `err` if it is not nil and ends the `span`.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
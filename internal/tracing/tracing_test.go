@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestSetup(t *testing.T) {
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("unknown exporter", func(t *testing.T) {
+		_, err := Setup(context.Background(), Config{Exporter: "jaeger"})
+		require.EqualError(t, err, `unknown tracing exporter "jaeger"`)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		shutdown, err := Setup(context.Background(), Config{Exporter: ExporterNone})
+		require.NoError(t, err)
+		require.NoError(t, shutdown(context.Background()))
+	})
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "traces.json")
+		shutdown, err := Setup(context.Background(), Config{Exporter: ExporterFile, Endpoint: path, SampleRatio: 1})
+		require.NoError(t, err)
+
+		_, span := otel.Tracer("test").Start(context.Background(), "operation")
+		End(span, errors.New("failure"))
+		require.NoError(t, shutdown(context.Background()))
+
+		traces, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.True(t, strings.Contains(string(traces), `"Name":"operation"`), string(traces))
+		require.True(t, strings.Contains(string(traces), `"Description":"failure"`), string(traces))
+		require.True(t, strings.Contains(string(traces), `"Value":"jobtome"`), string(traces))
+	})
+
+	t.Run("file not accessible", func(t *testing.T) {
+		_, err := Setup(context.Background(), Config{Exporter: ExporterFile, Endpoint: t.TempDir()})
+		require.Error(t, err)
+	})
+}
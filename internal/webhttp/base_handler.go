@@ -2,8 +2,10 @@ package webhttp
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi"
 )
@@ -19,6 +21,20 @@ func (uh baseHandler) pathParamInt64(r *http.Request, opts ParamInt64Opts) (int6
 	return opts.parse(val)
 }
 
+// clientIP returns an IP address of the client that made the request.
+// The address resolved by `RateLimit` is preferred as it respects the trusted proxies.
+func (uh baseHandler) clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 func (uh baseHandler) queryParam(r *http.Request, name string) string {
 	return r.URL.Query().Get(name)
 }
@@ -53,3 +69,37 @@ func (uh baseHandler) queryParamInt64(r *http.Request, opts ParamInt64Opts) (int
 	val := uh.queryParam(r, opts.P.Name)
 	return opts.parse(val)
 }
+
+func (uh baseHandler) queryParamBool(r *http.Request, opts ParamOpts) (bool, error) {
+	val := uh.queryParam(r, opts.Name)
+	if val == "" {
+		if opts.Optional {
+			return false, nil
+		}
+		return false, ErrMissingRequired
+	}
+
+	parsed, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrBadFormat, err)
+	}
+
+	return parsed, nil
+}
+
+func (uh baseHandler) queryParamTime(r *http.Request, opts ParamOpts) (time.Time, error) {
+	val := uh.queryParam(r, opts.Name)
+	if val == "" {
+		if opts.Optional {
+			return time.Time{}, nil
+		}
+		return time.Time{}, ErrMissingRequired
+	}
+
+	parsed, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrBadFormat, err)
+	}
+
+	return parsed, nil
+}
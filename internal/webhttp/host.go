@@ -0,0 +1,41 @@
+package webhttp
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// NewHostRouter returns a router that passes the requests of unknown hosts to the `fallback`.
+func NewHostRouter(fallback http.Handler) *HostRouter {
+	return &HostRouter{hosts: map[string]http.Handler{}, fallback: fallback}
+}
+
+// HostRouter dispatches requests by the `Host` header, so the API and the redirects can be served by a single listener.
+type HostRouter struct {
+	hosts    map[string]http.Handler
+	fallback http.Handler
+}
+
+// Handle passes the requests made to the `host` to the `handler`, the port of the host is ignored.
+func (hr *HostRouter) Handle(host string, handler http.Handler) {
+	hr.hosts[normalizeHost(host)] = handler
+}
+
+func (hr *HostRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if handler, ok := hr.hosts[normalizeHost(r.Host)]; ok {
+		handler.ServeHTTP(w, r)
+		return
+	}
+
+	hr.fallback.ServeHTTP(w, r)
+}
+
+// normalizeHost drops the port and the trailing dot of the `host` and converts it to lower case.
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
@@ -0,0 +1,44 @@
+package webhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostRouter(t *testing.T) {
+	named := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(name))
+		})
+	}
+
+	router := NewHostRouter(named("api"))
+	router.Handle("sho.rt", named("resolver"))
+	router.Handle("Go.Example.com:8080", named("resolver"))
+
+	for _, tc := range []struct {
+		host string
+		exp  string
+	}{
+		{host: "sho.rt", exp: "resolver"},
+		{host: "SHO.RT:443", exp: "resolver"},
+		{host: "sho.rt.", exp: "resolver"},
+		{host: "go.example.com", exp: "resolver"},
+		{host: "api.sho.rt", exp: "api"},
+		{host: "10.0.0.12:8080", exp: "api"},
+		{host: "[::1]:8080", exp: "api"},
+	} {
+		t.Run(tc.host, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://localhost/abc", nil)
+			req.Host = tc.host
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			require.Equal(t, tc.exp, resp.Body.String())
+		})
+	}
+}
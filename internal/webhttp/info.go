@@ -1,22 +1,34 @@
 package webhttp
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 
 	"github.com/go-chi/chi"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/health"
 )
 
+// HealthChecker checks the dependencies of the service.
+type HealthChecker interface {
+	Run(ctx context.Context) health.Report
+}
+
 // InfoHandler handles requests about service status.
-type InfoHandler struct{}
+type InfoHandler struct {
+	// Health decides if the service is ready, the service is always ready if it is not set.
+	Health HealthChecker
+}
 
 // Register creates a binding between method handlers and endpoints.
 func (ih InfoHandler) Register(router chi.Router) {
-	router.Method(http.MethodGet, "/-/liveness", http.HandlerFunc(ih.Readiness))
-	router.Method(http.MethodGet, "/-/readiness", http.HandlerFunc(ih.Liveness))
+	router.Method(http.MethodGet, "/-/liveness", http.HandlerFunc(ih.Liveness))
+	router.With(ProducesJSON).Method(http.MethodGet, "/-/readiness", http.HandlerFunc(ih.Readiness))
 	router.With(ProducesJSON).Method(http.MethodGet, "/-/version", http.HandlerFunc(ih.Version))
+	router.Method(http.MethodGet, "/-/metrics", promhttp.Handler())
 }
 
 // Liveness returns HTTP status `200` for each request.
@@ -25,10 +37,19 @@ func (InfoHandler) Liveness(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-// Readiness returns HTTP status `200` for each request.
+// Readiness returns HTTP status `200` if all health checks pass and `503` otherwise,
+// the body lists the status and latency of each check.
 // It is used to determine if the service is ready to start receiving requests.
-func (InfoHandler) Readiness(w http.ResponseWriter, _ *http.Request) {
-	w.WriteHeader(http.StatusOK)
+func (ih InfoHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	report := health.Report{Status: health.StatusUp}
+	if ih.Health != nil {
+		report = ih.Health.Run(r.Context())
+	}
+
+	if report.Status != health.StatusUp {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(Mapper{}.report2ReadinessResp(report))
 }
 
 // Version return information about binary: version, commit sha, timestamp of compilation
@@ -0,0 +1,110 @@
+package webhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pavelmemory/jobtome/internal/health"
+	"github.com/pavelmemory/jobtome/internal/logging"
+)
+
+func TestInfoHandler_Liveness(t *testing.T) {
+	checker := health.NewChecker(time.Second)
+	checker.Shutdown()
+
+	r := NewRouter(logging.NewTestLogger())
+	InfoHandler{Health: checker}.Register(r)
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "http://localhost/-/liveness", nil))
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Zero(t, resp.Body.Len())
+}
+
+func TestInfoHandler_Readiness(t *testing.T) {
+	spec := loadSpec(t)
+	pass := func(context.Context) error { return nil }
+
+	for _, tc := range []struct {
+		name     string
+		checker  func() HealthChecker
+		status   int
+		expState string
+		expErrs  map[string]string
+	}{
+		{
+			name:     "no checks",
+			checker:  func() HealthChecker { return nil },
+			status:   http.StatusOK,
+			expState: health.StatusUp,
+		},
+		{
+			name: "ready",
+			checker: func() HealthChecker {
+				checker := health.NewChecker(time.Second)
+				checker.Add("ping", pass)
+				return checker
+			},
+			status:   http.StatusOK,
+			expState: health.StatusUp,
+			expErrs:  map[string]string{"shutdown": "", "ping": ""},
+		},
+		{
+			name: "failed check",
+			checker: func() HealthChecker {
+				checker := health.NewChecker(time.Second)
+				checker.Add("ping", pass)
+				checker.Add("migrations", func(context.Context) error { return errors.New("database is locked") })
+				return checker
+			},
+			status:   http.StatusServiceUnavailable,
+			expState: health.StatusDown,
+			expErrs:  map[string]string{"shutdown": "", "ping": "", "migrations": "database is locked"},
+		},
+		{
+			name: "shutting down",
+			checker: func() HealthChecker {
+				checker := health.NewChecker(time.Second)
+				checker.Shutdown()
+				return checker
+			},
+			status:   http.StatusServiceUnavailable,
+			expState: health.StatusDown,
+			expErrs:  map[string]string{"shutdown": "shutting down"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRouter(logging.NewTestLogger())
+			InfoHandler{Health: tc.checker()}.Register(r)
+
+			req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/-/readiness", nil)
+			input, err := spec.validateRequest(t, req)
+			require.NoError(t, err)
+
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			require.Equal(t, tc.status, resp.Code)
+			require.Equal(t, "application/json; charset=utf-8", resp.Header().Get("content-type"))
+			spec.validateResponse(t, input, resp)
+
+			var act ReadinessResp
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&act))
+			require.Equal(t, tc.expState, act.Status)
+			require.Len(t, act.Checks, len(tc.expErrs))
+			for _, check := range act.Checks {
+				expErr, ok := tc.expErrs[check.Name]
+				require.True(t, ok, check.Name)
+				require.Equal(t, expErr, check.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package webhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/auth"
+	"github.com/pavelmemory/jobtome/internal/logging"
+)
+
+//go:generate mockgen -source=keys.go -destination mock_keys.go -package webhttp KeyService
+
+// Authenticator identifies the caller by the secret of the API key.
+type Authenticator interface {
+	// Authenticate returns the active API key with the `secret`.
+	Authenticate(ctx context.Context, secret string) (auth.Key, error)
+}
+
+// KeyService provides set of operations to manage API keys.
+type KeyService interface {
+	Authenticator
+	// Issue creates a new API key in the workspace and returns it together with its secret.
+	Issue(ctx context.Context, name, workspace string, scopes []auth.Scope) (auth.Key, string, error)
+	// List returns API keys of the workspace including the revoked ones, the empty workspace matches all keys.
+	List(ctx context.Context, workspace string) ([]auth.Key, error)
+	// Revoke disables the API key of the workspace by its unique identifier, the empty workspace matches all keys.
+	Revoke(ctx context.Context, workspace string, id int64) error
+}
+
+// Authorize returns a middleware function that lets through only the requests made with an API key granted the `scope`.
+// The key is accepted from `Authorization: Bearer <key>` or `X-API-Key: <key>` header.
+// The authenticated key is propagated with the request's context, see `auth.FromContext`.
+// A nil `authenticator` disables the check, so all requests are let through.
+func Authorize(authenticator Authenticator, scope auth.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if authenticator == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := logging.FromContext(r.Context())
+
+			secret := apiKey(r)
+			if secret == "" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="jobtome"`)
+				WriteError(w, logger, fmt.Errorf("no api key: %w", internal.ErrUnauthenticated))
+				return
+			}
+
+			key, err := authenticator.Authenticate(r.Context(), secret)
+			if err != nil {
+				logger.WithError(err).Error("authenticate api key")
+				if errors.Is(err, internal.ErrUnauthenticated) {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="jobtome", error="invalid_token"`)
+				}
+				WriteError(w, logger, err)
+				return
+			}
+
+			logger = logger.WithInt64("api_key_id", key.ID)
+			if !key.Allows(scope) {
+				logger.WithString("scope", string(scope)).Error("api key is not allowed")
+				WriteError(w, logger, fmt.Errorf("scope %q is not granted: %w", scope, internal.ErrForbidden))
+				return
+			}
+
+			ctx := logging.ToContext(auth.ToContext(r.Context(), key), logger)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// apiKey returns the secret of the API key the request is made with or an empty string.
+func apiKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+
+	const bearer = "bearer "
+	header := r.Header.Get("Authorization")
+	if len(header) > len(bearer) && strings.EqualFold(header[:len(bearer)], bearer) {
+		return strings.TrimSpace(header[len(bearer):])
+	}
+
+	return ""
+}
+
+// NewKeyHandler returns HTTP handler of the API keys management initialized with provided service abstraction.
+func NewKeyHandler(keyService KeyService) KeyHandler {
+	return KeyHandler{keyService: keyService}
+}
+
+// KeyHandler handles requests for the API keys, all of them require the admin scope.
+// The admin key manages only the keys of its own workspace, the operator key manages the keys of all workspaces.
+type KeyHandler struct {
+	baseHandler
+	keyService KeyService
+	mapper     Mapper
+}
+
+func (kh KeyHandler) urlPrefix() string {
+	return "/api/keys"
+}
+
+// Register creates a binding between method handlers and endpoints.
+func (kh KeyHandler) Register(router chi.Router) {
+	router = router.With(LogRequest(), Authorize(kh.keyService, auth.ScopeAdmin))
+	router.With(ProducesJSON, AcceptsJSON).Method(http.MethodPost, kh.urlPrefix(), http.HandlerFunc(kh.Issue))
+	router.With(ProducesJSON).Method(http.MethodGet, kh.urlPrefix(), http.HandlerFunc(kh.List))
+	router.Method(http.MethodDelete, kh.urlPrefix()+"/{id}", http.HandlerFunc(kh.Revoke))
+}
+
+func (kh KeyHandler) logger(ctx context.Context, method string) logging.Logger {
+	return logging.FromContext(ctx).WithString("component", "KeyHandler").WithString("method", method)
+}
+
+// managed returns the caller's key and the workspace it manages the keys of, the empty workspace stands for all of them.
+func (kh KeyHandler) managed(ctx context.Context) (auth.Key, string, error) {
+	caller, ok := auth.FromContext(ctx)
+	if !ok {
+		return auth.Key{}, "", fmt.Errorf("no api key: %w", internal.ErrForbidden)
+	}
+
+	if caller.Allows(auth.ScopeOperator) {
+		return caller, "", nil
+	}
+
+	return caller, caller.Workspace, nil
+}
+
+func (kh KeyHandler) Issue(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := kh.logger(ctx, "Issue")
+
+	logger.Debug("start")
+	defer logger.Debug("end")
+
+	var req IssueKeyReq
+	if err := Decode(r.Body, &req); err != nil {
+		logger.WithError(err).Error("decode payload")
+		ErrorResponse{Cause: err, StatusCode: http.StatusBadRequest}.Write(logger, w)
+		return
+	}
+
+	scopes, err := auth.ParseScopes(req.Scopes)
+	if err != nil {
+		logger.WithError(err).Error("parse scopes")
+		ErrorResponse{Cause: err, StatusCode: http.StatusBadRequest}.Write(logger, w)
+		return
+	}
+
+	caller, _, err := kh.managed(ctx)
+	if err != nil {
+		logger.WithError(err).Error("retrieve caller")
+		WriteError(w, logger, err)
+		return
+	}
+
+	workspace := req.Workspace
+	if workspace == "" {
+		workspace = caller.Workspace
+	}
+
+	if !caller.Manages(workspace) {
+		err := fmt.Errorf("workspace %q is not managed: %w", workspace, internal.ErrForbidden)
+		logger.WithError(err).Error("check workspace")
+		WriteError(w, logger, err)
+		return
+	}
+
+	for _, scope := range scopes {
+		if !caller.Allows(scope) {
+			err := fmt.Errorf("scope %q is not granted: %w", scope, internal.ErrForbidden)
+			logger.WithError(err).Error("check scopes")
+			WriteError(w, logger, err)
+			return
+		}
+	}
+
+	key, secret, err := kh.keyService.Issue(ctx, req.Name, workspace, scopes)
+	if err != nil {
+		logger.WithError(err).Error("issue api key")
+		WriteError(w, logger, err)
+		return
+	}
+
+	resp := IssueKeyResp{GetKeyResp: kh.mapper.key2GetKeyResp(key), Key: secret}
+	w.Header().Set("location", kh.urlPrefix()+"/"+strconv.FormatInt(key.ID, 10))
+	w.WriteHeader(http.StatusCreated)
+	if err := Encode(w, resp); err != nil {
+		logger.WithError(err).Error("encode api key")
+		return
+	}
+}
+
+func (kh KeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := kh.logger(ctx, "List")
+
+	logger.Debug("start")
+	defer logger.Debug("end")
+
+	_, workspace, err := kh.managed(ctx)
+	if err != nil {
+		logger.WithError(err).Error("retrieve caller")
+		WriteError(w, logger, err)
+		return
+	}
+
+	keys, err := kh.keyService.List(ctx, workspace)
+	if err != nil {
+		logger.WithError(err).Error("list api keys")
+		WriteError(w, logger, err)
+		return
+	}
+
+	resp := make([]GetKeyResp, len(keys))
+	for i, key := range keys {
+		resp[i] = kh.mapper.key2GetKeyResp(key)
+	}
+
+	if err := Encode(w, resp); err != nil {
+		logger.WithError(err).Error("encode api keys")
+		ErrorResponse{Cause: err, StatusCode: http.StatusInternalServerError}.Write(logger, w)
+		return
+	}
+}
+
+func (kh KeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := kh.logger(ctx, "Revoke")
+
+	logger.Debug("start")
+	defer logger.Debug("end")
+
+	id, err := kh.pathParamInt64(r, ParamInt64Opts{P: ParamOpts{Name: "id"}})
+	if err != nil {
+		cause := fmt.Errorf(`parameter "id": %w`, err)
+		logger.WithError(cause).Error("extract path parameter")
+		ErrorResponse{Cause: cause, StatusCode: http.StatusBadRequest, Errors: paramErrors("id", err)}.Write(logger, w)
+		return
+	}
+
+	_, workspace, err := kh.managed(ctx)
+	if err != nil {
+		logger.WithError(err).Error("retrieve caller")
+		WriteError(w, logger, err)
+		return
+	}
+
+	if err := kh.keyService.Revoke(ctx, workspace, id); err != nil {
+		logger.WithError(err).WithInt64("id", id).Error("revoke api key")
+		WriteError(w, logger, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
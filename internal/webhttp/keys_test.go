@@ -0,0 +1,335 @@
+package webhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/auth"
+	"github.com/pavelmemory/jobtome/internal/logging"
+	"github.com/pavelmemory/jobtome/internal/shorten"
+)
+
+func TestAuthorize(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		header  string
+		value   string
+		key     auth.Key
+		err     error
+		expCode int
+	}{
+		{name: "no key", expCode: http.StatusUnauthorized},
+		{name: "unknown key", header: "Authorization", value: "Bearer jt_unknown", err: internal.ErrUnauthenticated, expCode: http.StatusUnauthorized},
+		{name: "not granted", header: "Authorization", value: "Bearer jt_read", key: auth.Key{ID: 1, Scopes: []auth.Scope{auth.ScopeShortenRead}}, expCode: http.StatusForbidden},
+		{name: "bearer", header: "Authorization", value: "bearer jt_delete", key: auth.Key{ID: 1, Scopes: []auth.Scope{auth.ScopeShortenDelete}}, expCode: http.StatusNoContent},
+		{name: "api key header", header: "X-API-Key", value: "jt_delete", key: auth.Key{ID: 1, Scopes: []auth.Scope{auth.ScopeShortenDelete}}, expCode: http.StatusNoContent},
+		{name: "admin", header: "X-API-Key", value: "jt_admin", key: auth.Key{ID: 1, Scopes: []auth.Scope{auth.ScopeAdmin}}, expCode: http.StatusNoContent},
+		{name: "failure", header: "X-API-Key", value: "jt_delete", err: errors.New("connection lost"), expCode: http.StatusInternalServerError},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRouter(logging.NewTestLogger())
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAuthenticator := NewMockAuthenticator(ctrl)
+			if tc.header != "" {
+				secret := strings.TrimPrefix(strings.TrimPrefix(tc.value, "Bearer "), "bearer ")
+				mockAuthenticator.EXPECT().Authenticate(gomock.Any(), secret).Return(tc.key, tc.err)
+			}
+
+			mockShortenService := NewMockShortenService(ctrl)
+			if tc.expCode == http.StatusNoContent {
+				mockShortenService.EXPECT().Delete(gomock.Any(), int64(1)).Return(nil)
+			}
+
+			NewShortenHandler(mockShortenService, mockAuthenticator, "https://sho.rt").Register(r)
+
+			req := httptest.NewRequest(http.MethodDelete, "http://localhost/api/shorten/1", nil)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+			resp := httptest.NewRecorder()
+
+			r.ServeHTTP(resp, req)
+
+			require.Equal(t, tc.expCode, resp.Code)
+			if tc.expCode == http.StatusUnauthorized {
+				require.True(t, strings.HasPrefix(resp.Header().Get("WWW-Authenticate"), "Bearer"))
+			}
+		})
+	}
+}
+
+func TestInWorkspace(t *testing.T) {
+	r := NewRouter(logging.NewTestLogger())
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuthenticator := NewMockAuthenticator(ctrl)
+	mockAuthenticator.EXPECT().Authenticate(gomock.Any(), "jt_marketing").
+		Return(auth.Key{ID: 1, Name: "ci", Scopes: []auth.Scope{auth.ScopeShortenDelete}, Workspace: "marketing"}, nil)
+
+	mockShortenService := NewMockShortenService(ctrl)
+	mockShortenService.EXPECT().Delete(gomock.Any(), int64(1)).
+		DoAndReturn(func(ctx context.Context, _ int64) error {
+			require.Equal(t, "marketing", shorten.WorkspaceFrom(ctx))
+			require.Equal(t, "ci", shorten.OwnerFrom(ctx))
+			return nil
+		})
+
+	NewShortenHandler(mockShortenService, mockAuthenticator, "https://sho.rt").Register(r)
+
+	req := httptest.NewRequest(http.MethodDelete, "http://localhost/api/shorten/1", nil)
+	req.Header.Set("X-API-Key", "jt_marketing")
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusNoContent, resp.Code)
+}
+
+func TestKeyHandler(t *testing.T) {
+	admin := auth.Key{ID: 1, Name: "root", Scopes: []auth.Scope{auth.ScopeAdmin}, Workspace: "default"}
+	operator := auth.Key{ID: 4, Name: "ops", Scopes: []auth.Scope{auth.ScopeOperator}, Workspace: "default"}
+	createdAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("issue", func(t *testing.T) {
+		r := NewRouter(logging.NewTestLogger())
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockKeyService := NewMockKeyService(ctrl)
+		mockKeyService.EXPECT().Authenticate(gomock.Any(), "jt_admin").Return(admin, nil)
+		mockKeyService.EXPECT().Issue(gomock.Any(), "ci", "default", []auth.Scope{auth.ScopeShortenRead}).
+			Return(auth.Key{ID: 2, Name: "ci", Scopes: []auth.Scope{auth.ScopeShortenRead}, Workspace: "default", CreatedAt: createdAt}, "jt_secret", nil)
+
+		NewKeyHandler(mockKeyService).Register(r)
+
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/api/keys", strings.NewReader(`{"name":"ci","scopes":["shorten:read"]}`))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("X-API-Key", "jt_admin")
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusCreated, resp.Code)
+		require.Equal(t, "/api/keys/2", resp.Header().Get("location"))
+		require.JSONEq(t, `{"id":2,"name":"ci","scopes":["shorten:read"],"workspace":"default","created_at":"2030-01-02T03:04:05Z","key":"jt_secret"}`, resp.Body.String())
+	})
+
+	t.Run("issue in foreign workspace", func(t *testing.T) {
+		r := NewRouter(logging.NewTestLogger())
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockKeyService := NewMockKeyService(ctrl)
+		mockKeyService.EXPECT().Authenticate(gomock.Any(), "jt_admin").Return(admin, nil)
+
+		NewKeyHandler(mockKeyService).Register(r)
+
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/api/keys", strings.NewReader(`{"name":"ci","scopes":["shorten:read"],"workspace":"marketing"}`))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("X-API-Key", "jt_admin")
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("issue not granted scope", func(t *testing.T) {
+		r := NewRouter(logging.NewTestLogger())
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockKeyService := NewMockKeyService(ctrl)
+		mockKeyService.EXPECT().Authenticate(gomock.Any(), "jt_admin").Return(admin, nil)
+
+		NewKeyHandler(mockKeyService).Register(r)
+
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/api/keys", strings.NewReader(`{"name":"ops","scopes":["operator"]}`))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("X-API-Key", "jt_admin")
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("operator issue in workspace", func(t *testing.T) {
+		r := NewRouter(logging.NewTestLogger())
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockKeyService := NewMockKeyService(ctrl)
+		mockKeyService.EXPECT().Authenticate(gomock.Any(), "jt_operator").Return(operator, nil)
+		mockKeyService.EXPECT().Issue(gomock.Any(), "ci", "marketing", []auth.Scope{auth.ScopeShortenRead}).
+			Return(auth.Key{ID: 2, Name: "ci", Scopes: []auth.Scope{auth.ScopeShortenRead}, Workspace: "marketing", CreatedAt: createdAt}, "jt_secret", nil)
+
+		NewKeyHandler(mockKeyService).Register(r)
+
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/api/keys", strings.NewReader(`{"name":"ci","scopes":["shorten:read"],"workspace":"marketing"}`))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("X-API-Key", "jt_operator")
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusCreated, resp.Code)
+	})
+
+	t.Run("issue unknown scope", func(t *testing.T) {
+		r := NewRouter(logging.NewTestLogger())
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockKeyService := NewMockKeyService(ctrl)
+		mockKeyService.EXPECT().Authenticate(gomock.Any(), "jt_admin").Return(admin, nil)
+
+		NewKeyHandler(mockKeyService).Register(r)
+
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/api/keys", strings.NewReader(`{"name":"ci","scopes":["root"]}`))
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("X-API-Key", "jt_admin")
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		r := NewRouter(logging.NewTestLogger())
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockKeyService := NewMockKeyService(ctrl)
+		mockKeyService.EXPECT().Authenticate(gomock.Any(), "jt_admin").Return(admin, nil)
+		mockKeyService.EXPECT().List(gomock.Any(), "default").
+			Return([]auth.Key{{ID: 2, Name: "ci", Scopes: []auth.Scope{auth.ScopeShortenRead}, Workspace: "default", CreatedAt: createdAt, RevokedAt: createdAt}}, nil)
+
+		NewKeyHandler(mockKeyService).Register(r)
+
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/api/keys", nil)
+		req.Header.Set("X-API-Key", "jt_admin")
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		var keys []map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&keys))
+		require.Equal(t, []map[string]interface{}{{
+			"id":         float64(2),
+			"name":       "ci",
+			"scopes":     []interface{}{"shorten:read"},
+			"workspace":  "default",
+			"created_at": "2030-01-02T03:04:05Z",
+			"revoked_at": "2030-01-02T03:04:05Z",
+		}}, keys)
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		r := NewRouter(logging.NewTestLogger())
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockKeyService := NewMockKeyService(ctrl)
+		mockKeyService.EXPECT().Authenticate(gomock.Any(), "jt_admin").Return(admin, nil)
+		mockKeyService.EXPECT().Revoke(gomock.Any(), "default", int64(2)).Return(nil)
+
+		NewKeyHandler(mockKeyService).Register(r)
+
+		req := httptest.NewRequest(http.MethodDelete, "http://localhost/api/keys/2", nil)
+		req.Header.Set("X-API-Key", "jt_admin")
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusNoContent, resp.Code)
+	})
+
+	t.Run("revoke foreign", func(t *testing.T) {
+		r := NewRouter(logging.NewTestLogger())
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockKeyService := NewMockKeyService(ctrl)
+		mockKeyService.EXPECT().Authenticate(gomock.Any(), "jt_admin").Return(admin, nil)
+		mockKeyService.EXPECT().Revoke(gomock.Any(), "default", int64(5)).Return(internal.ErrNotFound)
+
+		NewKeyHandler(mockKeyService).Register(r)
+
+		req := httptest.NewRequest(http.MethodDelete, "http://localhost/api/keys/5", nil)
+		req.Header.Set("X-API-Key", "jt_admin")
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusNotFound, resp.Code)
+	})
+
+	t.Run("operator list", func(t *testing.T) {
+		r := NewRouter(logging.NewTestLogger())
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockKeyService := NewMockKeyService(ctrl)
+		mockKeyService.EXPECT().Authenticate(gomock.Any(), "jt_operator").Return(operator, nil)
+		mockKeyService.EXPECT().List(gomock.Any(), "").Return(nil, nil)
+
+		NewKeyHandler(mockKeyService).Register(r)
+
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/api/keys", nil)
+		req.Header.Set("X-API-Key", "jt_operator")
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.JSONEq(t, `[]`, resp.Body.String())
+	})
+
+	t.Run("not admin", func(t *testing.T) {
+		r := NewRouter(logging.NewTestLogger())
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockKeyService := NewMockKeyService(ctrl)
+		mockKeyService.EXPECT().Authenticate(gomock.Any(), "jt_writer").
+			Return(auth.Key{ID: 3, Scopes: []auth.Scope{auth.ScopeShortenWrite}}, nil)
+
+		NewKeyHandler(mockKeyService).Register(r)
+
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/api/keys", nil)
+		req.Header.Set("X-API-Key", "jt_writer")
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusForbidden, resp.Code)
+	})
+}
@@ -1,29 +1,211 @@
 package webhttp
 
 import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pavelmemory/jobtome/internal/auth"
+	"github.com/pavelmemory/jobtome/internal/health"
 	"github.com/pavelmemory/jobtome/internal/shorten"
 )
 
 type CreateShortenReq struct {
 	URL string `json:"url"`
+	// Alias is an optional code chosen by the user instead of the generated one.
+	Alias string `json:"alias,omitempty"`
+	// ExpiresAt is an optional moment the shorten stops working.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// MaxClicks is an optional number of redirects allowed for the shorten.
+	MaxClicks int64 `json:"max_clicks,omitempty"`
+}
+
+// UpdateShortenReq is a partial update of the shorten, omitted fields are left untouched.
+type UpdateShortenReq struct {
+	URL   *string `json:"url"`
+	Alias *string `json:"alias"`
+	// ExpiresAt set to null removes the expiration.
+	ExpiresAt OptionalTime `json:"expires_at"`
+	// MaxClicks set to 0 removes the limit.
+	MaxClicks *int64 `json:"max_clicks"`
+	// Tags replaces all tags of the shorten.
+	Tags *[]string `json:"tags"`
+}
+
+// OptionalTime distinguishes a time set to null from a missing one.
+type OptionalTime struct {
+	Set   bool
+	Value *time.Time
+}
+
+func (ot *OptionalTime) UnmarshalJSON(data []byte) error {
+	ot.Set = true
+	return json.Unmarshal(data, &ot.Value)
 }
 
 type GetShortenResp struct {
 	ID   int64  `json:"id"`
 	URL  string `json:"url"`
 	Hash string `json:"hash"`
+	// ShortURL is the public URL that redirects to the `URL`.
+	ShortURL  string     `json:"short_url"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	MaxClicks int64      `json:"max_clicks,omitempty"`
+	// Clicks is a number of redirects already made with the shorten.
+	Clicks    int64      `json:"clicks,omitempty"`
+	Tags      []string   `json:"tags,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	// DeletedAt is a moment the shorten was moved to the trash, it is set only for the deleted shortens.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// Owner is a name of the API key the shorten was created with.
+	Owner string `json:"owner,omitempty"`
 }
 
 type ListShortenResp []GetShortenResp
 
-type Mapper struct{}
+type CounterResp struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+type DailyCounterResp struct {
+	Day   string `json:"day"`
+	Count int64  `json:"count"`
+}
+
+type StatsShortenResp struct {
+	Total         int64              `json:"total"`
+	Daily         []DailyCounterResp `json:"daily"`
+	TopReferers   []CounterResp      `json:"top_referers"`
+	TopUserAgents []CounterResp      `json:"top_user_agents"`
+}
+
+type IssueKeyReq struct {
+	// Name describes the owner of the key.
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// Workspace of the key, the workspace of the issuing key if omitted.
+	Workspace string `json:"workspace,omitempty"`
+}
+
+type GetKeyResp struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	Workspace string     `json:"workspace"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// IssueKeyResp is the only response that contains the secret of the key.
+type IssueKeyResp struct {
+	GetKeyResp
+	Key string `json:"key"`
+}
+
+type CheckResp struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type ReadinessResp struct {
+	Status string      `json:"status"`
+	Checks []CheckResp `json:"checks"`
+}
+
+type Mapper struct {
+	// publicBaseURL is a URL of the resolver the short URLs are built from.
+	publicBaseURL string
+}
 
 func (m Mapper) createShortenReq2Entity(req CreateShortenReq) shorten.Entity {
-	return shorten.Entity{URL: req.URL}
+	entity := shorten.Entity{URL: req.URL, Hash: req.Alias, MaxClicks: req.MaxClicks}
+	if req.ExpiresAt != nil {
+		entity.ExpiresAt = *req.ExpiresAt
+	}
+
+	return entity
 }
 
-func (Mapper) entity2GetShortenResp(entity shorten.Entity) GetShortenResp {
-	return (GetShortenResp)(entity)
+func (Mapper) updateShortenReq2Patch(req UpdateShortenReq) shorten.Patch {
+	patch := shorten.Patch{URL: req.URL, Alias: req.Alias, MaxClicks: req.MaxClicks, Tags: req.Tags}
+	if req.ExpiresAt.Set {
+		expiresAt := time.Time{}
+		if req.ExpiresAt.Value != nil {
+			expiresAt = *req.ExpiresAt.Value
+		}
+		patch.ExpiresAt = &expiresAt
+	}
+
+	return patch
+}
+
+func (m Mapper) entity2GetShortenResp(entity shorten.Entity) GetShortenResp {
+	return GetShortenResp{
+		ID:        entity.ID,
+		URL:       entity.URL,
+		Hash:      entity.Hash,
+		ShortURL:  m.shortURL(entity.Hash),
+		CreatedAt: entity.CreatedAt.UTC(),
+		ExpiresAt: m.optionalTime(entity.ExpiresAt),
+		MaxClicks: entity.MaxClicks,
+		Clicks:    entity.Clicks,
+		Tags:      entity.Tags,
+		UpdatedAt: m.optionalTime(entity.UpdatedAt),
+		DeletedAt: m.optionalTime(entity.DeletedAt),
+		Owner:     entity.Owner,
+	}
+}
+
+func (m Mapper) key2GetKeyResp(key auth.Key) GetKeyResp {
+	resp := GetKeyResp{
+		ID:        key.ID,
+		Name:      key.Name,
+		Scopes:    make([]string, len(key.Scopes)),
+		Workspace: key.Workspace,
+		CreatedAt: key.CreatedAt.UTC(),
+		RevokedAt: m.optionalTime(key.RevokedAt),
+	}
+	for i, scope := range key.Scopes {
+		resp.Scopes[i] = string(scope)
+	}
+
+	return resp
+}
+
+func (Mapper) report2ReadinessResp(report health.Report) ReadinessResp {
+	resp := ReadinessResp{Status: report.Status, Checks: make([]CheckResp, len(report.Checks))}
+	for i, result := range report.Checks {
+		resp.Checks[i] = CheckResp{
+			Name:      result.Name,
+			Status:    result.Status,
+			LatencyMS: float64(result.Latency.Microseconds()) / 1000,
+		}
+		if result.Err != nil {
+			resp.Checks[i].Error = result.Err.Error()
+		}
+	}
+
+	return resp
+}
+
+// shortURL returns the public URL that redirects with the `hash`.
+func (m Mapper) shortURL(hash string) string {
+	return strings.TrimSuffix(m.publicBaseURL, "/") + "/" + url.PathEscape(hash)
+}
+
+// optionalTime returns nil for zero `t`, so it is omitted from the response.
+func (Mapper) optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	t = t.UTC()
+	return &t
 }
 
 func (m Mapper) entities2ListShortenResp(entities []shorten.Entity) ListShortenResp {
@@ -34,3 +216,26 @@ func (m Mapper) entities2ListShortenResp(entities []shorten.Entity) ListShortenR
 
 	return res
 }
+
+func (m Mapper) stats2StatsShortenResp(stats shorten.Stats) StatsShortenResp {
+	res := StatsShortenResp{
+		Total:         stats.Total,
+		Daily:         make([]DailyCounterResp, len(stats.Daily)),
+		TopReferers:   m.counters2CounterResps(stats.TopReferers),
+		TopUserAgents: m.counters2CounterResps(stats.TopUserAgents),
+	}
+	for i, daily := range stats.Daily {
+		res.Daily[i] = DailyCounterResp{Day: daily.Day.Format("2006-01-02"), Count: daily.Count}
+	}
+
+	return res
+}
+
+func (Mapper) counters2CounterResps(counters []shorten.Counter) []CounterResp {
+	res := make([]CounterResp, len(counters))
+	for i, counter := range counters {
+		res[i] = CounterResp{Value: counter.Value, Count: counter.Count}
+	}
+
+	return res
+}
@@ -0,0 +1,61 @@
+package webhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/logging"
+)
+
+func TestInstrument(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockShortenService := NewMockShortenService(ctrl)
+	mockShortenService.EXPECT().Resolve(gomock.Any(), "hash", gomock.Any()).Return("https://example.com", nil)
+	mockShortenService.EXPECT().Resolve(gomock.Any(), "unknown", gomock.Any()).Return("", internal.ErrNotFound)
+
+	r := NewRouter(logging.NewTestLogger())
+	NewResolverHandler(mockShortenService).Register(r)
+
+	redirected := requestsTotal.With(prometheus.Labels{"route": "/{hash}", "method": http.MethodGet, "status": "307"})
+	notFound := requestsTotal.With(prometheus.Labels{"route": "/{hash}", "method": http.MethodGet, "status": "404"})
+	unmatched := requestsTotal.With(prometheus.Labels{"route": unmatchedRoute, "method": http.MethodGet, "status": "501"})
+	expRedirected, expNotFound, expUnmatched := testutil.ToFloat64(redirected)+1, testutil.ToFloat64(notFound)+1, testutil.ToFloat64(unmatched)+1
+
+	for _, target := range []string{"http://localhost/hash", "http://localhost/unknown", "http://localhost/a/b"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	require.Equal(t, expRedirected, testutil.ToFloat64(redirected))
+	require.Equal(t, expNotFound, testutil.ToFloat64(notFound))
+	require.Equal(t, expUnmatched, testutil.ToFloat64(unmatched))
+}
+
+func TestInfoHandler_Metrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockShortenService := NewMockShortenService(ctrl)
+	mockShortenService.EXPECT().Resolve(gomock.Any(), gomock.Any(), gomock.Any()).Return("https://example.com", nil)
+
+	r := NewRouter(logging.NewTestLogger())
+	InfoHandler{}.Register(r)
+	NewResolverHandler(mockShortenService).Register(r)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost/hash", nil))
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "http://localhost/-/metrics", nil))
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.True(t, strings.Contains(resp.Body.String(), `jobtome_http_requests_total{method="GET",route="/{hash}",status="307"}`))
+	require.True(t, strings.Contains(resp.Body.String(), `jobtome_http_request_duration_seconds_bucket{method="GET",route="/{hash}",status="307"`))
+}
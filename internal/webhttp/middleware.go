@@ -1,24 +1,67 @@
 package webhttp
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"mime"
 	"net/http"
 	"strings"
 	"sync/atomic"
 
 	"github.com/go-chi/chi/middleware"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/pavelmemory/jobtome/internal/logging"
 )
 
+// requestIDHeader is a header the request ID is accepted from and sent back with.
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength limits the length of the request ID accepted from the client.
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// RequestID returns a middleware function that identifies each request with an ID sent back with `X-Request-ID` header.
+// The ID set by the client or a proxy with the same header is reused, otherwise a random one is generated.
+func RequestID() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(requestIDHeader)
+			if id == "" || len(id) > maxRequestIDLength || strings.IndexFunc(id, isNotPrintable) >= 0 {
+				random := make([]byte, 16)
+				_, _ = rand.Read(random)
+				id = hex.EncodeToString(random)
+			}
+
+			w.Header().Set(requestIDHeader, id)
+			ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func isNotPrintable(r rune) bool {
+	return r < '!' || r > '~'
+}
+
 // InjectLogger returns a middleware function that injects a logger into request's context.
-// It also propagates logger with a request unique sequence number, so all the logs
-// for a particular request could be grouped together.
+// It also propagates logger with a request unique sequence number and the request ID, see `RequestID`,
+// so all the logs for a particular request could be grouped together.
+// If the request is traced, see `Trace`, the logger is propagated with the trace and span IDs as well,
+// so the logs could be correlated with the traces across all the replicas.
 func InjectLogger(logger logging.Logger) func(http.Handler) http.Handler {
 	var reqSeq = new(int64)
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			logger := logger.WithInt64("req_seq", atomic.AddInt64(reqSeq, 1))
+			if id, ok := r.Context().Value(requestIDKey{}).(string); ok {
+				logger = logger.WithString("request_id", id)
+			}
+			if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+				logger = logger.WithString("trace_id", sc.TraceID().String()).WithString("span_id", sc.SpanID().String())
+			}
 			ctx := logging.ToContext(r.Context(), logger)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
@@ -71,20 +114,21 @@ func RequestContentType(contentType string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			contentType := r.Header.Get("content-type")
+			unsupported := ErrorResponse{StatusCode: http.StatusUnsupportedMediaType}
 			mediaType, params, err := mime.ParseMediaType(contentType)
 			if err != nil {
-				w.WriteHeader(http.StatusUnsupportedMediaType)
+				unsupported.Write(logging.FromContext(r.Context()), w)
 				return
 			}
 
 			if !strings.EqualFold(wantMediaType, mediaType) {
-				w.WriteHeader(http.StatusUnsupportedMediaType)
+				unsupported.Write(logging.FromContext(r.Context()), w)
 				return
 			}
 
 			for k, v := range params {
 				if !strings.EqualFold(v, wantParams[k]) {
-					w.WriteHeader(http.StatusUnsupportedMediaType)
+					unsupported.Write(logging.FromContext(r.Context()), w)
 					return
 				}
 			}
@@ -133,5 +177,10 @@ func (rw *responseContentTypeWrapper) WriteHeader(statusCode int) {
 		rw.contentType = ""
 	}
 
+	// the header can't be changed once the status is written, so it is set for the body that may follow
+	if statusCode != http.StatusNoContent && rw.Header().Get("content-type") == "" && rw.contentType != "" {
+		rw.ResponseWriter.Header().Set("content-type", rw.contentType)
+	}
+
 	rw.ResponseWriter.WriteHeader(statusCode)
 }
@@ -6,11 +6,9 @@ package webhttp
 
 import (
 	context "context"
-	reflect "reflect"
-
 	gomock "github.com/golang/mock/gomock"
-
 	shorten "github.com/pavelmemory/jobtome/internal/shorten"
+	reflect "reflect"
 )
 
 // MockShortenService is a mock of ShortenService interface
@@ -67,18 +65,33 @@ func (mr *MockShortenServiceMockRecorder) Get(ctx, id interface{}) *gomock.Call
 }
 
 // List mocks base method
-func (m *MockShortenService) List(ctx context.Context, pager shorten.Pager) ([]shorten.Entity, error) {
+func (m *MockShortenService) List(ctx context.Context, filter shorten.Filter, pager shorten.Pager) ([]shorten.Entity, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "List", ctx, pager)
+	ret := m.ctrl.Call(m, "List", ctx, filter, pager)
 	ret0, _ := ret[0].([]shorten.Entity)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // List indicates an expected call of List
-func (mr *MockShortenServiceMockRecorder) List(ctx, pager interface{}) *gomock.Call {
+func (mr *MockShortenServiceMockRecorder) List(ctx, filter, pager interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockShortenService)(nil).List), ctx, filter, pager)
+}
+
+// Update mocks base method
+func (m *MockShortenService) Update(ctx context.Context, id int64, patch shorten.Patch) (shorten.Entity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, id, patch)
+	ret0, _ := ret[0].(shorten.Entity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update
+func (mr *MockShortenServiceMockRecorder) Update(ctx, id, patch interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockShortenService)(nil).List), ctx, pager)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockShortenService)(nil).Update), ctx, id, patch)
 }
 
 // Delete mocks base method
@@ -95,17 +108,76 @@ func (mr *MockShortenServiceMockRecorder) Delete(ctx, id interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockShortenService)(nil).Delete), ctx, id)
 }
 
+// Trash mocks base method
+func (m *MockShortenService) Trash(ctx context.Context, pager shorten.Pager) ([]shorten.Entity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Trash", ctx, pager)
+	ret0, _ := ret[0].([]shorten.Entity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Trash indicates an expected call of Trash
+func (mr *MockShortenServiceMockRecorder) Trash(ctx, pager interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Trash", reflect.TypeOf((*MockShortenService)(nil).Trash), ctx, pager)
+}
+
+// Restore mocks base method
+func (m *MockShortenService) Restore(ctx context.Context, id int64) (shorten.Entity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, id)
+	ret0, _ := ret[0].(shorten.Entity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore
+func (mr *MockShortenServiceMockRecorder) Restore(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockShortenService)(nil).Restore), ctx, id)
+}
+
+// Purge mocks base method
+func (m *MockShortenService) Purge(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Purge indicates an expected call of Purge
+func (mr *MockShortenServiceMockRecorder) Purge(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockShortenService)(nil).Purge), ctx, id)
+}
+
 // Resolve mocks base method
-func (m *MockShortenService) Resolve(ctx context.Context, hash string) (string, error) {
+func (m *MockShortenService) Resolve(ctx context.Context, hash string, click shorten.Click) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Resolve", ctx, hash)
+	ret := m.ctrl.Call(m, "Resolve", ctx, hash, click)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Resolve indicates an expected call of Resolve
-func (mr *MockShortenServiceMockRecorder) Resolve(ctx, hash interface{}) *gomock.Call {
+func (mr *MockShortenServiceMockRecorder) Resolve(ctx, hash, click interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resolve", reflect.TypeOf((*MockShortenService)(nil).Resolve), ctx, hash, click)
+}
+
+// Stats mocks base method
+func (m *MockShortenService) Stats(ctx context.Context, id int64, days int) (shorten.Stats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats", ctx, id, days)
+	ret0, _ := ret[0].(shorten.Stats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Stats indicates an expected call of Stats
+func (mr *MockShortenServiceMockRecorder) Stats(ctx, id, days interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resolve", reflect.TypeOf((*MockShortenService)(nil).Resolve), ctx, hash)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockShortenService)(nil).Stats), ctx, id, days)
 }
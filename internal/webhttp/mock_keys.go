@@ -0,0 +1,133 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: keys.go
+
+// Package webhttp is a generated GoMock package.
+package webhttp
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	auth "github.com/pavelmemory/jobtome/internal/auth"
+	reflect "reflect"
+)
+
+// MockAuthenticator is a mock of Authenticator interface
+type MockAuthenticator struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuthenticatorMockRecorder
+}
+
+// MockAuthenticatorMockRecorder is the mock recorder for MockAuthenticator
+type MockAuthenticatorMockRecorder struct {
+	mock *MockAuthenticator
+}
+
+// NewMockAuthenticator creates a new mock instance
+func NewMockAuthenticator(ctrl *gomock.Controller) *MockAuthenticator {
+	mock := &MockAuthenticator{ctrl: ctrl}
+	mock.recorder = &MockAuthenticatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockAuthenticator) EXPECT() *MockAuthenticatorMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method
+func (m *MockAuthenticator) Authenticate(ctx context.Context, secret string) (auth.Key, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", ctx, secret)
+	ret0, _ := ret[0].(auth.Key)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate
+func (mr *MockAuthenticatorMockRecorder) Authenticate(ctx, secret interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockAuthenticator)(nil).Authenticate), ctx, secret)
+}
+
+// MockKeyService is a mock of KeyService interface
+type MockKeyService struct {
+	ctrl     *gomock.Controller
+	recorder *MockKeyServiceMockRecorder
+}
+
+// MockKeyServiceMockRecorder is the mock recorder for MockKeyService
+type MockKeyServiceMockRecorder struct {
+	mock *MockKeyService
+}
+
+// NewMockKeyService creates a new mock instance
+func NewMockKeyService(ctrl *gomock.Controller) *MockKeyService {
+	mock := &MockKeyService{ctrl: ctrl}
+	mock.recorder = &MockKeyServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockKeyService) EXPECT() *MockKeyServiceMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method
+func (m *MockKeyService) Authenticate(ctx context.Context, secret string) (auth.Key, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", ctx, secret)
+	ret0, _ := ret[0].(auth.Key)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate
+func (mr *MockKeyServiceMockRecorder) Authenticate(ctx, secret interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockKeyService)(nil).Authenticate), ctx, secret)
+}
+
+// Issue mocks base method
+func (m *MockKeyService) Issue(ctx context.Context, name, workspace string, scopes []auth.Scope) (auth.Key, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Issue", ctx, name, workspace, scopes)
+	ret0, _ := ret[0].(auth.Key)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Issue indicates an expected call of Issue
+func (mr *MockKeyServiceMockRecorder) Issue(ctx, name, workspace, scopes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Issue", reflect.TypeOf((*MockKeyService)(nil).Issue), ctx, name, workspace, scopes)
+}
+
+// List mocks base method
+func (m *MockKeyService) List(ctx context.Context, workspace string) ([]auth.Key, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, workspace)
+	ret0, _ := ret[0].([]auth.Key)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List
+func (mr *MockKeyServiceMockRecorder) List(ctx, workspace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockKeyService)(nil).List), ctx, workspace)
+}
+
+// Revoke mocks base method
+func (m *MockKeyService) Revoke(ctx context.Context, workspace string, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, workspace, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke
+func (mr *MockKeyServiceMockRecorder) Revoke(ctx, workspace, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockKeyService)(nil).Revoke), ctx, workspace, id)
+}
@@ -0,0 +1,69 @@
+package webhttp
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+// OpenAPISpec is an OpenAPI 3 specification of the endpoints served by the service.
+//
+//go:embed openapi.json
+var OpenAPISpec []byte
+
+// swaggerUIAssets are the files of swagger-ui-dist 5.18.2 the Swagger UI page is rendered with,
+// they are embedded, so the page works without the internet access.
+//
+//go:embed swaggerui/swagger-ui.css swaggerui/swagger-ui-bundle.js
+var swaggerUIAssets embed.FS
+
+// swaggerUI is a page that renders the specification with the embedded Swagger UI.
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>jobtome API</title>
+  <link rel="stylesheet" href="/-/docs/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="/-/docs/swagger-ui-bundle.js"></script>
+  <script>
+    window.onload = () => { window.ui = SwaggerUIBundle({url: "/-/openapi.json", dom_id: "#swagger-ui"}); };
+  </script>
+</body>
+</html>
+`
+
+// OpenAPIHandler serves the OpenAPI specification of the service.
+type OpenAPIHandler struct {
+	// UI enables Swagger UI page at `/-/docs`.
+	UI bool
+}
+
+// Register creates a binding between method handlers and endpoints.
+func (oh OpenAPIHandler) Register(router chi.Router) {
+	router.With(ProducesJSON).Method(http.MethodGet, "/-/openapi.json", http.HandlerFunc(oh.Spec))
+	if oh.UI {
+		assets, err := fs.Sub(swaggerUIAssets, "swaggerui")
+		if err != nil {
+			panic(err) // the embedded directory always exists
+		}
+
+		router.Method(http.MethodGet, "/-/docs", http.HandlerFunc(oh.Docs))
+		router.Method(http.MethodGet, "/-/docs/{asset}", http.StripPrefix("/-/docs", http.FileServer(http.FS(assets))))
+	}
+}
+
+// Spec returns the specification.
+func (OpenAPIHandler) Spec(w http.ResponseWriter, _ *http.Request) {
+	_, _ = w.Write(OpenAPISpec)
+}
+
+// Docs returns Swagger UI page that renders the specification.
+func (OpenAPIHandler) Docs(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("content-type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(swaggerUI))
+}
@@ -0,0 +1,417 @@
+package webhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/auth"
+	"github.com/pavelmemory/jobtome/internal/logging"
+	"github.com/pavelmemory/jobtome/internal/shorten"
+)
+
+func TestOpenAPI_Routes(t *testing.T) {
+	spec := loadSpec(t)
+
+	api := NewRouter(logging.NewTestLogger())
+	NewShortenHandler(nil, nil, "https://sho.rt").Register(api)
+	NewKeyHandler(nil).Register(api)
+	InfoHandler{}.Register(api)
+	OpenAPIHandler{}.Register(api)
+
+	resolver := NewRouter(logging.NewTestLogger())
+	NewResolverHandler(nil).Register(resolver)
+
+	var routes []string
+	for _, router := range []chi.Router{api, resolver} {
+		require.NoError(t, chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+			routes = append(routes, method+" "+route)
+			return nil
+		}))
+	}
+	sort.Strings(routes)
+
+	var documented []string
+	for path, item := range spec.Paths.Map() {
+		for method := range item.Operations() {
+			documented = append(documented, method+" "+path)
+		}
+	}
+	sort.Strings(documented)
+
+	require.Equal(t, routes, documented)
+}
+
+func TestOpenAPI_Requests(t *testing.T) {
+	spec := loadSpec(t)
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	url, alias, maxClicks, tags := "https://example.com", "summer-sale", int64(10), []string{"promo"}
+
+	for schema, req := range map[string]interface{}{
+		"CreateShortenReq": CreateShortenReq{URL: url, Alias: alias, ExpiresAt: &expiresAt, MaxClicks: maxClicks},
+		"UpdateShortenReq": map[string]interface{}{"url": url, "alias": alias, "expires_at": nil, "max_clicks": maxClicks, "tags": tags},
+		"IssueKeyReq":      IssueKeyReq{Name: "ci", Scopes: []string{string(auth.ScopeShortenRead)}, Workspace: "marketing"},
+	} {
+		t.Run(schema, func(t *testing.T) {
+			data, err := json.Marshal(req)
+			require.NoError(t, err)
+			var value interface{}
+			require.NoError(t, json.Unmarshal(data, &value))
+
+			require.NoError(t, spec.Components.Schemas[schema].Value.VisitJSON(value))
+		})
+	}
+
+	t.Run("UpdateShortenReq fields", func(t *testing.T) {
+		// UpdateShortenReq is decoded only, so its fields are compared with the schema by their tags
+		var documented []string
+		for name := range spec.Components.Schemas["UpdateShortenReq"].Value.Properties {
+			documented = append(documented, name)
+		}
+		sort.Strings(documented)
+
+		data, err := json.Marshal(UpdateShortenReq{})
+		require.NoError(t, err)
+		var fields map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &fields))
+		var declared []string
+		for name := range fields {
+			declared = append(declared, name)
+		}
+		sort.Strings(declared)
+
+		require.Equal(t, documented, declared)
+	})
+}
+
+func TestOpenAPI_ShortenHandler(t *testing.T) {
+	spec := loadSpec(t)
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	full := shorten.Entity{
+		ID:        1,
+		URL:       "https://example.com",
+		Hash:      "1234567",
+		CreatedAt: expiresAt,
+		ExpiresAt: expiresAt,
+		MaxClicks: 10,
+		Clicks:    3,
+		Owner:     "ci",
+		Tags:      []string{"promo"},
+		UpdatedAt: expiresAt,
+	}
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		path   string
+		route  string
+		body   string
+		// invalid request doesn't match the specification and is expected to be rejected by the handler
+		invalid bool
+		mock    func(*MockShortenService)
+	}{
+		{
+			name:   "create",
+			method: http.MethodPost, path: "/api/shorten", route: "/api/shorten",
+			body: `{"url":"https://example.com"}`,
+			mock: func(m *MockShortenService) {
+				m.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				m.EXPECT().Get(gomock.Any(), int64(1)).Return(full, nil)
+			},
+		},
+		{
+			name:   "create invalid",
+			method: http.MethodPost, path: "/api/shorten", route: "/api/shorten",
+			body: `{"url":""}`,
+			mock: func(m *MockShortenService) {
+				m.EXPECT().Create(gomock.Any(), gomock.Any()).
+					Return(int64(0), shorten.ValidationError{Cause: internal.ErrBadInput, Details: map[string]interface{}{"url": "blank or empty"}})
+			},
+		},
+		{
+			name:   "create taken alias",
+			method: http.MethodPost, path: "/api/shorten", route: "/api/shorten",
+			body: `{"url":"https://example.com","alias":"summer-sale"}`,
+			mock: func(m *MockShortenService) {
+				m.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(0), internal.ErrNotUnique)
+			},
+		},
+		{
+			name:   "create not json",
+			method: http.MethodPost, path: "/api/shorten", route: "/api/shorten",
+			invalid: true,
+			mock:    func(m *MockShortenService) {},
+		},
+		{
+			name:   "get",
+			method: http.MethodGet, path: "/api/shorten/1", route: "/api/shorten/{id}",
+			mock: func(m *MockShortenService) {
+				m.EXPECT().Get(gomock.Any(), int64(1)).Return(full, nil)
+			},
+		},
+		{
+			name:   "get minimal",
+			method: http.MethodGet, path: "/api/shorten/1", route: "/api/shorten/{id}",
+			mock: func(m *MockShortenService) {
+				m.EXPECT().Get(gomock.Any(), int64(1)).Return(shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567"}, nil)
+			},
+		},
+		{
+			name:   "get bad id",
+			method: http.MethodGet, path: "/api/shorten/one", route: "/api/shorten/{id}",
+			invalid: true,
+			mock:    func(m *MockShortenService) {},
+		},
+		{
+			name:   "get not existing",
+			method: http.MethodGet, path: "/api/shorten/1", route: "/api/shorten/{id}",
+			mock: func(m *MockShortenService) {
+				m.EXPECT().Get(gomock.Any(), int64(1)).Return(shorten.Entity{}, internal.ErrNotFound)
+			},
+		},
+		{
+			name:   "list",
+			method: http.MethodGet, path: "/api/shorten?limit=2", route: "/api/shorten",
+			mock: func(m *MockShortenService) {
+				m.EXPECT().List(gomock.Any(), shorten.Filter{}, shorten.Pager{Limit: 2}).Return([]shorten.Entity{full, {ID: 2, URL: "https://stub.com", Hash: "2"}}, nil)
+			},
+		},
+		{
+			name:   "list filtered",
+			method: http.MethodGet, path: "/api/shorten?url=summer&status=active&sort=-created_at&created_from=2030-01-02T00:00:00Z", route: "/api/shorten",
+			mock: func(m *MockShortenService) {
+				m.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return([]shorten.Entity{full}, nil)
+			},
+		},
+		{
+			name:   "list bad filter",
+			method: http.MethodGet, path: "/api/shorten?url=ab", route: "/api/shorten",
+			invalid: true,
+			mock: func(m *MockShortenService) {
+				m.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil, shorten.ValidationError{Cause: internal.ErrBadInput, Details: map[string]interface{}{"url": "is shorter then 3 characters"}})
+			},
+		},
+		{
+			name:   "list bad cursor",
+			method: http.MethodGet, path: "/api/shorten?cursor=bad", route: "/api/shorten",
+			mock: func(m *MockShortenService) {},
+		},
+		{
+			name:   "list empty",
+			method: http.MethodGet, path: "/api/shorten", route: "/api/shorten",
+			mock: func(m *MockShortenService) {
+				m.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+			},
+		},
+		{
+			name:   "update",
+			method: http.MethodPatch, path: "/api/shorten/1", route: "/api/shorten/{id}",
+			body: `{"expires_at":null,"tags":["promo"]}`,
+			mock: func(m *MockShortenService) {
+				m.EXPECT().Update(gomock.Any(), int64(1), gomock.Any()).Return(full, nil)
+			},
+		},
+		{
+			name:   "delete",
+			method: http.MethodDelete, path: "/api/shorten/1", route: "/api/shorten/{id}",
+			mock: func(m *MockShortenService) {
+				m.EXPECT().Delete(gomock.Any(), int64(1)).Return(nil)
+			},
+		},
+		{
+			name:   "delete permanently",
+			method: http.MethodDelete, path: "/api/shorten/1?permanent=true", route: "/api/shorten/{id}",
+			mock: func(m *MockShortenService) {
+				m.EXPECT().Purge(gomock.Any(), int64(1)).Return(nil)
+			},
+		},
+		{
+			name:   "trash",
+			method: http.MethodGet, path: "/api/shorten/trash", route: "/api/shorten/trash",
+			mock: func(m *MockShortenService) {
+				deleted := full
+				deleted.DeletedAt = expiresAt
+				m.EXPECT().Trash(gomock.Any(), shorten.Pager{Limit: 50}).Return([]shorten.Entity{deleted}, nil)
+			},
+		},
+		{
+			name:   "restore",
+			method: http.MethodPost, path: "/api/shorten/1/restore", route: "/api/shorten/{id}/restore",
+			mock: func(m *MockShortenService) {
+				m.EXPECT().Restore(gomock.Any(), int64(1)).Return(full, nil)
+			},
+		},
+		{
+			name:   "restore not deleted",
+			method: http.MethodPost, path: "/api/shorten/1/restore", route: "/api/shorten/{id}/restore",
+			mock: func(m *MockShortenService) {
+				m.EXPECT().Restore(gomock.Any(), int64(1)).Return(shorten.Entity{}, internal.ErrNotFound)
+			},
+		},
+		{
+			name:   "stats",
+			method: http.MethodGet, path: "/api/shorten/1/stats?days=2", route: "/api/shorten/{id}/stats",
+			mock: func(m *MockShortenService) {
+				m.EXPECT().Stats(gomock.Any(), int64(1), 2).Return(shorten.Stats{
+					Total:         3,
+					Daily:         []shorten.DailyCounter{{Day: expiresAt.Truncate(24 * time.Hour), Count: 3}},
+					TopReferers:   []shorten.Counter{{Value: "https://referer.com", Count: 3}},
+					TopUserAgents: []shorten.Counter{{Value: "curl", Count: 3}},
+				}, nil)
+			},
+		},
+		{
+			name:   "stats failure",
+			method: http.MethodGet, path: "/api/shorten/1/stats", route: "/api/shorten/{id}/stats",
+			mock: func(m *MockShortenService) {
+				m.EXPECT().Stats(gomock.Any(), int64(1), 30).Return(shorten.Stats{}, fmt.Errorf("connection lost"))
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRouter(logging.NewTestLogger())
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockShortenService := NewMockShortenService(ctrl)
+			tc.mock(mockShortenService)
+			NewShortenHandler(mockShortenService, nil, "https://sho.rt").Register(r)
+
+			req := httptest.NewRequest(tc.method, "http://localhost:8080"+tc.path, strings.NewReader(tc.body))
+			if tc.body != "" {
+				req.Header.Set("content-type", "application/json")
+			}
+			resp := httptest.NewRecorder()
+
+			input, err := spec.validateRequest(t, req)
+			require.Equal(t, tc.route, input.Route.Path)
+			if tc.invalid {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			r.ServeHTTP(resp, req)
+
+			spec.validateResponse(t, input, resp)
+		})
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	r := NewRouter(logging.NewTestLogger())
+	OpenAPIHandler{UI: true}.Register(r)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/-/openapi.json", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, "application/json; charset=utf-8", resp.Header().Get("content-type"))
+	require.JSONEq(t, string(OpenAPISpec), resp.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "http://localhost/-/docs", nil)
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.True(t, strings.Contains(resp.Body.String(), "/-/openapi.json"))
+	require.False(t, strings.Contains(resp.Body.String(), "https://"), "the page must not load external resources")
+
+	for asset, contentType := range map[string]string{
+		"swagger-ui.css":       "text/css; charset=utf-8",
+		"swagger-ui-bundle.js": "text/javascript; charset=utf-8",
+	} {
+		require.True(t, strings.Contains(resp.Body.String(), "/-/docs/"+asset))
+
+		req = httptest.NewRequest(http.MethodGet, "http://localhost/-/docs/"+asset, nil)
+		assetResp := httptest.NewRecorder()
+		r.ServeHTTP(assetResp, req)
+
+		require.Equal(t, http.StatusOK, assetResp.Code, asset)
+		require.Equal(t, contentType, assetResp.Header().Get("content-type"), asset)
+		require.NotZero(t, assetResp.Body.Len(), asset)
+	}
+
+	for path, status := range map[string]int{
+		"/-/docs/":              http.StatusNotImplemented, // the assets aren't listed
+		"/-/docs/openapi.json":  http.StatusNotFound,
+		"/-/docs/swagger-ui.js": http.StatusNotFound,
+	} {
+		req = httptest.NewRequest(http.MethodGet, "http://localhost"+path, nil)
+		resp = httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, status, resp.Code, path)
+	}
+}
+
+// openAPISpec is a parsed OpenAPI specification with the router of the requests to its operations.
+type openAPISpec struct {
+	*openapi3.T
+	router routers.Router
+}
+
+// loadSpec parses the OpenAPI specification and verifies it is valid.
+func loadSpec(t *testing.T) openAPISpec {
+	spec, err := openapi3.NewLoader().LoadFromData(OpenAPISpec)
+	require.NoError(t, err)
+	require.NoError(t, spec.Validate(context.Background()))
+
+	router, err := legacy.NewRouter(spec)
+	require.NoError(t, err)
+
+	return openAPISpec{T: spec, router: router}
+}
+
+// validationOptions make the validation strict: the authentication is out of scope of the specification,
+// the requests are kept as they are sent and the undocumented statuses are reported.
+var validationOptions = &openapi3filter.Options{
+	AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+	SkipSettingDefaults:   true,
+	IncludeResponseStatus: true,
+	MultiError:            true,
+}
+
+// validateRequest finds the documented operation of the `req` and returns the error if the `req` doesn't match it.
+func (s openAPISpec) validateRequest(t *testing.T, req *http.Request) (*openapi3filter.RequestValidationInput, error) {
+	route, params, err := s.router.FindRoute(req)
+	require.NoError(t, err, "%s %s is not documented", req.Method, req.URL.Path)
+
+	input := &openapi3filter.RequestValidationInput{Request: req, PathParams: params, Route: route, Options: validationOptions}
+	return input, openapi3filter.ValidateRequest(context.Background(), input)
+}
+
+// validateResponse verifies the status, the content type and the body of the `resp` are documented for the operation.
+func (s openAPISpec) validateResponse(t *testing.T, input *openapi3filter.RequestValidationInput, resp *httptest.ResponseRecorder) {
+	if response := input.Route.Operation.Responses.Status(resp.Code); response != nil && len(response.Value.Content) == 0 {
+		require.Zero(t, resp.Body.Len(), "body of status %d of %s %s is not documented", resp.Code, input.Request.Method, input.Route.Path)
+	}
+
+	require.NoError(t, openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 resp.Code,
+		Header:                 resp.Header(),
+		Body:                   io.NopCloser(bytes.NewReader(resp.Body.Bytes())),
+		Options:                validationOptions,
+	}))
+}
@@ -0,0 +1,60 @@
+package webhttp
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/pavelmemory/jobtome/internal/shorten"
+)
+
+// Types of the problems sent back to the clients.
+const (
+	// ProblemTypeDefault means the problem has no additional semantics beyond the status code.
+	ProblemTypeDefault = "about:blank"
+	// ProblemTypeValidation means the request has invalid fields listed in the `errors` of the problem.
+	ProblemTypeValidation = "urn:jobtome:problem:validation"
+)
+
+// Problem is a body of the error response defined by RFC 7807.
+type Problem struct {
+	// Type identifies the kind of the problem.
+	Type string `json:"type"`
+	// Title is a short human-readable summary of the problem.
+	Title string `json:"title"`
+	// Status is a status code of the response.
+	Status int `json:"status"`
+	// Detail is a human-readable explanation of the occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+	// RequestID identifies the request in the logs, see `RequestID`.
+	RequestID string `json:"request_id,omitempty"`
+	// Errors lists the invalid fields of the request.
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes why the field of the request is invalid.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// fieldErrors returns the invalid fields reported by `shorten.ValidationError` sorted by their names.
+func fieldErrors(err error) []FieldError {
+	var validationErr shorten.ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Details) == 0 {
+		return nil
+	}
+
+	fields := make([]FieldError, 0, len(validationErr.Details))
+	for field, details := range validationErr.Details {
+		fields = append(fields, FieldError{Field: field, Message: fmt.Sprint(details)})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+
+	return fields
+}
+
+// paramErrors reports the request parameter `name` is invalid because of the `err`.
+func paramErrors(name string, err error) []FieldError {
+	return []FieldError{{Field: name, Message: err.Error()}}
+}
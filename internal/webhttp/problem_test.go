@@ -0,0 +1,120 @@
+package webhttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/logging"
+	"github.com/pavelmemory/jobtome/internal/shorten"
+)
+
+// productionLogger is a logger with disabled debugging mode.
+type productionLogger struct {
+	logging.Logger
+}
+
+func (productionLogger) IsDebug() bool {
+	return false
+}
+
+func TestWriteError(t *testing.T) {
+	validationErr := fmt.Errorf("create: %w", shorten.ValidationError{
+		Cause:   internal.ErrBadInput,
+		Details: map[string]interface{}{"url": "blank or empty", "alias": "reserved"},
+	})
+
+	for _, tc := range []struct {
+		name   string
+		debug  bool
+		err    error
+		exp    string
+		status int
+	}{
+		{
+			name:   "validation",
+			err:    validationErr,
+			status: http.StatusBadRequest,
+			exp: `{"type":"urn:jobtome:problem:validation","title":"Bad Request","status":400,"request_id":"req-1","errors":[
+				{"field":"alias","message":"reserved"},
+				{"field":"url","message":"blank or empty"}
+			]}`,
+		},
+		{
+			name:   "not found",
+			err:    fmt.Errorf("get: %w", internal.ErrNotFound),
+			status: http.StatusNotFound,
+			exp:    `{"type":"about:blank","title":"Not Found","status":404,"request_id":"req-1"}`,
+		},
+		{
+			name:   "internal",
+			err:    errors.New("connection lost"),
+			status: http.StatusInternalServerError,
+			exp:    `{"type":"about:blank","title":"Internal Server Error","status":500,"request_id":"req-1"}`,
+		},
+		{
+			name:   "internal in debugging mode",
+			debug:  true,
+			err:    errors.New("connection lost"),
+			status: http.StatusInternalServerError,
+			exp:    `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"connection lost","request_id":"req-1"}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var logger logging.Logger = productionLogger{Logger: logging.NewTestLogger()}
+			if tc.debug {
+				logger = logging.NewTestLogger()
+			}
+
+			resp := httptest.NewRecorder()
+			resp.Header().Set("X-Request-ID", "req-1")
+
+			WriteError(resp, logger, tc.err)
+
+			require.Equal(t, tc.status, resp.Code)
+			require.Equal(t, "application/problem+json", resp.Header().Get("content-type"))
+			require.JSONEq(t, tc.exp, resp.Body.String())
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	r := NewRouter(logging.NewTestLogger())
+
+	t.Run("generated", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/undefined", nil)
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusNotImplemented, resp.Code)
+		id := resp.Header().Get("X-Request-ID")
+		require.Len(t, id, 32)
+		require.JSONEq(t, `{"type":"about:blank","title":"Not Implemented","status":501,"request_id":"`+id+`"}`, resp.Body.String())
+	})
+
+	t.Run("propagated", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/undefined", nil)
+		req.Header.Set("X-Request-ID", "from-proxy")
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, "from-proxy", resp.Header().Get("X-Request-ID"))
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/undefined", nil)
+		req.Header.Set("X-Request-ID", "with spaces")
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Len(t, resp.Header().Get("X-Request-ID"), 32)
+	})
+}
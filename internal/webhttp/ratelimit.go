@@ -0,0 +1,176 @@
+package webhttp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/logging"
+	"github.com/pavelmemory/jobtome/internal/ratelimit"
+)
+
+// RateLimitPolicy defines how the requests of a router are limited.
+type RateLimitPolicy struct {
+	// Name separates the buckets of the routers that share the backend, e.g. "api" or "resolver".
+	Name string
+	// Backend keeps the state of the buckets.
+	Backend ratelimit.Backend
+	// PerIP limits all requests made from the same client IP.
+	PerIP ratelimit.Policy
+	// PerKey additionally limits requests made with the same API key, see `Authorize`.
+	PerKey ratelimit.Policy
+	// TrustedProxies are the networks of the reverse proxies allowed to set `X-Forwarded-For` header.
+	// The header of the requests made from other addresses is ignored, so it can't be used to evade the limits.
+	TrustedProxies []*net.IPNet
+}
+
+// RateLimit returns a middleware function that rejects requests exceeding the `policy` with `429 Too Many Requests`.
+// Responses carry `RateLimit-Limit`, `RateLimit-Remaining` and `RateLimit-Reset` headers of the most restrictive bucket,
+// rejected ones carry `Retry-After` as well. The client IP is propagated with the request's context,
+// so it is used instead of the proxy address, e.g. for the clicks.
+// Requests are let through if the backend fails, so the service stays available.
+func RateLimit(policy RateLimitPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := ClientIP(r, policy.TrustedProxies)
+			ctx := context.WithValue(r.Context(), clientIPKey{}, ip)
+			logger := logging.FromContext(ctx)
+
+			buckets := []rateBucket{{key: policy.Name + ":ip:" + ip, policy: policy.PerIP}}
+			if secret := apiKey(r); secret != "" {
+				// the digest is used as a key, so the secrets are not kept in the backend
+				sum := sha256.Sum256([]byte(secret))
+				buckets = append(buckets, rateBucket{key: policy.Name + ":key:" + hex.EncodeToString(sum[:]), policy: policy.PerKey})
+			}
+
+			var (
+				tightest ratelimit.Decision
+				limited  bool
+			)
+			for _, bucket := range buckets {
+				if !bucket.policy.Enabled() {
+					continue
+				}
+
+				decision, err := policy.Backend.Take(ctx, bucket.key, bucket.policy)
+				if err != nil {
+					logger.WithError(err).Error("rate limit")
+					continue
+				}
+
+				if !limited || decision.Remaining < tightest.Remaining {
+					tightest, limited = decision, true
+				}
+				if !decision.Allowed {
+					tightest = decision
+					break
+				}
+			}
+
+			if limited {
+				w.Header().Set("RateLimit-Limit", strconv.Itoa(tightest.Limit))
+				w.Header().Set("RateLimit-Remaining", strconv.Itoa(tightest.Remaining))
+				w.Header().Set("RateLimit-Reset", ceilSeconds(tightest.Reset))
+				if !tightest.Allowed {
+					w.Header().Set("Retry-After", ceilSeconds(tightest.RetryAfter))
+					logger.WithString("client_ip", ip).Debug("request is rate limited")
+					WriteError(w, logger, fmt.Errorf("too many requests: %w", internal.ErrRateLimited))
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// rateBucket is a bucket the request takes a token from.
+type rateBucket struct {
+	key    string
+	policy ratelimit.Policy
+}
+
+type clientIPKey struct{}
+
+// ClientIP returns an IP address of the client that made the request.
+// `X-Forwarded-For` header is trusted only if the request came from one of the `trusted` networks,
+// in this case the rightmost address not belonging to them is the client's one.
+func ClientIP(r *http.Request, trusted []*net.IPNet) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+
+	if !isTrusted(remote, trusted) {
+		return remote
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(forwarded[i])
+		if addr == "" {
+			continue
+		}
+		if net.ParseIP(addr) == nil {
+			// the header is malformed, the rest of it can't be trusted
+			break
+		}
+		if !isTrusted(addr, trusted) {
+			return addr
+		}
+		remote = addr
+	}
+
+	return remote
+}
+
+func isTrusted(addr string, trusted []*net.IPNet) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseNetworks parses the CIDR notations of the networks, single IP addresses are accepted as well.
+func ParseNetworks(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("parse network %q: %w", cidr, err)
+		}
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+// ceilSeconds formats the `d` as a number of whole seconds rounded up.
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,122 @@
+package webhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pavelmemory/jobtome/internal/logging"
+	"github.com/pavelmemory/jobtome/internal/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	do := func(r http.Handler, remoteAddr, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/-/version", nil)
+		req.RemoteAddr = remoteAddr
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("per ip", func(t *testing.T) {
+		r := NewRouter(logging.NewTestLogger(), RateLimit(RateLimitPolicy{
+			Name:    "api",
+			Backend: ratelimit.NewMemoryBackend(),
+			PerIP:   ratelimit.Policy{Rate: 0.1, Burst: 2},
+		}))
+		InfoHandler{}.Register(r)
+
+		resp := do(r, "192.0.2.1:1234", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, "2", resp.Header().Get("RateLimit-Limit"))
+		require.Equal(t, "1", resp.Header().Get("RateLimit-Remaining"))
+		require.Equal(t, "10", resp.Header().Get("RateLimit-Reset"))
+
+		require.Equal(t, http.StatusOK, do(r, "192.0.2.1:1234", "").Code)
+
+		resp = do(r, "192.0.2.1:1234", "")
+		require.Equal(t, http.StatusTooManyRequests, resp.Code)
+		require.Equal(t, "0", resp.Header().Get("RateLimit-Remaining"))
+		require.Equal(t, "10", resp.Header().Get("Retry-After"))
+
+		require.Equal(t, http.StatusOK, do(r, "192.0.2.2:1234", "").Code)
+	})
+
+	t.Run("per key", func(t *testing.T) {
+		r := NewRouter(logging.NewTestLogger(), RateLimit(RateLimitPolicy{
+			Name:    "api",
+			Backend: ratelimit.NewMemoryBackend(),
+			PerIP:   ratelimit.Policy{Rate: 1, Burst: 10},
+			PerKey:  ratelimit.Policy{Rate: 0.1, Burst: 1},
+		}))
+		InfoHandler{}.Register(r)
+
+		resp := do(r, "192.0.2.1:1234", "jt_a")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, "1", resp.Header().Get("RateLimit-Limit"))
+		require.Equal(t, "0", resp.Header().Get("RateLimit-Remaining"))
+
+		require.Equal(t, http.StatusTooManyRequests, do(r, "192.0.2.2:1234", "jt_a").Code)
+		require.Equal(t, http.StatusOK, do(r, "192.0.2.1:1234", "jt_b").Code)
+		require.Equal(t, http.StatusOK, do(r, "192.0.2.1:1234", "").Code)
+	})
+
+	t.Run("backend failure", func(t *testing.T) {
+		r := NewRouter(logging.NewTestLogger(), RateLimit(RateLimitPolicy{
+			Name:    "api",
+			Backend: failingBackend{},
+			PerIP:   ratelimit.Policy{Rate: 1, Burst: 1},
+		}))
+		InfoHandler{}.Register(r)
+
+		resp := do(r, "192.0.2.1:1234", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Empty(t, resp.Header().Get("RateLimit-Limit"))
+	})
+}
+
+type failingBackend struct{}
+
+func (failingBackend) Take(context.Context, string, ratelimit.Policy) (ratelimit.Decision, error) {
+	return ratelimit.Decision{}, errors.New("connection lost")
+}
+
+func TestClientIP(t *testing.T) {
+	trusted, err := ParseNetworks([]string{"10.0.0.0/8", "192.0.2.10"})
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name      string
+		remote    string
+		forwarded []string
+		exp       string
+	}{
+		{name: "direct", remote: "198.51.100.1:1234", exp: "198.51.100.1"},
+		{name: "untrusted proxy", remote: "198.51.100.1:1234", forwarded: []string{"203.0.113.1"}, exp: "198.51.100.1"},
+		{name: "trusted proxy", remote: "10.0.0.1:1234", forwarded: []string{"203.0.113.1"}, exp: "203.0.113.1"},
+		{name: "chain of proxies", remote: "10.0.0.1:1234", forwarded: []string{"1.1.1.1, 203.0.113.1", "192.0.2.10"}, exp: "203.0.113.1"},
+		{name: "spoofed", remote: "10.0.0.1:1234", forwarded: []string{"10.0.0.5, 203.0.113.1"}, exp: "203.0.113.1"},
+		{name: "malformed", remote: "10.0.0.1:1234", forwarded: []string{"203.0.113.1, unknown, 10.0.0.2"}, exp: "10.0.0.2"},
+		{name: "without header", remote: "10.0.0.1:1234", exp: "10.0.0.1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+			req.RemoteAddr = tc.remote
+			for _, value := range tc.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+
+			require.Equal(t, tc.exp, ClientIP(req, trusted))
+		})
+	}
+
+	_, err = ParseNetworks([]string{"10.0.0.0/33"})
+	require.Error(t, err)
+}
@@ -2,16 +2,35 @@ package webhttp
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi"
 
+	"github.com/pavelmemory/jobtome/internal"
 	"github.com/pavelmemory/jobtome/internal/logging"
+	"github.com/pavelmemory/jobtome/internal/shorten"
 )
 
+// blockedPage is shown instead of redirecting with the shorten blocked by the destination policy.
+// The destination is not shown, as it could be a phishing site.
+const blockedPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Link disabled</title>
+</head>
+<body>
+  <h1>This link has been disabled</h1>
+  <p>The destination of this short link violates the policy of the service, so you are not redirected to it.</p>
+</body>
+</html>
+`
+
 type Resolver interface {
-	// Resolve returns a full URL accessioned with the hash.
-	Resolve(ctx context.Context, hash string) (string, error)
+	// Resolve returns a full URL accessioned with the hash and registers the click.
+	Resolve(ctx context.Context, hash string, click shorten.Click) (string, error)
 }
 
 func NewResolverHandler(resolver Resolver) ResolverHandler {
@@ -37,9 +56,21 @@ func (rh ResolverHandler) Resolve(w http.ResponseWriter, r *http.Request) {
 	defer logger.Debug("end")
 
 	hash := rh.pathParam(r, "hash")
-	url, err := rh.resolver.Resolve(r.Context(), hash)
+	click := shorten.Click{
+		Referer:   r.Referer(),
+		UserAgent: r.UserAgent(),
+		IP:        rh.clientIP(r),
+	}
+	url, err := rh.resolver.Resolve(r.Context(), hash, click)
 	if err != nil {
 		logger.WithError(err).WithString("hash", hash).Error("resolve hash")
+		if errors.Is(err, internal.ErrBlocked) && strings.Contains(r.Header.Get("accept"), "text/html") {
+			// browsers get a page that explains why the link doesn't work instead of the problem document
+			w.Header().Set("content-type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusUnavailableForLegalReasons)
+			_, _ = w.Write([]byte(blockedPage))
+			return
+		}
 		WriteError(w, logger, err)
 		return
 	}
@@ -1,6 +1,7 @@
 package webhttp
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -8,7 +9,9 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/pavelmemory/jobtome/internal"
 	"github.com/pavelmemory/jobtome/internal/logging"
+	"github.com/pavelmemory/jobtome/internal/shorten"
 )
 
 func TestResolverHandler_Resolve(t *testing.T) {
@@ -20,12 +23,15 @@ func TestResolverHandler_Resolve(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockShortenService := NewMockShortenService(ctrl)
-		mockShortenService.EXPECT().Resolve(gomock.Any(), "hash").Return("https://example.com", nil)
+		click := shorten.Click{Referer: "https://referer.com", UserAgent: "curl", IP: "192.0.2.1"}
+		mockShortenService.EXPECT().Resolve(gomock.Any(), "hash", click).Return("https://example.com", nil)
 
 		resolverHandler := NewResolverHandler(mockShortenService)
 		resolverHandler.Register(r)
 
 		req := httptest.NewRequest(http.MethodGet, "http://localhost/hash", nil)
+		req.Header.Set("referer", "https://referer.com")
+		req.Header.Set("user-agent", "curl")
 		resp := httptest.NewRecorder()
 
 		r.ServeHTTP(resp, req)
@@ -33,4 +39,59 @@ func TestResolverHandler_Resolve(t *testing.T) {
 		require.Equal(t, http.StatusTemporaryRedirect, resp.Code)
 		require.Equal(t, "https://example.com", resp.Header().Get("location"))
 	})
+
+	t.Run("expired", func(t *testing.T) {
+		logger := logging.NewTestLogger()
+		r := NewRouter(logger)
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockShortenService := NewMockShortenService(ctrl)
+		mockShortenService.EXPECT().Resolve(gomock.Any(), "hash", gomock.Any()).Return("", fmt.Errorf("resolve: %w", internal.ErrExpired))
+
+		resolverHandler := NewResolverHandler(mockShortenService)
+		resolverHandler.Register(r)
+
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/hash", nil)
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusGone, resp.Code)
+	})
+	t.Run("blocked", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			accept      string
+			contentType string
+		}{
+			"api client": {accept: "*/*", contentType: "application/problem+json"},
+			"browser":    {accept: "text/html,application/xhtml+xml", contentType: "text/html; charset=utf-8"},
+		} {
+			t.Run(name, func(t *testing.T) {
+				logger := logging.NewTestLogger()
+				r := NewRouter(logger)
+
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				mockShortenService := NewMockShortenService(ctrl)
+				mockShortenService.EXPECT().Resolve(gomock.Any(), "hash", gomock.Any()).
+					Return("", fmt.Errorf("resolve: %w: denied", internal.ErrBlocked))
+
+				resolverHandler := NewResolverHandler(mockShortenService)
+				resolverHandler.Register(r)
+
+				req := httptest.NewRequest(http.MethodGet, "http://localhost/hash", nil)
+				req.Header.Set("accept", tc.accept)
+				resp := httptest.NewRecorder()
+
+				r.ServeHTTP(resp, req)
+
+				require.Equal(t, http.StatusUnavailableForLegalReasons, resp.Code)
+				require.Equal(t, tc.contentType, resp.Header().Get("content-type"))
+				require.Empty(t, resp.Header().Get("location"))
+			})
+		}
+	})
 }
@@ -15,9 +15,11 @@ import (
 
 // NewRouter returns initialized HTTP router.
 // It sets up all required middlewares and bindings for endpoints.
-func NewRouter(logger logging.Logger) chi.Router {
+// The `middlewares` specific to the router, e.g. `RateLimit`, are applied after the common ones.
+func NewRouter(logger logging.Logger, middlewares ...func(http.Handler) http.Handler) chi.Router {
 	router := chi.NewRouter()
-	router.Use(InjectLogger(logger)) // TODO: CORS, caching, tracing, metrics, etc.
+	router.Use(Trace(), RequestID(), InjectLogger(logger), Instrument()) // TODO: CORS, caching, etc.
+	router.Use(middlewares...)
 
 	router.With(LogRequest()).NotFound(undefined)
 	router.With(LogRequest()).MethodNotAllowed(undefined)
@@ -26,13 +28,15 @@ func NewRouter(logger logging.Logger) chi.Router {
 }
 
 func undefined(w http.ResponseWriter, r *http.Request) {
-	logging.FromContext(r.Context()).Debug("request is not implemented")
-	w.WriteHeader(http.StatusNotImplemented)
+	logger := logging.FromContext(r.Context())
+	logger.Debug("request is not implemented")
+	ErrorResponse{StatusCode: http.StatusNotImplemented}.Write(logger, w)
 }
 
 // WriteError sends an error response back to the client.
 func WriteError(w http.ResponseWriter, logger logging.Logger, err error) {
-	resp := ErrorResponse{StatusCode: http.StatusInternalServerError}
+	// validation details are sent back to the client always, so it could point out the invalid fields
+	resp := ErrorResponse{StatusCode: http.StatusInternalServerError, Errors: fieldErrors(err)}
 	if logger.IsDebug() {
 		// sends error details back to the client only in debugging mode
 		resp.Cause = err
@@ -45,6 +49,16 @@ func WriteError(w http.ResponseWriter, logger logging.Logger, err error) {
 		resp.StatusCode = http.StatusConflict
 	case errors.Is(err, internal.ErrNotFound):
 		resp.StatusCode = http.StatusNotFound
+	case errors.Is(err, internal.ErrExpired):
+		resp.StatusCode = http.StatusGone
+	case errors.Is(err, internal.ErrUnauthenticated):
+		resp.StatusCode = http.StatusUnauthorized
+	case errors.Is(err, internal.ErrForbidden):
+		resp.StatusCode = http.StatusForbidden
+	case errors.Is(err, internal.ErrRateLimited):
+		resp.StatusCode = http.StatusTooManyRequests
+	case errors.Is(err, internal.ErrBlocked):
+		resp.StatusCode = http.StatusUnavailableForLegalReasons
 	}
 
 	resp.Write(logger, w)
@@ -60,17 +74,37 @@ type ErrorResponse struct {
 	// StatusCode status code that should be returned.
 	StatusCode int
 	// Cause is an error that needs to be placed as a message describing what was wrong.
+	// The message of the server errors (5xx) is sent only in debugging mode.
 	Cause error
+	// Errors lists the invalid fields of the request, they are taken from the `Cause` if not set.
+	Errors []FieldError
 }
 
+// Write sends the error back to the client as `application/problem+json` defined by RFC 7807.
 func (er ErrorResponse) Write(logger logging.Logger, w http.ResponseWriter) {
-	w.WriteHeader(er.StatusCode)
-
-	if er.Cause == nil {
-		return
+	problem := Problem{
+		Type:      ProblemTypeDefault,
+		Title:     http.StatusText(er.StatusCode),
+		Status:    er.StatusCode,
+		RequestID: w.Header().Get(requestIDHeader),
+		Errors:    er.Errors,
+	}
+	if er.Cause != nil {
+		if er.StatusCode < http.StatusInternalServerError || logger.IsDebug() {
+			problem.Detail = er.Cause.Error()
+		}
+		if len(problem.Errors) == 0 {
+			problem.Errors = fieldErrors(er.Cause)
+		}
 	}
+	if len(problem.Errors) > 0 {
+		problem.Type = ProblemTypeValidation
+	}
+
+	w.Header().Set("content-type", "application/problem+json")
+	w.WriteHeader(er.StatusCode)
 
-	if err := Encode(w, er.Cause.Error()); err != nil {
+	if err := Encode(w, problem); err != nil {
 		logger.WithError(err).Error("send response")
 	}
 }
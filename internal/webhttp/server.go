@@ -4,7 +4,6 @@ import (
 	"context"
 	"net"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/pavelmemory/jobtome/internal/logging"
@@ -49,8 +48,10 @@ func StopServer(logger logging.Logger, srv *http.Server, gracePeriod time.Durati
 	return err
 }
 
-func Serve(ctx context.Context, logger logging.Logger, srv *http.Server, port int) error {
-	lis, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+// Serve serves requests on the `addr` in form of `host:port` until the `ctx` is cancelled,
+// an empty host listens on all interfaces.
+func Serve(ctx context.Context, logger logging.Logger, srv *http.Server, addr string) error {
+	lis, err := net.Listen("tcp", addr)
 	if err != nil {
 		logger.WithError(err).Error("listener instantiation")
 		return err
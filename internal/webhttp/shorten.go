@@ -2,12 +2,17 @@ package webhttp
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi"
 
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/auth"
 	"github.com/pavelmemory/jobtome/internal/logging"
 	"github.com/pavelmemory/jobtome/internal/shorten"
 )
@@ -20,33 +25,80 @@ type ShortenService interface {
 	Create(ctx context.Context, entity shorten.Entity) (int64, error)
 	// Get returns a single shorten by its unique identifier.
 	Get(ctx context.Context, id int64) (shorten.Entity, error)
-	// List returns subset of all shortens.
-	List(ctx context.Context, pager shorten.Pager) ([]shorten.Entity, error)
-	// Delete removes shorten by its unique identifier.
+	// List returns subset of the shortens matched by the filter.
+	List(ctx context.Context, filter shorten.Filter, pager shorten.Pager) ([]shorten.Entity, error)
+	// Update applies the patch to the shorten and returns the updated shorten.
+	Update(ctx context.Context, id int64, patch shorten.Patch) (shorten.Entity, error)
+	// Delete moves shorten to the trash by its unique identifier.
 	Delete(ctx context.Context, id int64) error
-	// Resolve returns a full URL accessioned with the hash.
-	Resolve(ctx context.Context, hash string) (string, error)
+	// Trash returns subset of the deleted shortens.
+	Trash(ctx context.Context, pager shorten.Pager) ([]shorten.Entity, error)
+	// Restore returns the deleted shorten from the trash and returns it.
+	Restore(ctx context.Context, id int64) (shorten.Entity, error)
+	// Purge permanently removes shorten by its unique identifier, deleted or not.
+	Purge(ctx context.Context, id int64) error
+	// Resolve returns a full URL accessioned with the hash and registers the click.
+	Resolve(ctx context.Context, hash string, click shorten.Click) (string, error)
+	// Stats returns statistics of clicks made with the shorten for the last `days` days.
+	Stats(ctx context.Context, id int64, days int) (shorten.Stats, error)
 }
 
 // NewShortenHandler returns HTTP baseHandler initialized with provided service abstraction.
-func NewShortenHandler(shortenService ShortenService) ShortenHandler {
-	return ShortenHandler{shortenService: shortenService}
+// Requests are authorized with API keys by the `authenticator`, nil `authenticator` disables the authorization.
+// Short URLs of the responses are built from the `publicBaseURL` of the resolver, e.g. `https://sho.rt`.
+func NewShortenHandler(shortenService ShortenService, authenticator Authenticator, publicBaseURL string) ShortenHandler {
+	return ShortenHandler{
+		shortenService: shortenService,
+		authenticator:  authenticator,
+		mapper:         Mapper{publicBaseURL: publicBaseURL},
+	}
 }
 
 // ShortenHandler handles request for the user entity(-ies).
 type ShortenHandler struct {
 	baseHandler
 	shortenService ShortenService
+	authenticator  Authenticator
 	mapper         Mapper
 }
 
 // Register creates a binding between method handlers and endpoints.
+// Each endpoint requires the API key with the scope of the operation
+// and operates only on the shortens of the key's workspace.
+// The permanent removal additionally requires the admin scope, see `Delete`.
 func (uh ShortenHandler) Register(router chi.Router) {
+	var (
+		read   = Authorize(uh.authenticator, auth.ScopeShortenRead)
+		write  = Authorize(uh.authenticator, auth.ScopeShortenWrite)
+		remove = Authorize(uh.authenticator, auth.ScopeShortenDelete)
+	)
+
 	router = router.With(LogRequest())
-	router.With(ProducesJSON, AcceptsJSON).Method(http.MethodPost, uh.urlPrefix(), http.HandlerFunc(uh.Create))
-	router.With(ProducesJSON).Method(http.MethodGet, uh.urlPrefix(), http.HandlerFunc(uh.List))
-	router.With(ProducesJSON).Method(http.MethodGet, uh.urlPrefix()+"/{id}", http.HandlerFunc(uh.Get))
-	router.Method(http.MethodDelete, uh.urlPrefix()+"/{id}", http.HandlerFunc(uh.Delete))
+	router.With(write, InWorkspace, ProducesJSON, AcceptsJSON).Method(http.MethodPost, uh.urlPrefix(), http.HandlerFunc(uh.Create))
+	router.With(read, InWorkspace, ProducesJSON).Method(http.MethodGet, uh.urlPrefix(), http.HandlerFunc(uh.List))
+	router.With(read, InWorkspace, ProducesJSON).Method(http.MethodGet, uh.urlPrefix()+"/trash", http.HandlerFunc(uh.Trash))
+	router.With(read, InWorkspace, ProducesJSON).Method(http.MethodGet, uh.urlPrefix()+"/{id}", http.HandlerFunc(uh.Get))
+	router.With(write, InWorkspace, ProducesJSON, AcceptsJSON).Method(http.MethodPatch, uh.urlPrefix()+"/{id}", http.HandlerFunc(uh.Update))
+	router.With(remove, InWorkspace).Method(http.MethodDelete, uh.urlPrefix()+"/{id}", http.HandlerFunc(uh.Delete))
+	router.With(write, InWorkspace, ProducesJSON).Method(http.MethodPost, uh.urlPrefix()+"/{id}/restore", http.HandlerFunc(uh.Restore))
+	router.With(read, InWorkspace, ProducesJSON).Method(http.MethodGet, uh.urlPrefix()+"/{id}/stats", http.HandlerFunc(uh.Stats))
+}
+
+// InWorkspace is a middleware function that scopes the shorten operations to the workspace
+// of the API key authorized by `Authorize` and makes the key an owner of the created shortens.
+// Requests without the key stay in the default workspace.
+func InWorkspace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := auth.FromContext(r.Context())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := shorten.WithOwner(shorten.WithWorkspace(r.Context(), key.Workspace), key.Name)
+		ctx = logging.ToContext(ctx, logging.FromContext(ctx).WithString("workspace", key.Workspace))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
 }
 
 func (uh ShortenHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -70,8 +122,20 @@ func (uh ShortenHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the shorten of the same URL could already exist, so the stored one is returned
+	entity, err := uh.shortenService.Get(ctx, id)
+	if err != nil {
+		logger.WithError(err).WithInt64("id", id).Error("retrieve created shorten")
+		WriteError(w, logger, err)
+		return
+	}
+
 	w.Header().Set("location", uh.urlPrefix()+"/"+strconv.FormatInt(id, 10))
 	w.WriteHeader(http.StatusCreated)
+	if err := Encode(w, uh.mapper.entity2GetShortenResp(entity)); err != nil {
+		logger.WithError(err).Error("encode created shorten")
+		return
+	}
 }
 
 func (uh ShortenHandler) Get(w http.ResponseWriter, r *http.Request) {
@@ -85,7 +149,7 @@ func (uh ShortenHandler) Get(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		cause := fmt.Errorf(`parameter "id": %w`, err)
 		logger.WithError(cause).Error("extract path parameter")
-		ErrorResponse{Cause: cause, StatusCode: http.StatusBadRequest}.Write(logger, w)
+		ErrorResponse{Cause: cause, StatusCode: http.StatusBadRequest, Errors: paramErrors("id", err)}.Write(logger, w)
 		return
 	}
 
@@ -105,11 +169,33 @@ func (uh ShortenHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 const (
 	defaultListLimit = int64(50)
+	defaultStatsDays = int64(30)
 )
 
+// List lists the shortens matched by the filter parameters.
 func (uh ShortenHandler) List(w http.ResponseWriter, r *http.Request) {
+	uh.list(w, r, "List", true, uh.shortenService.List)
+}
+
+// Trash lists the deleted shortens, the most recently deleted go first.
+func (uh ShortenHandler) Trash(w http.ResponseWriter, r *http.Request) {
+	uh.list(w, r, "Trash", false, func(ctx context.Context, _ shorten.Filter, pager shorten.Pager) ([]shorten.Entity, error) {
+		return uh.shortenService.Trash(ctx, pager)
+	})
+}
+
+// list responds with a page of the shortens returned by the `list`.
+// If the `searchable` is set the shortens could be filtered and sorted, the page could start after the "cursor"
+// and the full page is followed by the `Link` header with the URL of the next page.
+func (uh ShortenHandler) list(
+	w http.ResponseWriter,
+	r *http.Request,
+	method string,
+	searchable bool,
+	list func(ctx context.Context, filter shorten.Filter, pager shorten.Pager) ([]shorten.Entity, error),
+) {
 	ctx := r.Context()
-	logger := uh.logger(ctx, "List")
+	logger := uh.logger(ctx, method)
 
 	logger.Debug("start")
 	defer logger.Debug("end")
@@ -118,25 +204,60 @@ func (uh ShortenHandler) List(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		cause := fmt.Errorf(`parameter "limit": %w`, err)
 		logger.WithError(cause).Error("extract query parameter")
-		ErrorResponse{Cause: cause, StatusCode: http.StatusBadRequest}.Write(logger, w)
+		ErrorResponse{Cause: cause, StatusCode: http.StatusBadRequest, Errors: paramErrors("limit", err)}.Write(logger, w)
 		return
 	}
+	if limit > shorten.MaxPageLimit {
+		// the service returns at most that many, so the full page is detected with the reduced limit
+		limit = shorten.MaxPageLimit
+	}
 
 	offset, err := uh.queryParamInt64(r, ParamInt64Opts{P: ParamOpts{Name: "offset", Optional: true}})
 	if err != nil {
 		cause := fmt.Errorf(`parameter "offset": %w`, err)
 		logger.WithError(cause).Error("extract query parameter")
-		ErrorResponse{Cause: cause, StatusCode: http.StatusBadRequest}.Write(logger, w)
+		ErrorResponse{Cause: cause, StatusCode: http.StatusBadRequest, Errors: paramErrors("offset", err)}.Write(logger, w)
 		return
 	}
 
-	entities, err := uh.shortenService.List(ctx, shorten.Pager{Limit: limit, Offset: offset})
+	pager := shorten.Pager{Limit: limit, Offset: offset}
+	var filter shorten.Filter
+	if searchable {
+		var name string
+		if filter, name, err = uh.filter(r); err != nil {
+			cause := fmt.Errorf(`parameter %q: %w`, name, err)
+			logger.WithError(cause).Error("extract query parameter")
+			ErrorResponse{Cause: cause, StatusCode: http.StatusBadRequest, Errors: paramErrors(name, err)}.Write(logger, w)
+			return
+		}
+
+		pager.Sort = uh.queryParam(r, "sort")
+		if cursor := uh.queryParam(r, "cursor"); cursor != "" {
+			after, err := decodeCursor(cursor)
+			if err == nil && after.Sort != pager.Sort {
+				err = fmt.Errorf("%w: issued for another sort", ErrBadFormat)
+			}
+			if err != nil {
+				cause := fmt.Errorf(`parameter "cursor": %w`, err)
+				logger.WithError(cause).Error("extract query parameter")
+				ErrorResponse{Cause: cause, StatusCode: http.StatusBadRequest, Errors: paramErrors("cursor", err)}.Write(logger, w)
+				return
+			}
+			pager.AfterID, pager.AfterKey = after.AfterID, after.AfterKey
+		}
+	}
+
+	entities, err := list(ctx, filter, pager)
 	if err != nil {
 		logger.WithError(err).Error("extract shortens")
 		WriteError(w, logger, err)
 		return
 	}
 
+	if searchable && limit > 0 && int64(len(entities)) == limit {
+		w.Header().Set("Link", nextPageLink(r, shorten.NextPage(pager, entities[len(entities)-1])))
+	}
+
 	if err := Encode(w, uh.mapper.entities2ListShortenResp(entities)); err != nil {
 		logger.WithError(err).Error("encode shortens")
 		ErrorResponse{Cause: err, StatusCode: http.StatusInternalServerError}.Write(logger, w)
@@ -144,6 +265,116 @@ func (uh ShortenHandler) List(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// filter returns the filter of the listed shortens, the name of the invalid parameter is returned with the error.
+func (uh ShortenHandler) filter(r *http.Request) (shorten.Filter, string, error) {
+	filter := shorten.Filter{
+		Domain: uh.queryParam(r, "domain"),
+		URL:    uh.queryParam(r, "url"),
+		Tag:    uh.queryParam(r, "tag"),
+		Owner:  uh.queryParam(r, "owner"),
+		Status: uh.queryParam(r, "status"),
+	}
+
+	var err error
+	if filter.CreatedFrom, err = uh.queryParamTime(r, ParamOpts{Name: "created_from", Optional: true}); err != nil {
+		return shorten.Filter{}, "created_from", err
+	}
+
+	if filter.CreatedTo, err = uh.queryParamTime(r, ParamOpts{Name: "created_to", Optional: true}); err != nil {
+		return shorten.Filter{}, "created_to", err
+	}
+
+	return filter, "", nil
+}
+
+// encodeCursor returns an opaque cursor of the page that starts after the `AfterID` of the `pager`.
+// Its content is `id:<id>` for the shortens ordered by ID and `<sort>:<key>:<id>` for the sorted ones,
+// so the format could be changed without breaking the issued cursors.
+func encodeCursor(pager shorten.Pager) string {
+	raw := "id:" + strconv.FormatInt(pager.AfterID, 10)
+	if pager.Sort != "" {
+		raw = pager.Sort + ":" + strconv.FormatInt(pager.AfterKey, 10) + ":" + strconv.FormatInt(pager.AfterID, 10)
+	}
+
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor returns the pager with the `Sort`, `AfterKey` and `AfterID` of the page the `cursor` points to.
+func decodeCursor(cursor string) (shorten.Pager, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return shorten.Pager{}, ErrBadFormat
+	}
+
+	var pager shorten.Pager
+	parts := strings.Split(string(raw), ":")
+	switch {
+	case len(parts) == 2 && parts[0] == "id":
+	case len(parts) == 3 && parts[0] != "" && parts[0] != "id":
+		pager.Sort = parts[0]
+		if pager.AfterKey, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+			return shorten.Pager{}, ErrBadFormat
+		}
+	default:
+		return shorten.Pager{}, ErrBadFormat
+	}
+
+	if pager.AfterID, err = strconv.ParseInt(parts[len(parts)-1], 10, 64); err != nil || pager.AfterID <= 0 {
+		return shorten.Pager{}, ErrBadFormat
+	}
+
+	return pager, nil
+}
+
+// nextPageLink returns a value of the `Link` header pointing to the page located by the `next` pager.
+// The rest of the query parameters of the request are kept except the "offset" that is already applied by the cursor.
+func nextPageLink(r *http.Request, next shorten.Pager) string {
+	query := r.URL.Query()
+	query.Del("offset")
+	query.Set("cursor", encodeCursor(next))
+
+	link := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return fmt.Sprintf(`<%s>; rel="next"`, link.String())
+}
+
+func (uh ShortenHandler) Update(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := uh.logger(ctx, "Update")
+
+	logger.Debug("start")
+	defer logger.Debug("end")
+
+	id, err := uh.pathParamInt64(r, ParamInt64Opts{P: ParamOpts{Name: "id"}})
+	if err != nil {
+		cause := fmt.Errorf(`parameter "id": %w`, err)
+		logger.WithError(cause).Error("extract path parameter")
+		ErrorResponse{Cause: cause, StatusCode: http.StatusBadRequest, Errors: paramErrors("id", err)}.Write(logger, w)
+		return
+	}
+
+	var req UpdateShortenReq
+	if err := Decode(r.Body, &req); err != nil {
+		logger.WithError(err).Error("decode payload")
+		ErrorResponse{Cause: err, StatusCode: http.StatusBadRequest}.Write(logger, w)
+		return
+	}
+
+	entity, err := uh.shortenService.Update(ctx, id, uh.mapper.updateShortenReq2Patch(req))
+	if err != nil {
+		logger.WithError(err).WithInt64("id", id).Error("update shorten")
+		WriteError(w, logger, err)
+		return
+	}
+
+	if err := Encode(w, uh.mapper.entity2GetShortenResp(entity)); err != nil {
+		logger.WithError(err).Error("encode entity")
+		ErrorResponse{Cause: err, StatusCode: http.StatusInternalServerError}.Write(logger, w)
+		return
+	}
+}
+
+// Delete moves the shorten to the trash. With `permanent=true` query parameter the shorten is removed permanently,
+// it requires the admin scope.
 func (uh ShortenHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	logger := uh.logger(ctx, "Delete")
@@ -155,11 +386,26 @@ func (uh ShortenHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		cause := fmt.Errorf(`parameter "id": %w`, err)
 		logger.WithError(cause).Error("extract path parameter")
-		ErrorResponse{Cause: cause, StatusCode: http.StatusBadRequest}.Write(logger, w)
+		ErrorResponse{Cause: cause, StatusCode: http.StatusBadRequest, Errors: paramErrors("id", err)}.Write(logger, w)
 		return
 	}
 
-	if err := uh.shortenService.Delete(ctx, id); err != nil {
+	permanent, err := uh.queryParamBool(r, ParamOpts{Name: "permanent", Optional: true})
+	if err != nil {
+		cause := fmt.Errorf(`parameter "permanent": %w`, err)
+		logger.WithError(cause).Error("extract query parameter")
+		ErrorResponse{Cause: cause, StatusCode: http.StatusBadRequest, Errors: paramErrors("permanent", err)}.Write(logger, w)
+		return
+	}
+
+	if !permanent {
+		err = uh.shortenService.Delete(ctx, id)
+	} else if key, ok := auth.FromContext(ctx); ok && !key.Allows(auth.ScopeAdmin) {
+		err = fmt.Errorf("scope %q is not granted: %w", auth.ScopeAdmin, internal.ErrForbidden)
+	} else {
+		err = uh.shortenService.Purge(ctx, id)
+	}
+	if err != nil {
 		logger.WithError(err).WithInt64("id", id).Error("delete shorten")
 		WriteError(w, logger, err)
 		return
@@ -168,6 +414,72 @@ func (uh ShortenHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (uh ShortenHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := uh.logger(ctx, "Restore")
+
+	logger.Debug("start")
+	defer logger.Debug("end")
+
+	id, err := uh.pathParamInt64(r, ParamInt64Opts{P: ParamOpts{Name: "id"}})
+	if err != nil {
+		cause := fmt.Errorf(`parameter "id": %w`, err)
+		logger.WithError(cause).Error("extract path parameter")
+		ErrorResponse{Cause: cause, StatusCode: http.StatusBadRequest, Errors: paramErrors("id", err)}.Write(logger, w)
+		return
+	}
+
+	entity, err := uh.shortenService.Restore(ctx, id)
+	if err != nil {
+		logger.WithError(err).WithInt64("id", id).Error("restore shorten")
+		WriteError(w, logger, err)
+		return
+	}
+
+	if err := Encode(w, uh.mapper.entity2GetShortenResp(entity)); err != nil {
+		logger.WithError(err).Error("encode entity")
+		ErrorResponse{Cause: err, StatusCode: http.StatusInternalServerError}.Write(logger, w)
+		return
+	}
+}
+
+func (uh ShortenHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := uh.logger(ctx, "Stats")
+
+	logger.Debug("start")
+	defer logger.Debug("end")
+
+	id, err := uh.pathParamInt64(r, ParamInt64Opts{P: ParamOpts{Name: "id"}})
+	if err != nil {
+		cause := fmt.Errorf(`parameter "id": %w`, err)
+		logger.WithError(cause).Error("extract path parameter")
+		ErrorResponse{Cause: cause, StatusCode: http.StatusBadRequest, Errors: paramErrors("id", err)}.Write(logger, w)
+		return
+	}
+
+	days, err := uh.queryParamInt64(r, ParamInt64Opts{P: ParamOpts{Name: "days", Optional: true}, Default: defaultStatsDays})
+	if err != nil {
+		cause := fmt.Errorf(`parameter "days": %w`, err)
+		logger.WithError(cause).Error("extract query parameter")
+		ErrorResponse{Cause: cause, StatusCode: http.StatusBadRequest, Errors: paramErrors("days", err)}.Write(logger, w)
+		return
+	}
+
+	stats, err := uh.shortenService.Stats(ctx, id, int(days))
+	if err != nil {
+		logger.WithError(err).WithInt64("id", id).Error("get shorten stats")
+		WriteError(w, logger, err)
+		return
+	}
+
+	if err := Encode(w, uh.mapper.stats2StatsShortenResp(stats)); err != nil {
+		logger.WithError(err).Error("encode stats")
+		ErrorResponse{Cause: err, StatusCode: http.StatusInternalServerError}.Write(logger, w)
+		return
+	}
+}
+
 func (uh ShortenHandler) urlPrefix() string {
 	return "/api/shorten"
 }
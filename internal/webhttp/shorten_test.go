@@ -1,14 +1,21 @@
 package webhttp
 
 import (
+	"context"
+	"encoding/base64"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/pavelmemory/jobtome/internal"
+	"github.com/pavelmemory/jobtome/internal/auth"
 	"github.com/pavelmemory/jobtome/internal/logging"
 	"github.com/pavelmemory/jobtome/internal/shorten"
 )
@@ -22,9 +29,12 @@ func TestShortenHandler_Create(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockShortenService := NewMockShortenService(ctrl)
+		createdAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
 		mockShortenService.EXPECT().Create(gomock.Any(), shorten.Entity{URL: "https://example.com"}).Return(int64(1), nil)
+		mockShortenService.EXPECT().Get(gomock.Any(), int64(1)).
+			Return(shorten.Entity{ID: 1, Hash: "c984d06", URL: "https://example.com", CreatedAt: createdAt}, nil)
 
-		shortenHandler := NewShortenHandler(mockShortenService)
+		shortenHandler := NewShortenHandler(mockShortenService, nil, "https://sho.rt/")
 		shortenHandler.Register(r)
 
 		req := httptest.NewRequest(http.MethodPost, "http://localhost/api/shorten", strings.NewReader(`{"url":"https://example.com"}`))
@@ -35,6 +45,90 @@ func TestShortenHandler_Create(t *testing.T) {
 
 		require.Equal(t, http.StatusCreated, resp.Code)
 		require.Equal(t, "/api/shorten/1", resp.Header().Get("location"))
+		require.Equal(t, "application/json; charset=utf-8", resp.Header().Get("content-type"))
+		require.JSONEq(t, `{
+			"id":1,
+			"hash":"c984d06",
+			"url":"https://example.com",
+			"short_url":"https://sho.rt/c984d06",
+			"created_at":"2030-01-02T03:04:05Z"
+		}`, resp.Body.String())
+	})
+}
+
+func TestShortenHandler_Create_limited(t *testing.T) {
+	logger := logging.NewTestLogger()
+	r := NewRouter(logger)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	mockShortenService := NewMockShortenService(ctrl)
+	mockShortenService.EXPECT().
+		Create(gomock.Any(), shorten.Entity{URL: "https://example.com", ExpiresAt: expiresAt, MaxClicks: 10}).
+		Return(int64(1), nil)
+	mockShortenService.EXPECT().Get(gomock.Any(), int64(1)).Return(shorten.Entity{ID: 1, Hash: "1", URL: "https://example.com"}, nil)
+
+	shortenHandler := NewShortenHandler(mockShortenService, nil, "https://sho.rt")
+	shortenHandler.Register(r)
+
+	body := `{"url":"https://example.com","expires_at":"2030-01-02T03:04:05Z","max_clicks":10}`
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/api/shorten", strings.NewReader(body))
+	req.Header.Set("content-type", "application/json")
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusCreated, resp.Code)
+}
+
+func TestShortenHandler_Create_alias(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		logger := logging.NewTestLogger()
+		r := NewRouter(logger)
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockShortenService := NewMockShortenService(ctrl)
+		mockShortenService.EXPECT().Create(gomock.Any(), shorten.Entity{URL: "https://example.com", Hash: "summer-sale"}).Return(int64(1), nil)
+		mockShortenService.EXPECT().Get(gomock.Any(), int64(1)).Return(shorten.Entity{ID: 1, Hash: "summer-sale", URL: "https://example.com"}, nil)
+
+		shortenHandler := NewShortenHandler(mockShortenService, nil, "https://sho.rt")
+		shortenHandler.Register(r)
+
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/api/shorten", strings.NewReader(`{"url":"https://example.com","alias":"summer-sale"}`))
+		req.Header.Set("content-type", "application/json")
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusCreated, resp.Code)
+		require.Equal(t, "/api/shorten/1", resp.Header().Get("location"))
+		require.Contains(t, resp.Body.String(), `"short_url":"https://sho.rt/summer-sale"`)
+	})
+
+	t.Run("taken", func(t *testing.T) {
+		logger := logging.NewTestLogger()
+		r := NewRouter(logger)
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockShortenService := NewMockShortenService(ctrl)
+		mockShortenService.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(0), fmt.Errorf("persist: %w", internal.ErrNotUnique))
+
+		shortenHandler := NewShortenHandler(mockShortenService, nil, "https://sho.rt")
+		shortenHandler.Register(r)
+
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/api/shorten", strings.NewReader(`{"url":"https://example.com","alias":"summer-sale"}`))
+		req.Header.Set("content-type", "application/json")
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusConflict, resp.Code)
 	})
 }
 
@@ -49,7 +143,7 @@ func TestShortenHandler_Get(t *testing.T) {
 		mockShortenService := NewMockShortenService(ctrl)
 		mockShortenService.EXPECT().Get(gomock.Any(), int64(1)).Return(shorten.Entity{ID: 1, Hash: "1", URL: "https://example.com"}, nil)
 
-		shortenHandler := NewShortenHandler(mockShortenService)
+		shortenHandler := NewShortenHandler(mockShortenService, nil, "https://sho.rt")
 		shortenHandler.Register(r)
 
 		req := httptest.NewRequest(http.MethodGet, "http://localhost/api/shorten/1", nil)
@@ -59,7 +153,7 @@ func TestShortenHandler_Get(t *testing.T) {
 
 		require.Equal(t, http.StatusOK, resp.Code)
 		require.Equal(t, "application/json; charset=utf-8", resp.Header().Get("content-type"))
-		require.JSONEq(t, `{"id":1, "hash":"1", "url":"https://example.com"}`, resp.Body.String())
+		require.JSONEq(t, `{"id":1, "hash":"1", "url":"https://example.com", "short_url":"https://sho.rt/1", "created_at":"0001-01-01T00:00:00Z"}`, resp.Body.String())
 	})
 }
 
@@ -76,9 +170,9 @@ func TestShortenHandler_List(t *testing.T) {
 			{ID: 2, Hash: "2", URL: "https://stub.com"},
 		}
 		mockShortenService := NewMockShortenService(ctrl)
-		mockShortenService.EXPECT().List(gomock.Any(), shorten.Pager{Limit: 10, Offset: 1}).Return(existing, nil)
+		mockShortenService.EXPECT().List(gomock.Any(), shorten.Filter{}, shorten.Pager{Limit: 10, Offset: 1}).Return(existing, nil)
 
-		shortenHandler := NewShortenHandler(mockShortenService)
+		shortenHandler := NewShortenHandler(mockShortenService, nil, "https://sho.rt")
 		shortenHandler.Register(r)
 
 		req := httptest.NewRequest(http.MethodGet, "http://localhost/api/shorten?limit=10&offset=1", nil)
@@ -88,8 +182,233 @@ func TestShortenHandler_List(t *testing.T) {
 
 		require.Equal(t, http.StatusOK, resp.Code)
 		require.Equal(t, "application/json; charset=utf-8", resp.Header().Get("content-type"))
-		require.JSONEq(t, `[{"id":1, "hash":"1", "url":"https://example.com"}, {"id":2, "hash":"2", "url":"https://stub.com"}]`, resp.Body.String())
+		require.JSONEq(t, `[
+			{"id":1, "hash":"1", "url":"https://example.com", "short_url":"https://sho.rt/1", "created_at":"0001-01-01T00:00:00Z"},
+			{"id":2, "hash":"2", "url":"https://stub.com", "short_url":"https://sho.rt/2", "created_at":"0001-01-01T00:00:00Z"}
+		]`, resp.Body.String())
+		require.Empty(t, resp.Header().Get("link"))
+	})
+
+	t.Run("cursor", func(t *testing.T) {
+		logger := logging.NewTestLogger()
+		r := NewRouter(logger)
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		existing := []shorten.Entity{
+			{ID: 4, Hash: "4", URL: "https://example.com"},
+			{ID: 7, Hash: "7", URL: "https://stub.com"},
+		}
+		mockShortenService := NewMockShortenService(ctrl)
+		mockShortenService.EXPECT().List(gomock.Any(), shorten.Filter{}, shorten.Pager{Limit: 2, AfterID: 3}).Return(existing, nil)
+
+		shortenHandler := NewShortenHandler(mockShortenService, nil, "https://sho.rt")
+		shortenHandler.Register(r)
+
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/api/shorten?limit=2&cursor="+encodeCursor(shorten.Pager{AfterID: 3}), nil)
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, `</api/shorten?cursor=`+encodeCursor(shorten.Pager{AfterID: 7})+`&limit=2>; rel="next"`, resp.Header().Get("link"))
+		require.JSONEq(t, `[
+			{"id":4, "hash":"4", "url":"https://example.com", "short_url":"https://sho.rt/4", "created_at":"0001-01-01T00:00:00Z"},
+			{"id":7, "hash":"7", "url":"https://stub.com", "short_url":"https://sho.rt/7", "created_at":"0001-01-01T00:00:00Z"}
+		]`, resp.Body.String())
+	})
+
+	t.Run("limit above max", func(t *testing.T) {
+		logger := logging.NewTestLogger()
+		r := NewRouter(logger)
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		existing := make([]shorten.Entity, shorten.MaxPageLimit)
+		for i := range existing {
+			existing[i] = shorten.Entity{ID: int64(i + 1), Hash: strconv.Itoa(i + 1), URL: "https://example.com"}
+		}
+		mockShortenService := NewMockShortenService(ctrl)
+		mockShortenService.EXPECT().List(gomock.Any(), shorten.Filter{}, shorten.Pager{Limit: shorten.MaxPageLimit}).Return(existing, nil)
+
+		shortenHandler := NewShortenHandler(mockShortenService, nil, "https://sho.rt")
+		shortenHandler.Register(r)
+
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/api/shorten?limit=100000", nil)
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, `</api/shorten?cursor=`+encodeCursor(shorten.Pager{AfterID: shorten.MaxPageLimit})+`&limit=100000>; rel="next"`, resp.Header().Get("link"))
+	})
+
+	t.Run("filtered and sorted", func(t *testing.T) {
+		logger := logging.NewTestLogger()
+		r := NewRouter(logger)
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		createdFrom := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+		filter := shorten.Filter{Domain: "example.com", URL: "summer", CreatedFrom: createdFrom, Tag: "promo", Owner: "ci", Status: "active"}
+		existing := []shorten.Entity{{ID: 4, Hash: "4", URL: "https://example.com/summer", Clicks: 12, Owner: "ci"}}
+		mockShortenService := NewMockShortenService(ctrl)
+		mockShortenService.EXPECT().
+			List(gomock.Any(), filter, shorten.Pager{Limit: 1, Sort: "-clicks", AfterID: 7, AfterKey: 20}).
+			Return(existing, nil)
+
+		shortenHandler := NewShortenHandler(mockShortenService, nil, "https://sho.rt")
+		shortenHandler.Register(r)
+
+		cursor := encodeCursor(shorten.Pager{Sort: "-clicks", AfterID: 7, AfterKey: 20})
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/api/shorten?limit=1&sort=-clicks&domain=example.com&url=summer"+
+			"&created_from=2030-01-02T00:00:00Z&tag=promo&owner=ci&status=active&cursor="+cursor, nil)
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		next := encodeCursor(shorten.Pager{Sort: "-clicks", AfterID: 4, AfterKey: 12})
+		require.Equal(t, `</api/shorten?created_from=2030-01-02T00%3A00%3A00Z&cursor=`+next+
+			`&domain=example.com&limit=1&owner=ci&sort=-clicks&status=active&tag=promo&url=summer>; rel="next"`, resp.Header().Get("link"))
+		require.JSONEq(t, `[
+			{"id":4, "hash":"4", "url":"https://example.com/summer", "short_url":"https://sho.rt/4", "clicks":12, "owner":"ci", "created_at":"0001-01-01T00:00:00Z"}
+		]`, resp.Body.String())
+	})
+
+	for name, tc := range map[string]struct {
+		query string
+		field string
+	}{
+		"bad created_from":       {query: "created_from=yesterday", field: "created_from"},
+		"bad created_to":         {query: "created_to=2030-01-02", field: "created_to"},
+		"cursor of another sort": {query: "sort=clicks&cursor=" + encodeCursor(shorten.Pager{AfterID: 3}), field: "cursor"},
+	} {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			logger := logging.NewTestLogger()
+			r := NewRouter(logger)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			shortenHandler := NewShortenHandler(NewMockShortenService(ctrl), nil, "https://sho.rt")
+			shortenHandler.Register(r)
+
+			req := httptest.NewRequest(http.MethodGet, "http://localhost/api/shorten?"+tc.query, nil)
+			resp := httptest.NewRecorder()
+
+			r.ServeHTTP(resp, req)
+
+			require.Equal(t, http.StatusBadRequest, resp.Code)
+			require.Contains(t, resp.Body.String(), `"field":"`+tc.field+`"`)
+		})
+	}
+
+	for name, cursor := range map[string]string{
+		"not base64":   "!!!",
+		"no prefix":    base64.RawURLEncoding.EncodeToString([]byte("3")),
+		"not a number": base64.RawURLEncoding.EncodeToString([]byte("id:x")),
+		"not positive": base64.RawURLEncoding.EncodeToString([]byte("id:0")),
+	} {
+		cursor := cursor
+		t.Run("bad cursor "+name, func(t *testing.T) {
+			logger := logging.NewTestLogger()
+			r := NewRouter(logger)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			shortenHandler := NewShortenHandler(NewMockShortenService(ctrl), nil, "https://sho.rt")
+			shortenHandler.Register(r)
+
+			req := httptest.NewRequest(http.MethodGet, "http://localhost/api/shorten?cursor="+cursor, nil)
+			resp := httptest.NewRecorder()
+
+			r.ServeHTTP(resp, req)
+
+			require.Equal(t, http.StatusBadRequest, resp.Code)
+			require.Contains(t, resp.Body.String(), `"field":"cursor"`)
+		})
+	}
+}
+
+func TestShortenHandler_Update(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		logger := logging.NewTestLogger()
+		r := NewRouter(logger)
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		updatedAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+		mockShortenService := NewMockShortenService(ctrl)
+		mockShortenService.EXPECT().
+			Update(gomock.Any(), int64(1), gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ int64, patch shorten.Patch) (shorten.Entity, error) {
+				require.Equal(t, "https://moved.com", *patch.URL)
+				require.Nil(t, patch.Alias)
+				require.Nil(t, patch.MaxClicks)
+				require.Equal(t, time.Time{}, *patch.ExpiresAt)
+				require.Equal(t, []string{"promo"}, *patch.Tags)
+				return shorten.Entity{ID: 1, Hash: "1", URL: "https://moved.com", Tags: []string{"promo"}, CreatedAt: updatedAt.Add(-time.Hour), UpdatedAt: updatedAt}, nil
+			})
+
+		shortenHandler := NewShortenHandler(mockShortenService, nil, "https://sho.rt")
+		shortenHandler.Register(r)
+
+		body := `{"url":"https://moved.com","expires_at":null,"tags":["promo"]}`
+		req := httptest.NewRequest(http.MethodPatch, "http://localhost/api/shorten/1", strings.NewReader(body))
+		req.Header.Set("content-type", "application/json")
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		exp := `{
+			"id":1,
+			"hash":"1",
+			"url":"https://moved.com",
+			"short_url":"https://sho.rt/1",
+			"tags":["promo"],
+			"created_at":"2030-01-02T02:04:05Z",
+			"updated_at":"2030-01-02T03:04:05Z"
+		}`
+		require.JSONEq(t, exp, resp.Body.String())
 	})
+
+	for name, tc := range map[string]struct {
+		err    error
+		status int
+	}{
+		"not existing":   {err: internal.ErrNotFound, status: http.StatusNotFound},
+		"alias is taken": {err: internal.ErrNotUnique, status: http.StatusConflict},
+	} {
+		t.Run(name, func(t *testing.T) {
+			logger := logging.NewTestLogger()
+			r := NewRouter(logger)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockShortenService := NewMockShortenService(ctrl)
+			mockShortenService.EXPECT().Update(gomock.Any(), int64(1), gomock.Any()).Return(shorten.Entity{}, fmt.Errorf("update: %w", tc.err))
+
+			shortenHandler := NewShortenHandler(mockShortenService, nil, "https://sho.rt")
+			shortenHandler.Register(r)
+
+			req := httptest.NewRequest(http.MethodPatch, "http://localhost/api/shorten/1", strings.NewReader(`{"alias":"summer-sale"}`))
+			req.Header.Set("content-type", "application/json")
+			resp := httptest.NewRecorder()
+
+			r.ServeHTTP(resp, req)
+
+			require.Equal(t, tc.status, resp.Code)
+		})
+	}
 }
 
 func TestShortenHandler_Delete(t *testing.T) {
@@ -103,7 +422,7 @@ func TestShortenHandler_Delete(t *testing.T) {
 		mockShortenService := NewMockShortenService(ctrl)
 		mockShortenService.EXPECT().Delete(gomock.Any(), int64(1)).Return(nil)
 
-		shortenHandler := NewShortenHandler(mockShortenService)
+		shortenHandler := NewShortenHandler(mockShortenService, nil, "https://sho.rt")
 		shortenHandler.Register(r)
 
 		req := httptest.NewRequest(http.MethodDelete, "http://localhost/api/shorten/1", nil)
@@ -113,4 +432,142 @@ func TestShortenHandler_Delete(t *testing.T) {
 
 		require.Equal(t, http.StatusNoContent, resp.Code)
 	})
+
+	t.Run("permanently", func(t *testing.T) {
+		for _, tc := range []struct {
+			name    string
+			scopes  []auth.Scope
+			expCode int
+		}{
+			{name: "admin", scopes: []auth.Scope{auth.ScopeAdmin}, expCode: http.StatusNoContent},
+			{name: "not admin", scopes: []auth.Scope{auth.ScopeShortenDelete}, expCode: http.StatusForbidden},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				logger := logging.NewTestLogger()
+				r := NewRouter(logger)
+
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				mockAuthenticator := NewMockAuthenticator(ctrl)
+				mockAuthenticator.EXPECT().Authenticate(gomock.Any(), "jt_key").Return(auth.Key{ID: 1, Scopes: tc.scopes}, nil)
+
+				mockShortenService := NewMockShortenService(ctrl)
+				if tc.expCode == http.StatusNoContent {
+					mockShortenService.EXPECT().Purge(gomock.Any(), int64(1)).Return(nil)
+				}
+
+				shortenHandler := NewShortenHandler(mockShortenService, mockAuthenticator, "https://sho.rt")
+				shortenHandler.Register(r)
+
+				req := httptest.NewRequest(http.MethodDelete, "http://localhost/api/shorten/1?permanent=true", nil)
+				req.Header.Set("X-API-Key", "jt_key")
+				resp := httptest.NewRecorder()
+
+				r.ServeHTTP(resp, req)
+
+				require.Equal(t, tc.expCode, resp.Code)
+			})
+		}
+	})
+
+	t.Run("bad permanent", func(t *testing.T) {
+		logger := logging.NewTestLogger()
+		r := NewRouter(logger)
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		shortenHandler := NewShortenHandler(NewMockShortenService(ctrl), nil, "https://sho.rt")
+		shortenHandler.Register(r)
+
+		req := httptest.NewRequest(http.MethodDelete, "http://localhost/api/shorten/1?permanent=yes", nil)
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+}
+
+func TestShortenHandler_Restore(t *testing.T) {
+	logger := logging.NewTestLogger()
+	r := NewRouter(logger)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	createdAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockShortenService := NewMockShortenService(ctrl)
+	mockShortenService.EXPECT().Restore(gomock.Any(), int64(1)).
+		Return(shorten.Entity{ID: 1, URL: "https://example.com", Hash: "1234567", CreatedAt: createdAt}, nil)
+
+	shortenHandler := NewShortenHandler(mockShortenService, nil, "https://sho.rt")
+	shortenHandler.Register(r)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/api/shorten/1/restore", nil)
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.JSONEq(t, `{"id":1,"url":"https://example.com","hash":"1234567","short_url":"https://sho.rt/1234567","created_at":"2030-01-01T00:00:00Z"}`, resp.Body.String())
+}
+
+func TestShortenHandler_Stats(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		logger := logging.NewTestLogger()
+		r := NewRouter(logger)
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		stats := shorten.Stats{
+			Total:         3,
+			Daily:         []shorten.DailyCounter{{Day: time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC), Count: 3}},
+			TopReferers:   []shorten.Counter{{Value: "https://referer.com", Count: 2}},
+			TopUserAgents: []shorten.Counter{{Value: "curl", Count: 3}},
+		}
+		mockShortenService := NewMockShortenService(ctrl)
+		mockShortenService.EXPECT().Stats(gomock.Any(), int64(1), 7).Return(stats, nil)
+
+		shortenHandler := NewShortenHandler(mockShortenService, nil, "https://sho.rt")
+		shortenHandler.Register(r)
+
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/api/shorten/1/stats?days=7", nil)
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, "application/json; charset=utf-8", resp.Header().Get("content-type"))
+		require.JSONEq(t, `{
+			"total": 3,
+			"daily": [{"day": "2020-11-01", "count": 3}],
+			"top_referers": [{"value": "https://referer.com", "count": 2}],
+			"top_user_agents": [{"value": "curl", "count": 3}]
+		}`, resp.Body.String())
+	})
+
+	t.Run("default period", func(t *testing.T) {
+		logger := logging.NewTestLogger()
+		r := NewRouter(logger)
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockShortenService := NewMockShortenService(ctrl)
+		mockShortenService.EXPECT().Stats(gomock.Any(), int64(1), 30).Return(shorten.Stats{}, nil)
+
+		shortenHandler := NewShortenHandler(mockShortenService, nil, "https://sho.rt")
+		shortenHandler.Register(r)
+
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/api/shorten/1/stats", nil)
+		resp := httptest.NewRecorder()
+
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.JSONEq(t, `{"total": 0, "daily": [], "top_referers": [], "top_user_agents": []}`, resp.Body.String())
+	})
 }
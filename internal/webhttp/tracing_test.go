@@ -0,0 +1,69 @@
+package webhttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pavelmemory/jobtome/internal/logging"
+	"github.com/pavelmemory/jobtome/internal/shorten"
+)
+
+func TestTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	}()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	const traceID, parentID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+
+	var handled trace.SpanContext
+	mockShortenService := NewMockShortenService(ctrl)
+	mockShortenService.EXPECT().Resolve(gomock.Any(), "hash", gomock.Any()).
+		DoAndReturn(func(ctx context.Context, _ string, _ shorten.Click) (string, error) {
+			handled = trace.SpanContextFromContext(ctx)
+			logging.FromContext(ctx).Info("resolved")
+			return "https://example.com", nil
+		})
+
+	logger := logging.NewTestLogger()
+	r := NewRouter(logger)
+	NewResolverHandler(mockShortenService).Register(r)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/hash", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentID+"-01")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusTemporaryRedirect, resp.Code)
+
+	require.Equal(t, traceID, handled.TraceID().String())
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, "GET /{hash}", spans[0].Name())
+	require.Equal(t, trace.SpanKindServer, spans[0].SpanKind())
+	require.Equal(t, parentID, spans[0].Parent().SpanID().String())
+	require.Equal(t, handled.SpanID(), spans[0].SpanContext().SpanID())
+	require.Contains(t, spans[0].Attributes(), attribute.Int("http.response.status_code", http.StatusTemporaryRedirect))
+
+	entries := logger.Entries()
+	require.Equal(t, traceID, entries[0]["trace_id"])
+	require.Equal(t, handled.SpanID().String(), entries[0]["span_id"])
+}